@@ -1,7 +1,6 @@
 package main
 
 import (
-	"io"
 	"net"
 	"net/http"
 	"os"
@@ -27,6 +26,45 @@ func main() {
 		Action: dumpRun,
 	}
 
+	objdump := &cli.Command{
+		Name:        "objdump",
+		Description: "disassemble function bodies",
+		Args:        cli.Args{},
+		Action:      objdumpRun,
+	}
+
+	wasm2wat := &cli.Command{
+		Name:        "wasm2wat",
+		Description: "print module in the text format",
+		Args:        cli.Args{},
+		Action:      wasm2watRun,
+		Flags: []*cli.Flag{
+			cli.NewFlag("output,o", "-", "output file"),
+			cli.NewFlag("fold", false, "print folded expressions"),
+		},
+	}
+
+	wat2wasm := &cli.Command{
+		Name:        "wat2wasm",
+		Description: "assemble module from the text format",
+		Args:        cli.Args{},
+		Action:      wat2wasmRun,
+		Flags: []*cli.Flag{
+			cli.NewFlag("output,o", "-", "output file"),
+			cli.NewFlag("debug-names", false, "generate name section from identifiers"),
+		},
+	}
+
+	validate := &cli.Command{
+		Name:        "validate",
+		Description: "validate modules",
+		Args:        cli.Args{},
+		Action:      validateRun,
+		Flags: []*cli.Flag{
+			cli.NewFlag("json", false, "print diagnostics as JSON lines"),
+		},
+	}
+
 	app := &cli.Command{
 		Name:        "wasmtool",
 		Description: "tool to work with wasm format",
@@ -40,6 +78,10 @@ func main() {
 		},
 		Commands: []*cli.Command{
 			dump,
+			objdump,
+			wasm2wat,
+			wat2wasm,
+			validate,
 		},
 	}
 
@@ -52,19 +94,14 @@ func before(c *cli.Command) error {
 		return errors.Wrap(err, "open log file")
 	}
 
-	err = tlio.WalkWriter(w, func(w io.Writer) error {
+	walkWriter(w, func(w interface{}) {
 		c, ok := w.(*tlog.ConsoleWriter)
 		if !ok {
-			return nil
+			return
 		}
 
 		c.StringOnNewLineMinLen = 16
-
-		return nil
 	})
-	if err != nil {
-		return errors.Wrap(err, "walk writer")
-	}
 
 	tlog.DefaultLogger = tlog.New(w)
 
@@ -90,6 +127,21 @@ func before(c *cli.Command) error {
 	return nil
 }
 
+func walkWriter(w interface{}, f func(w interface{})) {
+	for w != nil {
+		f(w)
+
+		switch x := w.(type) {
+		case tlio.WriteCloser:
+			w = x.Writer
+		case tlio.NopCloser:
+			w = x.Writer
+		default:
+			w = tlio.Unwrap(w)
+		}
+	}
+}
+
 func dumpRun(c *cli.Command) (err error) {
 	var d wasm.Decoder
 
@@ -107,30 +159,54 @@ func dumpRun(c *cli.Command) (err error) {
 				return errors.Wrap(err, "decode")
 			}
 
-			tlog.Printw("module", "start", m.Start, "sections", bytearr(m.Sections), "data_count", m.DataCount)
+			var names wasm.Names
+
+			_, err = d.ModuleNames(m, &names)
+			if err != nil {
+				tlog.Printw("name section", "err", err)
+			}
+
+			tlog.Printw("module", "name", names.Module, "start", m.Start, "sections", bytearr(m.Sections), "data_count", m.DataCount)
+
+			var funcs, tables, mems, globals wasm.Index
 
 			for i, v := range m.Import {
-				tlog.Printw("import", "i", i, "mod", v.Module, "name", v.Name)
+				switch v.Kind() {
+				case wasm.ExternFunc:
+					tlog.Printw("import", "i", i, "mod", v.Module, "name", v.Name, "kind", "func", "tp", v.FuncType(), "func", funcs, "func_name", names.Funcs.Name(funcs))
+					funcs++
+				case wasm.ExternTable:
+					t := v.Table()
+					tlog.Printw("import", "i", i, "mod", v.Module, "name", v.Name, "kind", "table", "tp", t.Type, "limits", t.Limits, "table", tables, "table_name", names.Tables.Name(tables))
+					tables++
+				case wasm.ExternMemory:
+					tlog.Printw("import", "i", i, "mod", v.Module, "name", v.Name, "kind", "memory", "limits", v.Memory(), "memory", mems, "memory_name", names.Memories.Name(mems))
+					mems++
+				case wasm.ExternGlobal:
+					g := v.Global()
+					tlog.Printw("import", "i", i, "mod", v.Module, "name", v.Name, "kind", "global", "tp", g.Type, "mut", g.Mut, "global", globals, "global_name", names.Globals.Name(globals))
+					globals++
+				}
 			}
 
 			for i, v := range m.Type {
-				tlog.Printw("type", "i", i, "params", v.Params, "result", v.Result)
+				tlog.Printw("type", "i", i, "name", names.Types.Name(wasm.Index(i)), "params", v.Params, "result", v.Result)
 			}
 
 			for i, v := range m.Function {
-				tlog.Printw("function", "i", i, "tp", v)
+				tlog.Printw("function", "i", i, "func", funcs+wasm.Index(i), "name", names.Funcs.Name(funcs+wasm.Index(i)), "tp", v)
 			}
 
 			for i, v := range m.Table {
-				tlog.Printw("table", "i", i, "tp", v.Type, "limits", v.Limits)
+				tlog.Printw("table", "i", i, "name", names.Tables.Name(tables+wasm.Index(i)), "tp", v.Type, "limits", v.Limits)
 			}
 
 			for i, v := range m.Memory {
-				tlog.Printw("memory", "i", i, "limits", v)
+				tlog.Printw("memory", "i", i, "name", names.Memories.Name(mems+wasm.Index(i)), "limits", v)
 			}
 
 			for i, v := range m.Global {
-				tlog.Printw("global", "i", i, "tp", v.Type, "mut", v.Mut, "expr", v.Expr)
+				tlog.Printw("global", "i", i, "name", names.Globals.Name(globals+wasm.Index(i)), "tp", v.Type, "mut", v.Mut, "expr", v.Expr)
 			}
 
 			for i, v := range m.Export {
@@ -138,21 +214,21 @@ func dumpRun(c *cli.Command) (err error) {
 			}
 
 			for i, v := range m.Element {
-				tlog.Printw("element", "i", i, "tp", v.Type, "expr", v.Expr, "funcs", v.Funcs)
+				tlog.Printw("element", "i", i, "name", names.Elems.Name(wasm.Index(i)), "mode", v.Mode, "table", v.Table, "tp", v.Type, "expr", v.Expr, "funcs", v.Funcs, "init", v.Init)
 			}
 
 			for i, v := range m.Code {
 				f, err := d.Func(v, wasm.FuncCode{})
 				if err != nil {
-					tlog.Printw("code", "i", i, "code", v, "err", err)
+					tlog.Printw("code", "i", i, "name", names.Funcs.Name(funcs+wasm.Index(i)), "code", v, "err", err)
 					continue
 				}
 
-				tlog.Printw("code", "i", i, "locals", f.Locals, "expr", f.Expr)
+				tlog.Printw("code", "i", i, "name", names.Funcs.Name(funcs+wasm.Index(i)), "locals", f.Locals, "expr", f.Expr)
 			}
 
 			for i, v := range m.Data {
-				tlog.Printw("data", "i", i, "expr", v.Expr, "init", v.Init)
+				tlog.Printw("data", "i", i, "name", names.Data.Name(wasm.Index(i)), "mode", v.Mode, "memory", v.Memory, "expr", v.Expr, "init", v.Init)
 			}
 
 			for i, v := range m.Custom {
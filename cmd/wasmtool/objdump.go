@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"nikand.dev/go/cli"
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+	"tlog.app/go/tlog"
+)
+
+type (
+	// disasm is a state of a single module disassembly.
+	disasm struct {
+		d wasm.Decoder
+
+		m     *wasm.Module
+		names wasm.Names
+
+		// imported function names
+		imports []string
+		exports map[wasm.Index]string
+
+		buf []byte
+	}
+)
+
+func objdumpRun(c *cli.Command) (err error) {
+	w := bufio.NewWriter(os.Stdout)
+	defer func() {
+		e := w.Flush()
+		if err == nil {
+			err = errors.Wrap(e, "flush")
+		}
+	}()
+
+	for _, a := range c.Args {
+		data, err := os.ReadFile(a)
+		if err != nil {
+			return errors.Wrap(err, "read file")
+		}
+
+		var x disasm
+
+		err = x.run(w, a, data)
+		if err != nil {
+			return errors.Wrap(err, "%v", a)
+		}
+	}
+
+	return nil
+}
+
+func (x *disasm) run(w *bufio.Writer, name string, data []byte) (err error) {
+	x.m = &wasm.Module{}
+
+	err = x.d.Module(data, x.m)
+	if err != nil {
+		return errors.Wrap(err, "decode")
+	}
+
+	_, err = x.d.ModuleNames(x.m, &x.names)
+	if err != nil {
+		tlog.Printw("name section", "err", err)
+
+		x.names = wasm.Names{}
+	}
+
+	x.exports = map[wasm.Index]string{}
+
+	for _, e := range x.m.Export {
+		if e.ExportType == wasm.ExternFunc {
+			x.exports[e.Index] = string(e.Name)
+		}
+	}
+
+	for _, im := range x.m.Import {
+		if im.Kind() == wasm.ExternFunc {
+			x.imports = append(x.imports, string(im.Module)+"."+string(im.Name))
+		}
+	}
+
+	offs, err := itemOffsets(&x.d, data, wasm.CodeSection)
+	if err != nil {
+		return errors.Wrap(err, "code section")
+	}
+
+	fmt.Fprintf(w, "%s:\tfile format wasm 0x%x\n\nCode Disassembly:\n", name, x.m.Version)
+
+	for i, code := range x.m.Code {
+		err = x.function(w, wasm.Index(len(x.imports)+i), offs[i], code)
+		if err != nil {
+			return errors.Wrap(err, "code %d", i)
+		}
+	}
+
+	return nil
+}
+
+func (x *disasm) function(w *bufio.Writer, fn wasm.Index, base int, code wasm.Code) (err error) {
+	f, err := x.d.Func(code, wasm.FuncCode{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%06x func[%d]%s:\n", base, fn, x.funcName(fn))
+
+	st := len(code) - len(f.Expr)
+
+	if st > 1 {
+		x.line(w, base, code[:st], "locals:")
+
+		params := x.params(fn)
+
+		for j := 0; j < len(f.Locals); {
+			k := j
+			for k < len(f.Locals) && f.Locals[k] == f.Locals[j] {
+				k++
+			}
+
+			fmt.Fprintf(w, " local[%d..%d] %v", params+j, params+k-1, f.Locals[j])
+
+			j = k
+		}
+
+		w.WriteByte('\n')
+	}
+
+	var in wasm.Instruction
+	depth := 0
+
+	for i := st; i < len(code); {
+		in, i, err = x.d.Instruction(code, i, in)
+		if err != nil {
+			return err
+		}
+
+		if (in.Opcode == wasm.End || in.Opcode == wasm.Else) && depth > 0 {
+			depth--
+		}
+
+		x.buf = x.buf[:0]
+
+		for j := 0; j < depth; j++ {
+			x.buf = append(x.buf, "  "...)
+		}
+
+		x.buf = append(x.buf, in.Name()...)
+		x.buf = in.AppendImmediates(x.buf)
+
+		switch in.Opcode {
+		case wasm.Call, wasm.RefFunc:
+			x.buf = append(x.buf, x.funcName(in.Index)...)
+		case wasm.GlobalGet, wasm.GlobalSet:
+			if n := x.names.Globals.Name(in.Index); n != nil {
+				x.buf = fmt.Appendf(x.buf, " <%s>", n)
+			}
+		}
+
+		x.line(w, base+in.Offset, code[in.Offset:i], string(x.buf))
+		w.WriteByte('\n')
+
+		switch in.Opcode {
+		case wasm.Block, wasm.Loop, wasm.If, wasm.Else:
+			depth++
+		}
+	}
+
+	return nil
+}
+
+// line prints offset and instruction bytes column.
+func (x *disasm) line(w *bufio.Writer, off int, b []byte, text string) {
+	const width = 26
+
+	fmt.Fprintf(w, " %06x:", off)
+
+	n := 0
+
+	for _, c := range b {
+		fmt.Fprintf(w, " %02x", c)
+		n += 3
+	}
+
+	for ; n < width; n++ {
+		w.WriteByte(' ')
+	}
+
+	fmt.Fprintf(w, " | %s", text)
+}
+
+// params returns the number of function parameters, locals are indexed after them.
+func (x *disasm) params(fn wasm.Index) int {
+	i := int(fn) - len(x.imports)
+
+	if i < 0 || i >= len(x.m.Function) || int(x.m.Function[i]) >= len(x.m.Type) {
+		return 0
+	}
+
+	return len(x.m.Type[x.m.Function[i]].Params)
+}
+
+// funcName returns symbolic function name from the name section, import or export.
+func (x *disasm) funcName(fn wasm.Index) string {
+	if n := x.names.Funcs.Name(fn); n != nil {
+		return fmt.Sprintf(" <%s>", n)
+	}
+
+	if int(fn) < len(x.imports) {
+		return fmt.Sprintf(" <%s>", x.imports[fn])
+	}
+
+	if n, ok := x.exports[fn]; ok {
+		return fmt.Sprintf(" <%s>", n)
+	}
+
+	return ""
+}
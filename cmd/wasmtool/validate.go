@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"nikand.dev/go/cli"
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// diagnostic is a single validation problem.
+	diagnostic struct {
+		File    string `json:"file"`
+		Section string `json:"section,omitempty"`
+		Item    string `json:"item,omitempty"`
+		Index   int    `json:"index"`  // function index for code section, -1 if not applicable
+		Offset  int    `json:"offset"` // file offset, -1 if unknown
+		Message string `json:"message"`
+	}
+)
+
+func validateRun(c *cli.Command) (err error) {
+	if len(c.Args) == 0 {
+		return errors.New("input files expected")
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer func() {
+		e := w.Flush()
+		if err == nil {
+			err = errors.Wrap(e, "flush")
+		}
+	}()
+
+	var v wasm.Validator
+	var diags []diagnostic
+
+	invalid := 0
+	asJSON := c.Bool("json")
+
+	for _, a := range c.Args {
+		data, err := os.ReadFile(a)
+		if err != nil {
+			diags = append(diags[:0], diagnostic{File: a, Index: -1, Offset: -1, Message: fmt.Sprintf("read: %v", err)})
+		} else {
+			diags = validateFile(&v, a, data, diags[:0])
+		}
+		if len(diags) != 0 {
+			invalid++
+		}
+
+		for _, d := range diags {
+			if asJSON {
+				err = json.NewEncoder(w).Encode(d)
+				if err != nil {
+					return errors.Wrap(err, "encode json")
+				}
+
+				continue
+			}
+
+			fmt.Fprintf(w, "%s", d.File)
+
+			if d.Offset >= 0 {
+				fmt.Fprintf(w, ":0x%x", d.Offset)
+			}
+
+			if d.Section != "" {
+				fmt.Fprintf(w, ": %s section", d.Section)
+			}
+
+			if d.Item != "" {
+				fmt.Fprintf(w, ": %s %d", d.Item, d.Index)
+			}
+
+			fmt.Fprintf(w, ": %s\n", d.Message)
+		}
+	}
+
+	if invalid != 0 {
+		return errors.New("%d of %d files are invalid", invalid, len(c.Args))
+	}
+
+	return nil
+}
+
+func validateFile(v *wasm.Validator, name string, data []byte, diags []diagnostic) []diagnostic {
+	var d wasm.Decoder
+	var m wasm.Module
+
+	err := d.Module(data, &m)
+	if err != nil {
+		diag := diagnostic{
+			File:    name,
+			Index:   -1,
+			Offset:  -1,
+			Message: fmt.Sprintf("decode: %v", err),
+		}
+
+		var derr wasm.DecodeError
+		if errors.As(err, &derr) {
+			diag.Offset = derr.Offset
+			diag.Message = fmt.Sprintf("decode: %v", derr.Err)
+		}
+
+		var serr wasm.SectionOrderError
+		if errors.As(err, &serr) {
+			diag.Section = wasm.SectionName(serr.ID)
+			diag.Offset = serr.Offset
+			diag.Message = serr.Error()
+		}
+
+		return append(diags, diag)
+	}
+
+	for _, e := range v.Errors(&m) {
+		diag := diagnostic{
+			File:    name,
+			Section: wasm.SectionName(e.Section),
+			Index:   int(e.Index),
+			Offset:  errorOffset(&d, data, &m, e),
+			Message: e.Err.Error(),
+		}
+
+		if e.Index >= 0 {
+			diag.Item = wasm.SectionItem(e.Section)
+		}
+
+		diags = append(diags, diag)
+	}
+
+	return diags
+}
+
+// errorOffset returns the file offset of the validation error.
+// It's precise for function bodies and globals,
+// it's the item or the section start for the rest.
+func errorOffset(d *wasm.Decoder, b []byte, m *wasm.Module, e wasm.ValidationError) int {
+	st, err := sectionOffset(d, b, e.Section)
+	if err != nil || e.Index < 0 {
+		return st
+	}
+
+	x := int(e.Index)
+
+	switch e.Section {
+	case wasm.FunctionSection, wasm.CodeSection:
+		x -= m.NumImported(wasm.ExternFunc)
+	case wasm.TableSection:
+		x -= m.NumImported(wasm.ExternTable)
+	case wasm.MemorySection:
+		x -= m.NumImported(wasm.ExternMemory)
+	case wasm.GlobalSection:
+		x -= m.NumImported(wasm.ExternGlobal)
+	}
+
+	offs, err := itemOffsets(d, b, e.Section)
+	if err != nil || x < 0 || x >= len(offs) {
+		return st
+	}
+
+	off := offs[x]
+
+	switch {
+	case e.Offset < 0:
+	case e.Section == wasm.CodeSection:
+		off += e.Offset
+	case e.Section == wasm.GlobalSection:
+		off += 2 + e.Offset // global type is two bytes
+	}
+
+	return off
+}
+
+// sectionOffset returns the file offset of the section id byte or -1.
+func sectionOffset(d *wasm.Decoder, b []byte, section byte) (int, error) {
+	for i := 8; i < len(b); {
+		st := i
+
+		id, _, next, err := d.Section(b, i)
+		if err != nil {
+			return -1, err
+		}
+
+		if id == section {
+			return st, nil
+		}
+
+		i = next
+	}
+
+	return -1, nil
+}
+
+// itemOffsets returns file offsets of the section entries.
+// For function bodies these are the offsets after the body size.
+// Module is expected to be decoded successfully already.
+func itemOffsets(d *wasm.Decoder, b []byte, section byte) (offs []int, err error) {
+	i := 8
+
+	for i < len(b) {
+		var id byte
+		var data []byte
+
+		id, data, i, err = d.Section(b, i)
+		if err != nil {
+			return nil, err
+		}
+
+		if id != section {
+			continue
+		}
+
+		base := i - len(data)
+
+		l, j, err := d.Int(data, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for n := 0; n < l; n++ {
+			st := j
+
+			switch section {
+			case wasm.TypeSection:
+				_, j, err = d.FuncType(data, j, wasm.FuncType{})
+			case wasm.ImportSection:
+				_, j, err = d.Import(data, j, wasm.Import{})
+			case wasm.FunctionSection:
+				_, j, err = d.Int(data, j)
+			case wasm.TableSection:
+				_, _, _, j, err = d.TableType(data, j)
+			case wasm.MemorySection:
+				_, j, err = d.MemoryType(data, j)
+			case wasm.GlobalSection:
+				_, _, j, err = d.GlobalType(data, j)
+				if err == nil {
+					_, j, err = d.Expr(data, j)
+				}
+			case wasm.ExportSection:
+				_, j, err = d.Export(data, j, wasm.Export{})
+			case wasm.ElementSection:
+				_, j, err = d.Element(data, j, wasm.Element{})
+			case wasm.DataSection:
+				_, j, err = d.Data(data, j, wasm.Data{})
+			case wasm.CodeSection:
+				var size int
+
+				size, j, err = d.Int(data, j)
+				st = j
+				j += size
+			default:
+				return nil, errors.New("unsupported section: %v", wasm.SectionName(section))
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			offs = append(offs, base+st)
+		}
+
+		return offs, nil
+	}
+
+	return offs, nil
+}
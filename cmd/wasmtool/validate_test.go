@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+)
+
+func TestValidateFile(tb *testing.T) {
+	var v wasm.Validator
+
+	for _, tc := range []struct {
+		hex   string
+		diags []diagnostic
+	}{
+		{"0061736d01000000", nil},
+		{"0061736d010000000105016000017f03020104000a0a01080041000c0042000b", []diagnostic{ // custom section name is out of bounds
+			{File: "a.wasm", Index: -1, Offset: 19, Message: "decode: section id 0: name: unexpected EOF"},
+		}},
+		{"0061736d01000000" + "0503010001" + "010401600000", []diagnostic{
+			{File: "a.wasm", Section: "type", Index: -1, Offset: 13, Message: "type section after memory section"},
+		}},
+	} {
+		b, err := hex.DecodeString(tc.hex)
+		require.NoError(tb, err)
+
+		diags := validateFile(&v, "a.wasm", b, nil)
+		assert.Equal(tb, tc.diags, diags, tc.hex)
+	}
+}
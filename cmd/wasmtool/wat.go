@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+
+	"nikand.dev/go/cli"
+	"nikand.dev/go/wasm"
+	"nikand.dev/go/wasm/wat"
+	"tlog.app/go/errors"
+)
+
+func wasm2watRun(c *cli.Command) (err error) {
+	if len(c.Args) != 1 {
+		return errors.New("one input file expected")
+	}
+
+	data, err := os.ReadFile(c.Args[0])
+	if err != nil {
+		return errors.Wrap(err, "read file")
+	}
+
+	var d wasm.Decoder
+	m := &wasm.Module{}
+
+	err = d.Module(data, m)
+	if err != nil {
+		return errors.Wrap(err, "decode")
+	}
+
+	p := wat.Printer{
+		Folded: c.Bool("fold"),
+	}
+
+	text, err := p.Module(nil, m)
+	if err != nil {
+		return errors.Wrap(err, "print")
+	}
+
+	return writeOutput(c.String("output"), text)
+}
+
+// writeOutput writes data to the file or to stdout if name is "-".
+func writeOutput(name string, data []byte) (err error) {
+	if name == "" || name == "-" {
+		_, err = os.Stdout.Write(data)
+
+		return errors.Wrap(err, "write")
+	}
+
+	err = os.WriteFile(name, data, 0o644)
+
+	return errors.Wrap(err, "write file")
+}
+
+func wat2wasmRun(c *cli.Command) (err error) {
+	if len(c.Args) != 1 {
+		return errors.New("one input file expected")
+	}
+
+	text, err := os.ReadFile(c.Args[0])
+	if err != nil {
+		return errors.Wrap(err, "read file")
+	}
+
+	p := wat.Parser{
+		DebugNames: c.Bool("debug-names"),
+	}
+
+	var m wasm.Module
+
+	err = p.Module(text, &m)
+	if err != nil {
+		return errors.Wrap(err, "parse")
+	}
+
+	var e wasm.Encoder
+
+	return writeOutput(c.String("output"), e.Module(nil, &m))
+}
@@ -3,6 +3,7 @@ package wasm
 import (
 	"encoding/binary"
 	stderrors "errors"
+	"fmt"
 	"io"
 	"math"
 	"unsafe"
@@ -13,6 +14,24 @@ import (
 type (
 	Decoder struct {
 		InstructionsDecoder
+
+		// Lenient accepts out of order and duplicate sections.
+		// A duplicate section replaces the previous one.
+		Lenient bool
+	}
+
+	// SectionOrderError is returned for a duplicate or out of order section.
+	SectionOrderError struct {
+		ID     byte // offending section id
+		Prev   byte // previous non-custom section id
+		Offset int  // offending section offset
+	}
+
+	// DecodeError is returned by Decoder.Module.
+	// It keeps the file offset the decoding failed at.
+	DecodeError struct {
+		Offset int
+		Err    error
 	}
 
 	LowDecoder struct {
@@ -25,6 +44,23 @@ var (
 	MaxSupportedVersion = 1
 )
 
+// sectionOrder is the position of the sections in a module.
+// Custom sections can be anywhere.
+var sectionOrder = [...]byte{
+	TypeSection:      1,
+	ImportSection:    2,
+	FunctionSection:  3,
+	TableSection:     4,
+	MemorySection:    5,
+	GlobalSection:    6,
+	ExportSection:    7,
+	StartSection:     8,
+	ElementSection:   9,
+	DataCountSection: 10,
+	CodeSection:      11,
+	DataSection:      12,
+}
+
 var (
 	ErrMagic              = stderrors.New("magic mismatch")
 	ErrOverflow           = stderrors.New("integer overflow")
@@ -41,7 +77,7 @@ func (d *Decoder) Module(b []byte, m *Module) (err error) {
 			return
 		}
 
-		err = errors.Wrap(err, "at pos 0x%x", i)
+		err = DecodeError{Offset: i, Err: err}
 	}()
 
 	if common(b[i:], Magic) != len(Magic) {
@@ -61,13 +97,36 @@ func (d *Decoder) Module(b []byte, m *Module) (err error) {
 		return ErrUnsupportedVersion
 	}
 
-	m.Start = -1
-	m.DataCount = 0
+	m.Start, m.HasStart = 0, false
+	m.DataCount, m.HasDataCount = 0, false
+	m.Sections = m.Sections[:0]
+
+	m.Type = m.Type[:0]
+	m.Import = m.Import[:0]
+	m.Function = m.Function[:0]
+	m.Table = m.Table[:0]
+	m.Memory = m.Memory[:0]
+	m.Global = m.Global[:0]
+	m.Export = m.Export[:0]
+	m.Element = m.Element[:0]
+	m.Code = m.Code[:0]
+	m.Data = m.Data[:0]
+	m.Custom = m.Custom[:0]
+
+	var prev byte
 
 	for i < len(b) {
 		id := b[i]
 		m.Sections = append(m.Sections, id)
 
+		if id != CustomSection && int(id) < len(sectionOrder) && !d.Lenient {
+			if sectionOrder[id] <= sectionOrder[prev] {
+				return SectionOrderError{ID: id, Prev: prev, Offset: i}
+			}
+
+			prev = id
+		}
+
 		size, end, err := d.Int(b, i+1)
 		if err != nil {
 			return errors.Wrap(err, "section size")
@@ -116,9 +175,27 @@ func (d *Decoder) Module(b []byte, m *Module) (err error) {
 		i = end
 	}
 
+	if m.DataCount > 0 && len(m.Data) == 0 {
+		return errors.New("data count mismatch: no data section, data count section: %d", m.DataCount)
+	}
+
 	return nil
 }
 
+func (e DecodeError) Error() string {
+	return fmt.Sprintf("at pos 0x%x: %v", e.Offset, e.Err)
+}
+
+func (e DecodeError) Unwrap() error { return e.Err }
+
+func (e SectionOrderError) Error() string {
+	if e.ID == e.Prev {
+		return fmt.Sprintf("duplicate %s section", SectionName(e.ID))
+	}
+
+	return fmt.Sprintf("%s section after %s section", SectionName(e.ID), SectionName(e.Prev))
+}
+
 func (d *Decoder) CustomSection(b []byte, st int, m *Module) (i int, err error) {
 	end, i, err := d.sectionHeader(b, st, CustomSection)
 	if err != nil {
@@ -130,6 +207,10 @@ func (d *Decoder) CustomSection(b []byte, st int, m *Module) (i int, err error)
 		return st, errors.Wrap(err, "name")
 	}
 
+	if i > end {
+		return st, errors.Wrap(ErrUnexpectedEOF, "name")
+	}
+
 	data := b[i:end]
 
 	n := len(m.Custom)
@@ -224,32 +305,54 @@ func (d *Decoder) Import(b []byte, st int, buf Import) (im Import, i int, err er
 		return im, i, ErrUnexpectedEOF
 	}
 
-	im.tp = b[i]
+	kind := b[i]
 	i++
 
-	switch im.tp {
-	case 0:
-		im.rawi[0], i, err = d.Int(b, i)
+	switch kind {
+	case ExternFunc:
+		var tp Index
+
+		tp, i, err = d.index(b, i)
 		if err != nil {
 			return im, i, errors.Wrap(err, "type index")
 		}
-	case 1:
-		im.rawb[0], im.rawi[0], im.rawi[1], i, err = d.TableType(b, i)
+
+		im.SetFunc(tp)
+	case ExternTable:
+		var t Table
+		var tp byte
+
+		tp, t.Limits.Lo, t.Limits.Hi, i, err = d.TableType(b, i)
 		if err != nil {
 			return im, i, errors.Wrap(err, "table type")
 		}
-	case 2:
-		im.rawi[0], im.rawi[1], i, err = d.Limits(b, i)
+
+		t.Type = Type(tp)
+
+		im.SetTable(t)
+	case ExternMemory:
+		var l Limits
+
+		l, i, err = d.MemoryType(b, i)
 		if err != nil {
 			return im, i, errors.Wrap(err, "memory limits")
 		}
-	case 3:
-		im.rawb[0], im.rawb[1], i, err = d.GlobalType(b, i)
+
+		im.SetMemory(l)
+	case ExternGlobal:
+		var g Global
+		var tp byte
+
+		tp, g.Mut, i, err = d.GlobalType(b, i)
 		if err != nil {
 			return im, i, errors.Wrap(err, "global type")
 		}
+
+		g.Type = Type(tp)
+
+		im.SetGlobal(g)
 	default:
-		return im, i - 1, errors.New("unsupported import description type: 0x%02x", im.tp)
+		return im, i - 1, errors.New("unsupported import description type: 0x%02x", kind)
 	}
 
 	return im, i, nil
@@ -374,7 +477,7 @@ func (d *Decoder) MemorySection(b []byte, st int, m *Module) (i int, err error)
 	m.Memory = m.Memory[:0]
 
 	for n := 0; n < l; n++ {
-		x.Lo, x.Hi, i, err = d.Limits(b, i)
+		x, i, err = d.MemoryType(b, i)
 		if err != nil {
 			return i, errors.Wrap(err, "memory %d", n)
 		}
@@ -459,41 +562,95 @@ func (d *Decoder) ElementSection(b []byte, st int, m *Module) (i int, err error)
 
 func (d *Decoder) Element(b []byte, st int, buf Element) (el Element, i int, err error) {
 	el = buf
+	el.Table = 0
+	el.Type = FuncRef
+	el.Expr = el.Expr[:0]
+	el.Funcs = el.Funcs[:0]
+	el.Init = el.Init[:0]
 
-	tp, i, err := d.Byte(b, st)
+	flags, i, err := d.Int(b, st)
 	if err != nil {
-		return el, i, err
+		return el, st, err
 	}
 
-	switch tp {
-	case 0:
-		var code []byte
+	if flags > 7 {
+		return el, st, errors.New("unsupported elem: 0x%02x", flags)
+	}
+
+	el.ExplicitTable = flags&3 == 2
+	el.InitExprs = flags&4 != 0
+
+	switch {
+	case flags&1 == 0:
+		el.Mode = ElemActive
+	case flags&2 == 0:
+		el.Mode = ElemPassive
+	default:
+		el.Mode = ElemDeclarative
+	}
+
+	if flags&3 == 2 {
+		el.Table, i, err = d.index(b, i)
+		if err != nil {
+			return el, i, errors.Wrap(err, "table")
+		}
+	}
+
+	var code []byte
 
+	if el.Mode == ElemActive {
 		code, i, err = d.Expr(b, i)
 		if err != nil {
 			return el, i, errors.Wrap(err, "expr")
 		}
 
 		el.Expr = appendOrSet(false, el.Expr[:0], code...)
+	}
 
-		var l int
+	if flags&3 != 0 && flags&4 == 0 {
+		var kind byte
 
-		l, i, err = d.Int(b, i)
+		kind, i, err = d.Byte(b, i)
 		if err != nil {
-			return el, i, errors.Wrap(err, "funcs")
+			return el, i, errors.Wrap(err, "elem kind")
+		}
+
+		if kind != 0 {
+			return el, i - 1, errors.New("unsupported elem kind: 0x%02x", kind)
 		}
+	}
 
-		for j := 0; j < l; j++ {
-			var id int
-			id, i, err = d.Int(b, i)
+	if flags&3 != 0 && flags&4 != 0 {
+		el.Type, i, err = d.RefType(b, i)
+		if err != nil {
+			return el, i, errors.Wrap(err, "ref type")
+		}
+	}
+
+	l, i, err := d.Int(b, i)
+	if err != nil {
+		return el, i, errors.Wrap(err, "elems")
+	}
+
+	for j := 0; j < l; j++ {
+		if flags&4 != 0 {
+			code, i, err = d.Expr(b, i)
 			if err != nil {
-				return el, i, errors.Wrap(err, "func id")
+				return el, i, errors.Wrap(err, "init expr %d", j)
 			}
 
-			el.Funcs = append(el.Funcs, Index(id))
+			el.Init = append(el.Init, code)
+
+			continue
 		}
-	default:
-		return el, st, errors.New("unsupported elem: 0x%02x", tp)
+
+		var id Index
+		id, i, err = d.index(b, i)
+		if err != nil {
+			return el, i, errors.Wrap(err, "func id")
+		}
+
+		el.Funcs = append(el.Funcs, id)
 	}
 
 	return el, i, nil
@@ -506,6 +663,7 @@ func (d *Decoder) DataCountSection(b []byte, st int, m *Module) (i int, err erro
 	}
 
 	m.DataCount = l
+	m.HasDataCount = true
 
 	if i != end {
 		return st, ErrSizeMismatch
@@ -521,6 +679,7 @@ func (d *Decoder) StartSection(b []byte, st int, m *Module) (i int, err error) {
 	}
 
 	m.Start = Index(l)
+	m.HasStart = true
 
 	if i != end {
 		return st, ErrSizeMismatch
@@ -571,9 +730,10 @@ func (d *Decoder) DataSection(b []byte, st int, m *Module) (i int, err error) {
 		return
 	}
 
-	var size int
-	var code []byte
-	var tp byte
+	if m.HasDataCount && m.DataCount != l {
+		return st, errors.New("data count mismatch: %d, data count section: %d", l, m.DataCount)
+	}
+
 	m.Data = m.Data[:cap(m.Data)]
 
 	for n := 0; n < l; n++ {
@@ -581,43 +741,69 @@ func (d *Decoder) DataSection(b []byte, st int, m *Module) (i int, err error) {
 			m.Data = append(m.Data, Data{})
 		}
 
-		tp, i, err = d.Byte(b, i)
+		m.Data[n], i, err = d.Data(b, i, m.Data[n])
 		if err != nil {
-			return
+			return i, errors.Wrap(err, "data %d", n)
 		}
+	}
 
-		switch tp {
-		case 0:
-			code, i, err = d.Expr(b, i)
-			if err != nil {
-				return i, errors.Wrap(err, "data %d: expr", n)
-			}
+	m.Data = m.Data[:l]
 
-			m.Data[n].Expr = appendOrSet(false, m.Data[n].Expr[:0], code...)
-		default:
-			return st, errors.New("data %d: unsupported data type: 0x%02x", n, tp)
-		}
+	if i != end {
+		return st, ErrSizeMismatch
+	}
 
-		size, i, err = d.Int(b, i)
+	return
+}
+
+func (d *Decoder) Data(b []byte, st int, buf Data) (x Data, i int, err error) {
+	x = buf
+	x.Memory = 0
+	x.ExplicitMemory = false
+	x.Expr = x.Expr[:0]
+
+	tp, i, err := d.Int(b, st)
+	if err != nil {
+		return x, st, err
+	}
+
+	switch tp {
+	case 0, 2:
+		x.Mode = DataActive
+	case 1:
+		x.Mode = DataPassive
+	default:
+		return x, st, errors.New("unsupported data type: 0x%02x", tp)
+	}
+
+	if tp == 2 {
+		x.ExplicitMemory = true
+
+		x.Memory, i, err = d.index(b, i)
 		if err != nil {
-			return st, errors.Wrap(err, "data %d: init size", n)
+			return x, i, errors.Wrap(err, "memory")
 		}
+	}
 
-		if i+size > len(b) {
-			return st, errors.Wrap(ErrUnexpectedEOF, "data %d: init", n)
+	if x.Mode == DataActive {
+		var code []byte
+
+		code, i, err = d.Expr(b, i)
+		if err != nil {
+			return x, i, errors.Wrap(err, "expr")
 		}
 
-		m.Data[n].Init = appendOrSet(false, m.Data[n].Init[:0], b[i:i+size]...)
-		i += size
+		x.Expr = appendOrSet(false, x.Expr[:0], code...)
 	}
 
-	m.Data = m.Data[:l]
-
-	if i != end {
-		return st, ErrSizeMismatch
+	init, i, err := d.Name(b, i)
+	if err != nil {
+		return x, i, errors.Wrap(err, "init")
 	}
 
-	return
+	x.Init = appendOrSet(false, x.Init[:0], init...)
+
+	return x, i, nil
 }
 
 func (d *Decoder) sectionHeader(b []byte, st int, section byte) (end, i int, err error) {
@@ -662,61 +848,115 @@ func (d *LowDecoder) Byte(b []byte, st int) (r byte, i int, err error) {
 	return b[i], i + 1, nil
 }
 
+// Int decodes u32 as int.
 func (d *LowDecoder) Int(b []byte, st int) (l, i int, err error) {
-	x, i, err := d.Uint64(b, st)
+	x, i, err := d.Uint32(b, st)
 	return int(x), i, err
 }
 
+// Uint32 decodes unsigned LEB128 of at most 32 bits.
+func (d *LowDecoder) Uint32(b []byte, st int) (v uint32, i int, err error) {
+	x, i, err := d.uleb(b, st, 32)
+	return uint32(x), i, err
+}
+
+// Uint64 decodes unsigned LEB128 of at most 64 bits.
 func (d *LowDecoder) Uint64(b []byte, st int) (v uint64, i int, err error) {
+	return d.uleb(b, st, 64)
+}
+
+// Int32 decodes signed LEB128 of at most 32 bits.
+func (d *LowDecoder) Int32(b []byte, st int) (v int32, i int, err error) {
+	x, i, err := d.sleb(b, st, 32)
+	return int32(x), i, err
+}
+
+// Int33 decodes signed LEB128 of at most 33 bits, used for block types.
+func (d *LowDecoder) Int33(b []byte, st int) (v int64, i int, err error) {
+	return d.sleb(b, st, 33)
+}
+
+// Int64 decodes signed LEB128 of at most 64 bits.
+func (d *LowDecoder) Int64(b []byte, st int) (v int64, i int, err error) {
+	return d.sleb(b, st, 64)
+}
+
+// uleb decodes unsigned LEB128 of the bits width.
+// Encoding must be at most ceil(bits/7) bytes long
+// and unused bits of the last byte must be zero.
+func (d *LowDecoder) uleb(b []byte, st int, bits uint) (v uint64, i int, err error) {
 	var s uint
-	i = st
 
-	for i < len(b) {
-		v |= uint64(b[i]&0x7f) << s
+	for i = st; i < len(b); s += 7 {
+		c := b[i]
 		i++
-		s += 7
 
-		if b[i-1]&0x80 == 0 {
-			return v, i, nil
+		if s+7 >= bits {
+			if c>>(bits-s) != 0 {
+				return 0, st, ErrOverflow
+			}
+
+			return v | uint64(c)<<s, i, nil
 		}
 
-		if s+7 > 64 {
-			return v, st, ErrOverflow
+		v |= uint64(c&0x7f) << s
+
+		if c&0x80 == 0 {
+			return v, i, nil
 		}
 	}
 
 	return 0, st, ErrUnexpectedEOF
 }
 
-func (d *LowDecoder) Int64(b []byte, st int) (v int64, i int, err error) {
+// sleb decodes signed LEB128 of the bits width.
+// Encoding must be at most ceil(bits/7) bytes long
+// and unused bits of the last byte must be the sign extension.
+func (d *LowDecoder) sleb(b []byte, st int, bits uint) (v int64, i int, err error) {
 	var s uint
-	i = st
 
-	for i < len(b) {
-		v |= int64(b[i]&0x7f) << s
+	for i = st; i < len(b); {
+		c := b[i]
 		i++
-		s += 7
 
-		if b[i-1]&0x80 == 0 {
-			v = v << (64 - s) >> (64 - s)
+		if s+7 >= bits {
+			x := int64(c&0x7f) << 57 >> 57
+			r := 64 - (bits - s)
 
-			return v, i, nil
+			if c&0x80 != 0 || x<<r>>r != x {
+				return 0, st, ErrOverflow
+			}
+
+			return v | x<<s, i, nil
 		}
 
-		if s+7 > 64 {
-			return v, st, ErrOverflow
+		v |= int64(c&0x7f) << s
+		s += 7
+
+		if c&0x80 == 0 {
+			return v << (64 - s) >> (64 - s), i, nil
 		}
 	}
 
 	return 0, st, ErrUnexpectedEOF
 }
 
+func (d *LowDecoder) Float32(b []byte, st int) (v float32, i int, err error) {
+	if st+4 > len(b) {
+		return 0, st, ErrUnexpectedEOF
+	}
+
+	x := binary.LittleEndian.Uint32(b[st:])
+
+	return math.Float32frombits(x), st + 4, nil
+}
+
 func (d *LowDecoder) Float64(b []byte, st int) (v float64, i int, err error) {
 	if st+8 > len(b) {
 		return 0, st, ErrUnexpectedEOF
 	}
 
-	x := uint64(b[i]) | uint64(b[i+1])<<8 | uint64(b[i+2])<<16 | uint64(b[i+3])<<24 | uint64(b[i+4])<<32 | uint64(b[i+5])<<40 | uint64(b[i+6])<<48 | uint64(b[i+7])<<56
+	x := binary.LittleEndian.Uint64(b[st:])
 
 	return math.Float64frombits(x), st + 8, nil
 }
@@ -751,6 +991,32 @@ func (d *LowDecoder) BasicType(b []byte, st int) (tp byte, i int, err error) {
 	return b[st], st + 1, nil
 }
 
+func (d *LowDecoder) ValueType(b []byte, st int) (tp Type, i int, err error) {
+	x, i, err := d.BasicType(b, st)
+	if err != nil {
+		return 0, st, err
+	}
+
+	if !valueType(x) {
+		return 0, st, errors.New("unsupported value type: 0x%02x", x)
+	}
+
+	return Type(x), i, nil
+}
+
+func (d *LowDecoder) RefType(b []byte, st int) (tp Type, i int, err error) {
+	x, i, err := d.BasicType(b, st)
+	if err != nil {
+		return 0, st, err
+	}
+
+	if x != FuncRef && x != ExternRef {
+		return 0, st, errors.New("unsupported reference type: 0x%02x", x)
+	}
+
+	return Type(x), i, nil
+}
+
 func (d *LowDecoder) ResultType(b []byte, st int, buf ResultType) (tp ResultType, i int, err error) {
 	tp = buf
 
@@ -800,33 +1066,47 @@ func (d *LowDecoder) FuncType(b []byte, st int, buf FuncType) (fn FuncType, i in
 }
 
 func (d *LowDecoder) Limits(b []byte, st int) (lo, hi, i int, err error) {
+	l, i, err := d.MemoryType(b, st)
+	if err != nil {
+		return 0, 0, i, err
+	}
+
+	if l.Shared {
+		return 0, 0, st, errors.New("unexpected shared limits")
+	}
+
+	return l.Lo, l.Hi, i, nil
+}
+
+// MemoryType decodes memory limits which may be shared.
+func (d *LowDecoder) MemoryType(b []byte, st int) (l Limits, i int, err error) {
 	tp, i, err := d.Byte(b, st)
 	if err != nil {
 		return
 	}
 
-	if tp != LimitLo && tp != LimitLoHi {
-		i = st
-		err = errors.New("expected limit, got 0x%02x", tp)
-		return
+	if tp&^(LimitLoHi|LimitShared) != 0 {
+		return l, st, errors.New("expected limit, got 0x%02x", tp)
 	}
 
-	lo, i, err = d.Int(b, i)
+	l.Shared = tp&LimitShared != 0
+
+	l.Lo, i, err = d.Int(b, i)
 	if err != nil {
-		return
+		return l, st, err
 	}
 
-	if tp == LimitLo {
-		hi = -1
-		return
+	if tp&LimitLoHi == 0 {
+		l.Hi = -1
+		return l, i, nil
 	}
 
-	hi, i, err = d.Int(b, i)
+	l.Hi, i, err = d.Int(b, i)
 	if err != nil {
-		return
+		return l, st, err
 	}
 
-	return
+	return l, i, nil
 }
 
 func (d *LowDecoder) TableType(b []byte, st int) (tp byte, lo, hi, i int, err error) {
@@ -860,6 +1140,15 @@ func (d *LowDecoder) GlobalType(b []byte, st int) (tp, mut byte, i int, err erro
 
 	tp = b[i]
 	mut = b[i+1]
+
+	if !valueType(tp) {
+		return 0, 0, st, errors.New("unsupported value type: 0x%02x", tp)
+	}
+
+	if mut > 1 {
+		return 0, 0, st, errors.New("unsupported global mutability: 0x%02x", mut)
+	}
+
 	i += 2
 
 	return
@@ -1,11 +1,395 @@
 package wasm
 
-import "math"
+import (
+	"encoding/binary"
+	"math"
+)
 
 type (
+	Encoder struct {
+		InstructionsEncoder
+	}
+
 	LowEncoder struct{}
 )
 
+// specOrder is the order sections are written in
+// if Module.Sections is empty.
+var specOrder = []byte{
+	TypeSection,
+	ImportSection,
+	FunctionSection,
+	TableSection,
+	MemorySection,
+	GlobalSection,
+	ExportSection,
+	StartSection,
+	ElementSection,
+	DataCountSection,
+	CodeSection,
+	DataSection,
+}
+
+// Module encodes m and appends it to b.
+// Sections are written in the order of m.Sections, so the Decoder output is encoded back byte to byte
+// as long as the original binary used the shortest LEB128 form for section sizes.
+// Non-empty sections missing from m.Sections are written at their spec position,
+// so everything added to a decoded module is encoded too.
+// Each CustomSection entry in m.Sections takes the next m.Custom item,
+// entries left without an item are skipped and items left without an entry are appended to the end.
+// If m.Sections is empty non-empty sections are written in the spec order and custom sections are appended to the end.
+func (e *Encoder) Module(b []byte, m *Module) []byte {
+	b = append(b, Magic...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.Version))
+
+	var listed [len(sectionOrder)]bool
+
+	for _, id := range m.Sections {
+		if int(id) < len(listed) {
+			listed[id] = true
+		}
+	}
+
+	next, custom := 0, 0
+
+	missing := func(b []byte, before byte) []byte {
+		for ; next < len(specOrder) && sectionOrder[specOrder[next]] < before; next++ {
+			if id := specOrder[next]; !listed[id] && !m.sectionEmpty(id) {
+				b = e.section(b, id, m)
+			}
+		}
+
+		return b
+	}
+
+	for _, id := range m.Sections {
+		switch {
+		case id == CustomSection && custom < len(m.Custom):
+			b = e.CustomSection(b, m.Custom[custom])
+			custom++
+		case id == CustomSection:
+		case (id == StartSection || id == DataCountSection) && m.sectionEmpty(id):
+			b = missing(b, sectionOrder[id])
+		default:
+			b = missing(b, sectionOrder[id])
+			b = e.section(b, id, m)
+		}
+	}
+
+	b = missing(b, math.MaxUint8)
+
+	for _, c := range m.Custom[custom:] {
+		b = e.CustomSection(b, c)
+	}
+
+	return b
+}
+
+// section encodes a single non-custom section of the module.
+func (e *Encoder) section(b []byte, id byte, m *Module) []byte {
+	switch id {
+	case TypeSection:
+		return e.TypeSection(b, m)
+	case ImportSection:
+		return e.ImportSection(b, m)
+	case FunctionSection:
+		return e.FunctionSection(b, m)
+	case TableSection:
+		return e.TableSection(b, m)
+	case MemorySection:
+		return e.MemorySection(b, m)
+	case GlobalSection:
+		return e.GlobalSection(b, m)
+	case ExportSection:
+		return e.ExportSection(b, m)
+	case StartSection:
+		return e.StartSection(b, m)
+	case ElementSection:
+		return e.ElementSection(b, m)
+	case CodeSection:
+		return e.CodeSection(b, m)
+	case DataSection:
+		return e.DataSection(b, m)
+	case DataCountSection:
+		return e.DataCountSection(b, m)
+	default:
+		panic(id)
+	}
+}
+
+func (e *Encoder) CustomSection(b []byte, c Custom) []byte {
+	b, st := e.SectionStart(b, CustomSection)
+
+	b = e.NameBytes(b, c.Name)
+	b = append(b, c.Data...)
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) TypeSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, TypeSection)
+
+	b = e.Int(b, len(m.Type))
+
+	for _, x := range m.Type {
+		b = e.FuncTypeOf(b, x)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) ImportSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, ImportSection)
+
+	b = e.Int(b, len(m.Import))
+
+	for _, x := range m.Import {
+		b = e.Import(b, x)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) Import(b []byte, im Import) []byte {
+	b = e.NameBytes(b, im.Module)
+	b = e.NameBytes(b, im.Name)
+	b = append(b, im.Kind())
+
+	switch im.Kind() {
+	case ExternFunc:
+		b = e.Int(b, int(im.FuncType()))
+	case ExternTable:
+		t := im.Table()
+		b = e.TableType(b, byte(t.Type), t.Limits.Lo, t.Limits.Hi)
+	case ExternMemory:
+		b = e.MemoryType(b, im.Memory())
+	case ExternGlobal:
+		g := im.Global()
+		b = e.GlobalType(b, byte(g.Type), g.Mut)
+	default:
+		panic(im.Kind())
+	}
+
+	return b
+}
+
+func (e *Encoder) FunctionSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, FunctionSection)
+
+	b = e.Int(b, len(m.Function))
+
+	for _, x := range m.Function {
+		b = e.Int(b, int(x))
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) TableSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, TableSection)
+
+	b = e.Int(b, len(m.Table))
+
+	for _, x := range m.Table {
+		b = e.TableType(b, byte(x.Type), x.Limits.Lo, x.Limits.Hi)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) MemorySection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, MemorySection)
+
+	b = e.Int(b, len(m.Memory))
+
+	for _, x := range m.Memory {
+		b = e.MemoryType(b, x)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) GlobalSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, GlobalSection)
+
+	b = e.Int(b, len(m.Global))
+
+	for _, x := range m.Global {
+		b = e.GlobalType(b, byte(x.Type), x.Mut)
+		b = append(b, x.Expr...)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) ExportSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, ExportSection)
+
+	b = e.Int(b, len(m.Export))
+
+	for _, x := range m.Export {
+		b = e.NameBytes(b, x.Name)
+		b = append(b, x.ExportType)
+		b = e.Int(b, int(x.Index))
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) StartSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, StartSection)
+
+	b = e.Int(b, int(m.Start))
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) ElementSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, ElementSection)
+
+	b = e.Int(b, len(m.Element))
+
+	for _, x := range m.Element {
+		b = e.Element(b, x)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+// Element encodes element segment in the form it was decoded in
+// or in the shortest form if el was made by hand.
+// Init expressions are encoded if there are any, Funcs otherwise.
+func (e *Encoder) Element(b []byte, el Element) []byte {
+	if el.Type == 0 {
+		el.Type = FuncRef
+	}
+
+	var flags byte
+
+	switch el.Mode {
+	case ElemPassive:
+		flags = 1
+	case ElemDeclarative:
+		flags = 3
+	default:
+		if el.ExplicitTable || el.Table != 0 || el.Type != FuncRef {
+			flags = 2
+		}
+	}
+
+	if len(el.Init) != 0 || el.InitExprs && len(el.Funcs) == 0 {
+		flags |= 4
+	}
+
+	b = append(b, flags)
+
+	if flags&3 == 2 {
+		b = e.Int(b, int(el.Table))
+	}
+
+	if el.Mode == ElemActive {
+		b = append(b, el.Expr...)
+	}
+
+	if flags&3 != 0 && flags&4 == 0 {
+		b = append(b, 0) // elemkind funcref
+	}
+
+	if flags&3 != 0 && flags&4 != 0 {
+		b = append(b, byte(el.Type))
+	}
+
+	if flags&4 != 0 {
+		b = e.Int(b, len(el.Init))
+
+		for _, x := range el.Init {
+			b = append(b, x...)
+		}
+
+		return b
+	}
+
+	b = e.Int(b, len(el.Funcs))
+
+	for _, x := range el.Funcs {
+		b = e.Int(b, int(x))
+	}
+
+	return b
+}
+
+func (e *Encoder) DataCountSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, DataCountSection)
+
+	b = e.Int(b, m.DataCount)
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) CodeSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, CodeSection)
+
+	b = e.Int(b, len(m.Code))
+
+	for _, x := range m.Code {
+		b = e.Int(b, len(x))
+		b = append(b, x...)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) DataSection(b []byte, m *Module) []byte {
+	b, st := e.SectionStart(b, DataSection)
+
+	b = e.Int(b, len(m.Data))
+
+	for _, x := range m.Data {
+		b = e.Data(b, x)
+	}
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) Data(b []byte, x Data) []byte {
+	switch {
+	case x.Mode == DataPassive:
+		b = append(b, 1)
+	case x.ExplicitMemory || x.Memory != 0:
+		b = append(b, 2)
+		b = e.Int(b, int(x.Memory))
+	default:
+		b = append(b, 0)
+	}
+
+	if x.Mode == DataActive {
+		b = append(b, x.Expr...)
+	}
+
+	return e.NameBytes(b, x.Init)
+}
+
+// SectionStart appends section id and returns the position
+// the section content starts at which is passed to SectionEnd.
+func (e *LowEncoder) SectionStart(b []byte, id byte) (_ []byte, st int) {
+	b = append(b, id)
+
+	return b, len(b)
+}
+
+// SectionEnd inserts section size before the section content started at st.
+func (e *LowEncoder) SectionEnd(b []byte, st int) []byte {
+	var buf [10]byte
+
+	size := e.Int(buf[:0], len(b)-st)
+
+	b = append(b, size...)
+	copy(b[st+len(size):], b[st:])
+	copy(b[st:], size)
+
+	return b
+}
+
 func (e *LowEncoder) Int(b []byte, v int) []byte {
 	return e.Uint64(b, uint64(v))
 }
@@ -49,10 +433,12 @@ func (e *LowEncoder) Int64(b []byte, v int64) []byte {
 	return b
 }
 
-func (e *LowEncoder) Float64(b []byte, v float64) []byte {
-	x := math.Float64bits(v)
+func (e *LowEncoder) Float32(b []byte, v float32) []byte {
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+}
 
-	return append(b, byte(x), byte(x>>8), byte(x>>16), byte(x>>24), byte(x>>32), byte(x>>40), byte(x>>48), byte(x>>56))
+func (e *LowEncoder) Float64(b []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
 }
 
 func (e *LowEncoder) Name(b []byte, v string) []byte {
@@ -62,6 +448,13 @@ func (e *LowEncoder) Name(b []byte, v string) []byte {
 	return b
 }
 
+func (e *LowEncoder) NameBytes(b []byte, v []byte) []byte {
+	b = e.Int(b, len(v))
+	b = append(b, v...)
+
+	return b
+}
+
 func (e *LowEncoder) BasicType(b []byte, tp byte) []byte {
 	return append(b, tp)
 }
@@ -79,15 +472,52 @@ func (e *LowEncoder) FuncType(b []byte, params, result []byte) []byte {
 	return b
 }
 
+// ResultTypeOf is the same as ResultType but takes typed value types.
+func (e *LowEncoder) ResultTypeOf(b []byte, tp ResultType) []byte {
+	b = e.Int(b, len(tp))
+
+	for _, t := range tp {
+		b = append(b, byte(t))
+	}
+
+	return b
+}
+
+// FuncTypeOf is the same as FuncType but takes FuncType.
+func (e *LowEncoder) FuncTypeOf(b []byte, fn FuncType) []byte {
+	b = append(b, FuncTypeHeader)
+	b = e.ResultTypeOf(b, fn.Params)
+	b = e.ResultTypeOf(b, fn.Result)
+
+	return b
+}
+
+func (e *LowEncoder) BlockType(b []byte, bt BlockType) []byte {
+	return e.Int64(b, int64(bt))
+}
+
 func (e *LowEncoder) Limits(b []byte, lo, hi int) []byte {
-	if hi < 0 {
-		b = append(b, LimitLo)
-		return e.Int(b, lo)
+	return e.MemoryType(b, Limits{Lo: lo, Hi: hi})
+}
+
+// MemoryType encodes memory limits which may be shared.
+func (e *LowEncoder) MemoryType(b []byte, l Limits) []byte {
+	var tp byte = LimitLo
+
+	if l.Hi >= 0 {
+		tp |= LimitLoHi
 	}
 
-	b = append(b, LimitLoHi)
-	b = e.Int(b, lo)
-	b = e.Int(b, hi)
+	if l.Shared {
+		tp |= LimitShared
+	}
+
+	b = append(b, tp)
+	b = e.Int(b, l.Lo)
+
+	if l.Hi >= 0 {
+		b = e.Int(b, l.Hi)
+	}
 
 	return b
 }
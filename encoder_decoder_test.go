@@ -1,9 +1,13 @@
 package wasm
 
 import (
+	"encoding/hex"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLowEncoderDecoder(tb *testing.T) {
@@ -72,6 +76,57 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		}
 	})
 
+	tb.Run("Width", func(tb *testing.T) {
+		u32 := func(b []byte) (int64, int, error) { x, i, err := d.Uint32(b, 0); return int64(x), i, err }
+		u64 := func(b []byte) (int64, int, error) { x, i, err := d.Uint64(b, 0); return int64(x), i, err }
+		s32 := func(b []byte) (int64, int, error) { x, i, err := d.Int32(b, 0); return int64(x), i, err }
+		s33 := func(b []byte) (int64, int, error) { return d.Int33(b, 0) }
+		s64 := func(b []byte) (int64, int, error) { return d.Int64(b, 0) }
+
+		for _, tc := range []struct {
+			dec func([]byte) (int64, int, error)
+			hex string
+			exp int64
+			err error
+		}{
+			{u32, "ffffffff0f", math.MaxUint32, nil},
+			{u32, "8080808000", 0, nil},
+			{u32, "ffffffff1f", 0, ErrOverflow},
+			{u32, "808080808000", 0, ErrOverflow},
+			{u32, "8080", 0, ErrUnexpectedEOF},
+			{u64, "ffffffffffffffffff01", -1, nil},
+			{u64, "ffffffffffffffffff02", 0, ErrOverflow},
+			{u64, "ffffffffffffffffff8100", 0, ErrOverflow},
+			{s32, "7f", -1, nil},
+			{s32, "ffffffff07", math.MaxInt32, nil},
+			{s32, "8080808078", math.MinInt32, nil},
+			{s32, "ffffffff0f", 0, ErrOverflow},
+			{s32, "8080808070", 0, ErrOverflow},
+			{s33, "ffffffff0f", math.MaxUint32, nil},
+			{s33, "8080808070", -1 << 32, nil},
+			{s33, "ffffffff2f", 0, ErrOverflow},
+			{s64, "ffffffffffffffffff00", math.MaxInt64, nil},
+			{s64, "8080808080808080807f", math.MinInt64, nil},
+			{s64, "ffffffffffffffffff01", 0, ErrOverflow},
+		} {
+			b, err := hex.DecodeString(tc.hex)
+			require.NoError(tb, err)
+
+			x, i, err := tc.dec(b)
+			if tc.err != nil {
+				assert.ErrorIs(tb, err, tc.err, tc.hex)
+				assert.Equal(tb, 0, i, tc.hex)
+
+				continue
+			}
+
+			if assert.NoError(tb, err, tc.hex) {
+				assert.Equal(tb, len(b), i, tc.hex)
+				assert.Equal(tb, tc.exp, x, tc.hex)
+			}
+		}
+	})
+
 	tb.Run("Float", func(tb *testing.T) {
 		for _, x := range []float64{0, 1, -1, 100.123456, -100.123456} {
 			b = e.Float64(b[:0], x)
@@ -88,11 +143,51 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		}
 	})
 
+	tb.Run("Float32Bits", func(tb *testing.T) {
+		for _, x := range []uint32{
+			0x00000000, // +0
+			0x80000000, // -0
+			0x7fc00000, // canonical NaN
+			0xffc00001, // negative NaN with payload
+			0x7f800001, // signalling NaN
+			0x7f800000, // +Inf
+			0x3f800000, // 1
+		} {
+			b = e.Float32(append(b[:0], 0xaa), math.Float32frombits(x))
+			assert.Len(tb, b, 5)
+
+			y, i, err := d.Float32(b, 1)
+			assert.NoError(tb, err)
+			assert.Equal(tb, len(b), i)
+			assert.Equal(tb, x, math.Float32bits(y), "%08x", x)
+		}
+	})
+
+	tb.Run("Float64Bits", func(tb *testing.T) {
+		for _, x := range []uint64{
+			0x0000000000000000, // +0
+			0x8000000000000000, // -0
+			0x7ff8000000000000, // canonical NaN
+			0xfff8000000000001, // negative NaN with payload
+			0x7ff0000000000001, // signalling NaN
+			0xfff0000000000000, // -Inf
+			0xc000000000000000, // -2
+		} {
+			b = e.Float64(append(b[:0], 0xaa), math.Float64frombits(x))
+			assert.Len(tb, b, 9)
+
+			y, i, err := d.Float64(b, 1)
+			assert.NoError(tb, err)
+			assert.Equal(tb, len(b), i)
+			assert.Equal(tb, x, math.Float64bits(y), "%016x", x)
+		}
+	})
+
 	tb.Run("Name", func(tb *testing.T) {
 		for _, x := range []string{"", "1", "a", "1qaz", "Hello, 世界"} {
 			b = e.Name(b[:0], x)
 
-			y, i, err := d.Name(b, 0)
+			y, i, err := d.NameString(b, 0)
 			assert.NoError(tb, err)
 			assert.Equal(tb, len(b), i)
 			assert.Equal(tb, x, y)
@@ -122,13 +217,20 @@ func TestLowEncoderDecoder(tb *testing.T) {
 
 	tb.Run("ResultType", func(tb *testing.T) {
 		for _, x := range []ResultType{{I32, I64}, {F32}} {
-			b = e.ResultType(b[:0], x...)
+			b = e.ResultTypeOf(b[:0], x)
 
 			y, i, err := d.ResultType(b, 0, tp[:0])
 			assert.NoError(tb, err)
 			assert.Equal(tb, len(b), i)
 			assert.Equal(tb, x, y)
 
+			raw := make([]byte, len(x))
+			for i, t := range x {
+				raw[i] = byte(t)
+			}
+
+			assert.Equal(tb, b, e.ResultType(nil, raw...))
+
 			if tb.Failed() {
 				tb.Logf("x: %v\nb: %x\ny: %v", x, b, y)
 				break
@@ -140,7 +242,7 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		for _, x := range []FuncType{
 			{Params: ResultType{I32, I64}, Result: ResultType{F32}},
 		} {
-			b = e.FuncType(b[:0], x.Params, x.Result)
+			b = e.FuncTypeOf(b[:0], x)
 
 			y, i, err := d.FuncType(b, 0, fn)
 			assert.NoError(tb, err)
@@ -154,7 +256,7 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		}
 	})
 
-	tb.Run("Limit", func(tb *testing.T) {
+	tb.Run("Limits", func(tb *testing.T) {
 		for _, x := range [][2]int{
 			[2]int{0, -1},
 			[2]int{1, -1},
@@ -162,9 +264,9 @@ func TestLowEncoderDecoder(tb *testing.T) {
 			[2]int{0, 4},
 			[2]int{1, 4},
 		} {
-			b = e.Limit(b[:0], x[0], x[1])
+			b = e.Limits(b[:0], x[0], x[1])
 
-			lo, hi, i, err := d.Limit(b, 0)
+			lo, hi, i, err := d.Limits(b, 0)
 			assert.NoError(tb, err)
 			assert.Equal(tb, len(b), i)
 			assert.Equal(tb, x, [2]int{lo, hi})
@@ -176,6 +278,35 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		}
 	})
 
+	tb.Run("MemoryType", func(tb *testing.T) {
+		for _, x := range []Limits{
+			{Lo: 0, Hi: -1},
+			{Lo: 1, Hi: 4},
+			{Lo: 1, Hi: 4, Shared: true},
+			{Lo: 2, Hi: -1, Shared: true},
+		} {
+			b = e.MemoryType(b[:0], x)
+
+			y, i, err := d.MemoryType(b, 0)
+			assert.NoError(tb, err)
+			assert.Equal(tb, len(b), i)
+			assert.Equal(tb, x, y)
+
+			if tb.Failed() {
+				tb.Logf("x: %v\nb: %x\ny: %v", x, b, y)
+				break
+			}
+		}
+
+		assert.Equal(tb, []byte{0x03, 0x01, 0x10}, e.MemoryType(b[:0], Limits{Lo: 1, Hi: 16, Shared: true}))
+
+		_, _, _, err := d.Limits([]byte{0x03, 0x01, 0x10}, 0)
+		assert.Error(tb, err)
+
+		_, _, err = d.MemoryType([]byte{0x04, 0x01}, 0)
+		assert.Error(tb, err)
+	})
+
 	tb.Run("TableType", func(tb *testing.T) {
 		type TableType struct {
 			tp     byte
@@ -223,3 +354,451 @@ func TestLowEncoderDecoder(tb *testing.T) {
 		}
 	})
 }
+
+func TestDecoderRefGlobals(tb *testing.T) {
+	var (
+		d Decoder
+		e Encoder
+		m Module
+	)
+
+	b, err := hex.DecodeString("0061736d01000000" +
+		"010401600000" + // type () -> ()
+		"03020100" + // function type 0
+		"060b02" + "7000d2000b" + "6f01d06f0b" + // global funcref (ref.func 0), mut externref (ref.null extern)
+		"0a040102000b") // code: empty body
+	require.NoError(tb, err)
+
+	err = d.Module(b, &m)
+	require.NoError(tb, err)
+
+	if assert.Len(tb, m.Global, 2) {
+		assert.Equal(tb, Global{Type: FuncRef, Mut: 0, Expr: Code{RefFunc, 0, End}}, m.Global[0])
+		assert.Equal(tb, Global{Type: ExternRef, Mut: 1, Expr: Code{RefNull, ExternRef, End}}, m.Global[1])
+	}
+
+	assert.Equal(tb, b, e.Module(nil, &m))
+
+	_, _, _, err = d.GlobalType([]byte{0x40, 0}, 0)
+	assert.Error(tb, err)
+
+	_, _, _, err = d.GlobalType([]byte{FuncRef, 2}, 0)
+	assert.Error(tb, err)
+}
+
+func TestImport(tb *testing.T) {
+	var (
+		d Decoder
+		e Encoder
+	)
+
+	imports := make([]Import, 4)
+
+	imports[0].SetFunc(3)
+	imports[1].SetTable(Table{Type: FuncRef, Limits: Limits{Lo: 1, Hi: -1}})
+	imports[2].SetMemory(Limits{Lo: 1, Hi: 10, Shared: true})
+	imports[3].SetGlobal(Global{Type: I64, Mut: 1})
+
+	for j, kind := range []byte{ExternFunc, ExternTable, ExternMemory, ExternGlobal} {
+		im := imports[j]
+		im.Module = []byte("env")
+		im.Name = []byte("x")
+
+		b := e.Import(nil, im)
+
+		r, i, err := d.Import(b, 0, Import{})
+		require.NoError(tb, err)
+		assert.Equal(tb, len(b), i)
+
+		assert.Equal(tb, kind, r.Kind())
+		assert.Equal(tb, "env", string(r.Module))
+		assert.Equal(tb, "x", string(r.Name))
+
+		assert.Equal(tb, im.FuncType(), r.FuncType())
+		assert.Equal(tb, im.Table(), r.Table())
+		assert.Equal(tb, im.Memory(), r.Memory())
+		assert.Equal(tb, im.Global(), r.Global())
+	}
+
+	assert.Equal(tb, Index(3), imports[0].FuncType())
+	assert.Equal(tb, Index(-1), imports[1].FuncType())
+	assert.Equal(tb, Table{Type: FuncRef, Limits: Limits{Lo: 1, Hi: -1}}, imports[1].Table())
+	assert.Equal(tb, Limits{Lo: 1, Hi: 10, Shared: true}, imports[2].Memory())
+	assert.Equal(tb, Global{Type: I64, Mut: 1}, imports[3].Global())
+	assert.Equal(tb, Global{}, imports[2].Global())
+}
+
+func TestElement(tb *testing.T) {
+	var (
+		d  Decoder
+		e  Encoder
+		el Element
+	)
+
+	offset := func(x byte) Code { return Code{I32Const, x, End} }
+
+	for _, tc := range []struct {
+		Hex string
+		Exp Element
+	}{
+		{Hex: "00410a0b0100", Exp: Element{Mode: ElemActive, Type: FuncRef, Expr: offset(10), Funcs: []Index{0}}},
+		{Hex: "0100020001", Exp: Element{Mode: ElemPassive, Type: FuncRef, Funcs: []Index{0, 1}}},
+		{Hex: "020141050b000102", Exp: Element{Mode: ElemActive, Table: 1, Type: FuncRef, Expr: offset(5), Funcs: []Index{2}, ExplicitTable: true}},
+		{Hex: "020041000b000102", Exp: Element{Mode: ElemActive, Table: 0, Type: FuncRef, Expr: offset(0), Funcs: []Index{2}, ExplicitTable: true}},
+		{Hex: "03000100", Exp: Element{Mode: ElemDeclarative, Type: FuncRef, Funcs: []Index{0}}},
+		{Hex: "0441000b02d2000bd0700b", Exp: Element{Mode: ElemActive, Type: FuncRef, Expr: offset(0), Init: []Code{{RefFunc, 0, End}, {RefNull, FuncRef, End}}, InitExprs: true}},
+		{Hex: "0441000b00", Exp: Element{Mode: ElemActive, Type: FuncRef, Expr: offset(0), InitExprs: true}},
+		{Hex: "057001d2010b", Exp: Element{Mode: ElemPassive, Type: FuncRef, Init: []Code{{RefFunc, 1, End}}, InitExprs: true}},
+		{Hex: "057000", Exp: Element{Mode: ElemPassive, Type: FuncRef, InitExprs: true}},
+		{Hex: "060241000b6f01d06f0b", Exp: Element{Mode: ElemActive, Table: 2, Type: ExternRef, Expr: offset(0), Init: []Code{{RefNull, ExternRef, End}}, ExplicitTable: true, InitExprs: true}},
+		{Hex: "060041000b7000", Exp: Element{Mode: ElemActive, Type: FuncRef, Expr: offset(0), ExplicitTable: true, InitExprs: true}},
+		{Hex: "077001d2030b", Exp: Element{Mode: ElemDeclarative, Type: FuncRef, Init: []Code{{RefFunc, 3, End}}, InitExprs: true}},
+		{Hex: "077000", Exp: Element{Mode: ElemDeclarative, Type: FuncRef, InitExprs: true}},
+	} {
+		b, err := hex.DecodeString(tc.Hex)
+		require.NoError(tb, err)
+
+		var i int
+
+		el, i, err = d.Element(b, 0, el)
+		require.NoError(tb, err, "%v", tc.Hex)
+		assert.Equal(tb, len(b), i)
+
+		if len(tc.Exp.Funcs) == 0 {
+			tc.Exp.Funcs = el.Funcs[:0]
+		}
+
+		if len(tc.Exp.Init) == 0 {
+			tc.Exp.Init = el.Init[:0]
+		}
+
+		if len(tc.Exp.Expr) == 0 {
+			tc.Exp.Expr = el.Expr[:0]
+		}
+
+		assert.Equal(tb, tc.Exp, el, "%v", tc.Hex)
+
+		assert.Equal(tb, tc.Hex, hex.EncodeToString(e.Element(nil, el)))
+	}
+
+	hand := Element{Mode: ElemActive, Type: FuncRef, Expr: offset(0), Funcs: []Index{2}}
+	assert.Equal(tb, "0041000b0102", hex.EncodeToString(e.Element(nil, hand)))
+
+	for _, x := range []string{"08", "0101", "0141000b0100"} {
+		b, err := hex.DecodeString(x)
+		require.NoError(tb, err)
+
+		_, _, err = d.Element(b, 0, Element{})
+		assert.Error(tb, err, "%v", x)
+	}
+}
+
+func TestData(tb *testing.T) {
+	var (
+		d Decoder
+		e Encoder
+		x Data
+	)
+
+	for _, tc := range []struct {
+		Hex string
+		Exp Data
+	}{
+		{Hex: "0041100b026869", Exp: Data{Mode: DataActive, Expr: Code{I32Const, 0x10, End}, Init: []byte("hi")}},
+		{Hex: "0103616263", Exp: Data{Mode: DataPassive, Init: []byte("abc")}},
+		{Hex: "020141000b00", Exp: Data{Mode: DataActive, Memory: 1, Expr: Code{I32Const, 0, End}, Init: []byte{}, ExplicitMemory: true}},
+		{Hex: "020041000b016a", Exp: Data{Mode: DataActive, Memory: 0, Expr: Code{I32Const, 0, End}, Init: []byte("j"), ExplicitMemory: true}},
+	} {
+		b, err := hex.DecodeString(tc.Hex)
+		require.NoError(tb, err)
+
+		var i int
+
+		x, i, err = d.Data(b, 0, x)
+		require.NoError(tb, err, "%v", tc.Hex)
+		assert.Equal(tb, len(b), i)
+
+		if len(tc.Exp.Expr) == 0 {
+			tc.Exp.Expr = x.Expr[:0]
+		}
+
+		assert.Equal(tb, tc.Exp, x, "%v", tc.Hex)
+
+		assert.Equal(tb, tc.Hex, hex.EncodeToString(e.Data(nil, x)))
+	}
+
+	_, _, err := d.Data([]byte{3}, 0, Data{})
+	assert.Error(tb, err)
+
+	var m Module
+
+	b, err := hex.DecodeString("0061736d01000000" +
+		"0c0102" + // data count 2
+		"0b050101010178") // one passive segment
+	require.NoError(tb, err)
+
+	err = d.Module(b, &m)
+	assert.ErrorContains(tb, err, "data count mismatch")
+
+	b, err = hex.DecodeString("0061736d01000000" +
+		"0c0101") // data count 1, no data section
+	require.NoError(tb, err)
+
+	err = d.Module(b, &m)
+	assert.ErrorContains(tb, err, "data count mismatch")
+}
+
+func TestModuleRoundTrip(tb *testing.T) {
+	header := "0061736d01000000"
+
+	sections := []string{
+		"00080566697273740102",                         // custom "first"
+		"010a0260027f7f017f600000",                     // type (i32 i32) -> i32, () -> ()
+		"02140203656e7603616464000003656e760167037f00", // import env.add func 0, env.g global i32
+		"03020101",                         // function type 1
+		"04050170010101",                   // table funcref 1 1
+		"0503010001",                       // memory 1
+		"0606017f01412a0b",                 // global mut i32 (i32.const 42)
+		"070e02046d61696e0001036d656d0200", // export main func 1, mem memory 0
+		"080101",                           // start 1
+		"0907010041000b0101",               // element (i32.const 0) [1]
+		"0c0101",                           // data count 1
+		"0a0b0109004101410210001a0b",       // code: i32.const 1, i32.const 2, call 0, drop
+		"0b08010041080b026869",             // data (i32.const 8) "hi"
+		"0009046c61737464617461",           // custom "last"
+	}
+
+	b, err := hex.DecodeString(header + strings.Join(sections, ""))
+	require.NoError(tb, err)
+
+	var (
+		d Decoder
+		e Encoder
+		m Module
+	)
+
+	err = d.Module(b, &m)
+	require.NoError(tb, err)
+
+	assert.True(tb, m.HasDataCount)
+	assert.Equal(tb, 1, m.DataCount)
+	assert.True(tb, m.HasStart)
+	assert.Equal(tb, Index(1), m.Start)
+	assert.Len(tb, m.Custom, 2)
+
+	r := e.Module(nil, &m)
+	assert.Equal(tb, b, r)
+
+	m.Sections = nil
+
+	last := len(sections) - 1
+
+	exp, err := hex.DecodeString(header + strings.Join(sections[1:last], "") + sections[0] + sections[last])
+	require.NoError(tb, err)
+
+	r = e.Module(r[:0], &m)
+	assert.Equal(tb, hex.EncodeToString(exp), hex.EncodeToString(r))
+}
+
+func TestModuleEncodeZero(tb *testing.T) {
+	var e Encoder
+
+	b := e.Module(nil, &Module{Version: 1})
+	assert.Equal(tb, "0061736d01000000", hex.EncodeToString(b))
+
+	var d Decoder
+	var m Module
+
+	err := d.Module(b, &m)
+	require.NoError(tb, err)
+	assert.NoError(tb, Validate(&m))
+	assert.False(tb, m.HasStart)
+	assert.False(tb, m.HasDataCount)
+}
+
+func TestModuleEncodeAdded(tb *testing.T) {
+	header := "0061736d01000000"
+
+	b, err := hex.DecodeString(header +
+		"00080566697273740102" + // custom "first"
+		"010401600000" + // type () -> ()
+		"03020100" + // function type 0
+		"0a040102000b") // code: nop
+	require.NoError(tb, err)
+
+	var (
+		d Decoder
+		e Encoder
+		m Module
+	)
+
+	err = d.Module(b, &m)
+	require.NoError(tb, err)
+
+	m.Export = append(m.Export, Export{Name: []byte("main"), ExportType: ExternFunc, Index: 0})
+	m.Custom = append(m.Custom, Custom{Name: []byte("last")})
+
+	exp := header +
+		"00080566697273740102" +
+		"010401600000" +
+		"03020100" +
+		"070801046d61696e0000" + // export main func 0
+		"0a040102000b" +
+		"0005046c617374" // custom "last"
+
+	r := e.Module(nil, &m)
+	assert.Equal(tb, exp, hex.EncodeToString(r))
+
+	m.Custom = m.Custom[:0]
+	m.Sections = append(m.Sections, CustomSection)
+
+	exp = header +
+		"010401600000" +
+		"03020100" +
+		"070801046d61696e0000" +
+		"0a040102000b"
+
+	r = e.Module(r[:0], &m)
+	assert.Equal(tb, exp, hex.EncodeToString(r))
+}
+
+func TestModuleDecodeError(tb *testing.T) {
+	for _, tc := range []struct {
+		hex    string
+		offset int
+	}{
+		{"0061736e01000000", 0},
+		{"0061736d01000000" + "010401610000", 11},                                // bad func type header
+		{"0061736d01000000" + "0105", 8},                                         // section is out of bounds
+		{"0061736d010000000105016000017f03020104000a0a01080041000c0042000b", 19}, // custom section name is out of bounds
+	} {
+		b, err := hex.DecodeString(tc.hex)
+		require.NoError(tb, err)
+
+		var d Decoder
+		var m Module
+
+		err = d.Module(b, &m)
+
+		var derr DecodeError
+		if assert.ErrorAs(tb, err, &derr, tc.hex) {
+			assert.Equal(tb, tc.offset, derr.Offset, tc.hex)
+		}
+	}
+}
+
+func TestModuleSectionOrder(tb *testing.T) {
+	header := "0061736d01000000"
+
+	for _, tc := range []struct {
+		sections []string
+		err      SectionOrderError
+	}{
+		{[]string{"0503010001", "010a0260027f7f017f600000"}, SectionOrderError{ID: TypeSection, Prev: MemorySection, Offset: 13}},
+		{[]string{"0503010001", "0503010002"}, SectionOrderError{ID: MemorySection, Prev: MemorySection, Offset: 13}},
+		{[]string{"0a0401020000", "0c0100"}, SectionOrderError{ID: DataCountSection, Prev: CodeSection, Offset: 14}},
+	} {
+		b, err := hex.DecodeString(header + strings.Join(tc.sections, ""))
+		require.NoError(tb, err)
+
+		var d Decoder
+		var m Module
+
+		err = d.Module(b, &m)
+
+		var serr SectionOrderError
+		if assert.ErrorAs(tb, err, &serr, "%v", tc.sections) {
+			assert.Equal(tb, tc.err, serr)
+		}
+
+		d.Lenient = true
+
+		err = d.Module(b, &m)
+		assert.NoError(tb, err, "%v", tc.sections)
+	}
+
+	// custom sections are allowed anywhere
+	b, err := hex.DecodeString(header + "0503010001" + "00020161" + "0c0100" + "00020162" + "0a0401020000")
+	require.NoError(tb, err)
+
+	var d Decoder
+	var m Module
+
+	err = d.Module(b, &m)
+	require.NoError(tb, err)
+	assert.Len(tb, m.Custom, 2)
+	assert.Equal(tb, []Limits{{Lo: 1, Hi: -1}}, m.Memory)
+}
+
+func TestNames(tb *testing.T) {
+	var (
+		e Encoder
+		d Decoder
+	)
+
+	n := Names{
+		Module: []byte("mod"),
+		Funcs: NameMap{
+			{Index: 0, Name: []byte("imported")},
+			{Index: 2, Name: []byte("main")},
+		},
+		Locals: IndirectNameMap{
+			{Index: 2, Names: NameMap{{Index: 0, Name: []byte("x")}, {Index: 1, Name: []byte("y")}}},
+		},
+		Globals: NameMap{{Index: 0, Name: []byte("sp")}},
+		Data:    NameMap{{Index: 0, Name: []byte(".rodata")}},
+	}
+
+	b := e.Names(nil, &n)
+
+	var r Names
+
+	i, err := d.Names(b, 0, &r)
+	require.NoError(tb, err)
+	assert.Equal(tb, len(b), i)
+	assert.Equal(tb, n, r)
+
+	assert.Equal(tb, []byte("main"), r.Funcs.Name(2))
+	assert.Nil(tb, r.Funcs.Name(1))
+	assert.Equal(tb, []byte("y"), r.Locals.Name(2, 1))
+	assert.Nil(tb, r.Locals.Name(0, 0))
+
+	// unknown subsection is skipped
+	b = append(b, 0x7f, 0x02, 0xaa, 0xbb)
+
+	_, err = d.Names(b, 0, &r)
+	require.NoError(tb, err)
+	assert.Equal(tb, n, r)
+
+	m := &Module{Custom: []Custom{{Name: []byte("other")}, {Name: []byte(NameSectionName), Data: b}}}
+
+	ok, err := d.ModuleNames(m, &r)
+	require.NoError(tb, err)
+	assert.True(tb, ok)
+	assert.Equal(tb, n, r)
+
+	ok, err = d.ModuleNames(&Module{}, &r)
+	require.NoError(tb, err)
+	assert.False(tb, ok)
+
+	for _, tc := range []struct {
+		hex      string
+		indirect bool
+	}{
+		{"02010161000162", false},    // names 1, 0
+		{"02010161010162", false},    // names 1, 1
+		{"0201000000", true},         // locals of 1, 0
+		{"010002000161000162", true}, // locals 0, 0 of 0
+	} {
+		b, err := hex.DecodeString(tc.hex)
+		require.NoError(tb, err)
+
+		if tc.indirect {
+			_, _, err = d.IndirectNameMap(b, 0, nil)
+		} else {
+			_, _, err = d.NameMap(b, 0, nil)
+		}
+
+		assert.ErrorContains(tb, err, "ascending", tc.hex)
+	}
+}
@@ -0,0 +1,160 @@
+package wasm
+
+// FE ext opcodes, threads and atomics
+const (
+	FEMemoryAtomicNotify = 0x00
+	FEMemoryAtomicWait32 = 0x01
+	FEMemoryAtomicWait64 = 0x02
+	FEAtomicFence        = 0x03
+
+	FEI32AtomicLoad    = 0x10
+	FEI64AtomicLoad    = 0x11
+	FEI32AtomicLoad8U  = 0x12
+	FEI32AtomicLoad16U = 0x13
+	FEI64AtomicLoad8U  = 0x14
+	FEI64AtomicLoad16U = 0x15
+	FEI64AtomicLoad32U = 0x16
+
+	FEI32AtomicStore   = 0x17
+	FEI64AtomicStore   = 0x18
+	FEI32AtomicStore8  = 0x19
+	FEI32AtomicStore16 = 0x1a
+	FEI64AtomicStore8  = 0x1b
+	FEI64AtomicStore16 = 0x1c
+	FEI64AtomicStore32 = 0x1d
+
+	FEI32AtomicRmwAdd    = 0x1e
+	FEI64AtomicRmwAdd    = 0x1f
+	FEI32AtomicRmw8AddU  = 0x20
+	FEI32AtomicRmw16AddU = 0x21
+	FEI64AtomicRmw8AddU  = 0x22
+	FEI64AtomicRmw16AddU = 0x23
+	FEI64AtomicRmw32AddU = 0x24
+
+	FEI32AtomicRmwSub    = 0x25
+	FEI64AtomicRmwSub    = 0x26
+	FEI32AtomicRmw8SubU  = 0x27
+	FEI32AtomicRmw16SubU = 0x28
+	FEI64AtomicRmw8SubU  = 0x29
+	FEI64AtomicRmw16SubU = 0x2a
+	FEI64AtomicRmw32SubU = 0x2b
+
+	FEI32AtomicRmwAnd    = 0x2c
+	FEI64AtomicRmwAnd    = 0x2d
+	FEI32AtomicRmw8AndU  = 0x2e
+	FEI32AtomicRmw16AndU = 0x2f
+	FEI64AtomicRmw8AndU  = 0x30
+	FEI64AtomicRmw16AndU = 0x31
+	FEI64AtomicRmw32AndU = 0x32
+
+	FEI32AtomicRmwOr    = 0x33
+	FEI64AtomicRmwOr    = 0x34
+	FEI32AtomicRmw8OrU  = 0x35
+	FEI32AtomicRmw16OrU = 0x36
+	FEI64AtomicRmw8OrU  = 0x37
+	FEI64AtomicRmw16OrU = 0x38
+	FEI64AtomicRmw32OrU = 0x39
+
+	FEI32AtomicRmwXor    = 0x3a
+	FEI64AtomicRmwXor    = 0x3b
+	FEI32AtomicRmw8XorU  = 0x3c
+	FEI32AtomicRmw16XorU = 0x3d
+	FEI64AtomicRmw8XorU  = 0x3e
+	FEI64AtomicRmw16XorU = 0x3f
+	FEI64AtomicRmw32XorU = 0x40
+
+	FEI32AtomicRmwXchg    = 0x41
+	FEI64AtomicRmwXchg    = 0x42
+	FEI32AtomicRmw8XchgU  = 0x43
+	FEI32AtomicRmw16XchgU = 0x44
+	FEI64AtomicRmw8XchgU  = 0x45
+	FEI64AtomicRmw16XchgU = 0x46
+	FEI64AtomicRmw32XchgU = 0x47
+
+	FEI32AtomicRmwCmpxchg    = 0x48
+	FEI64AtomicRmwCmpxchg    = 0x49
+	FEI32AtomicRmw8CmpxchgU  = 0x4a
+	FEI32AtomicRmw16CmpxchgU = 0x4b
+	FEI64AtomicRmw8CmpxchgU  = 0x4c
+	FEI64AtomicRmw16CmpxchgU = 0x4d
+	FEI64AtomicRmw32CmpxchgU = 0x4e
+)
+
+var feNames = [...]string{
+	FEMemoryAtomicNotify: "MemoryAtomicNotify",
+	FEMemoryAtomicWait32: "MemoryAtomicWait32",
+	FEMemoryAtomicWait64: "MemoryAtomicWait64",
+	FEAtomicFence:        "AtomicFence",
+
+	FEI32AtomicLoad:    "I32AtomicLoad",
+	FEI64AtomicLoad:    "I64AtomicLoad",
+	FEI32AtomicLoad8U:  "I32AtomicLoad8U",
+	FEI32AtomicLoad16U: "I32AtomicLoad16U",
+	FEI64AtomicLoad8U:  "I64AtomicLoad8U",
+	FEI64AtomicLoad16U: "I64AtomicLoad16U",
+	FEI64AtomicLoad32U: "I64AtomicLoad32U",
+
+	FEI32AtomicStore:   "I32AtomicStore",
+	FEI64AtomicStore:   "I64AtomicStore",
+	FEI32AtomicStore8:  "I32AtomicStore8",
+	FEI32AtomicStore16: "I32AtomicStore16",
+	FEI64AtomicStore8:  "I64AtomicStore8",
+	FEI64AtomicStore16: "I64AtomicStore16",
+	FEI64AtomicStore32: "I64AtomicStore32",
+
+	FEI32AtomicRmwAdd:    "I32AtomicRmwAdd",
+	FEI64AtomicRmwAdd:    "I64AtomicRmwAdd",
+	FEI32AtomicRmw8AddU:  "I32AtomicRmw8AddU",
+	FEI32AtomicRmw16AddU: "I32AtomicRmw16AddU",
+	FEI64AtomicRmw8AddU:  "I64AtomicRmw8AddU",
+	FEI64AtomicRmw16AddU: "I64AtomicRmw16AddU",
+	FEI64AtomicRmw32AddU: "I64AtomicRmw32AddU",
+
+	FEI32AtomicRmwSub:    "I32AtomicRmwSub",
+	FEI64AtomicRmwSub:    "I64AtomicRmwSub",
+	FEI32AtomicRmw8SubU:  "I32AtomicRmw8SubU",
+	FEI32AtomicRmw16SubU: "I32AtomicRmw16SubU",
+	FEI64AtomicRmw8SubU:  "I64AtomicRmw8SubU",
+	FEI64AtomicRmw16SubU: "I64AtomicRmw16SubU",
+	FEI64AtomicRmw32SubU: "I64AtomicRmw32SubU",
+
+	FEI32AtomicRmwAnd:    "I32AtomicRmwAnd",
+	FEI64AtomicRmwAnd:    "I64AtomicRmwAnd",
+	FEI32AtomicRmw8AndU:  "I32AtomicRmw8AndU",
+	FEI32AtomicRmw16AndU: "I32AtomicRmw16AndU",
+	FEI64AtomicRmw8AndU:  "I64AtomicRmw8AndU",
+	FEI64AtomicRmw16AndU: "I64AtomicRmw16AndU",
+	FEI64AtomicRmw32AndU: "I64AtomicRmw32AndU",
+
+	FEI32AtomicRmwOr:    "I32AtomicRmwOr",
+	FEI64AtomicRmwOr:    "I64AtomicRmwOr",
+	FEI32AtomicRmw8OrU:  "I32AtomicRmw8OrU",
+	FEI32AtomicRmw16OrU: "I32AtomicRmw16OrU",
+	FEI64AtomicRmw8OrU:  "I64AtomicRmw8OrU",
+	FEI64AtomicRmw16OrU: "I64AtomicRmw16OrU",
+	FEI64AtomicRmw32OrU: "I64AtomicRmw32OrU",
+
+	FEI32AtomicRmwXor:    "I32AtomicRmwXor",
+	FEI64AtomicRmwXor:    "I64AtomicRmwXor",
+	FEI32AtomicRmw8XorU:  "I32AtomicRmw8XorU",
+	FEI32AtomicRmw16XorU: "I32AtomicRmw16XorU",
+	FEI64AtomicRmw8XorU:  "I64AtomicRmw8XorU",
+	FEI64AtomicRmw16XorU: "I64AtomicRmw16XorU",
+	FEI64AtomicRmw32XorU: "I64AtomicRmw32XorU",
+
+	FEI32AtomicRmwXchg:    "I32AtomicRmwXchg",
+	FEI64AtomicRmwXchg:    "I64AtomicRmwXchg",
+	FEI32AtomicRmw8XchgU:  "I32AtomicRmw8XchgU",
+	FEI32AtomicRmw16XchgU: "I32AtomicRmw16XchgU",
+	FEI64AtomicRmw8XchgU:  "I64AtomicRmw8XchgU",
+	FEI64AtomicRmw16XchgU: "I64AtomicRmw16XchgU",
+	FEI64AtomicRmw32XchgU: "I64AtomicRmw32XchgU",
+
+	FEI32AtomicRmwCmpxchg:    "I32AtomicRmwCmpxchg",
+	FEI64AtomicRmwCmpxchg:    "I64AtomicRmwCmpxchg",
+	FEI32AtomicRmw8CmpxchgU:  "I32AtomicRmw8CmpxchgU",
+	FEI32AtomicRmw16CmpxchgU: "I32AtomicRmw16CmpxchgU",
+	FEI64AtomicRmw8CmpxchgU:  "I64AtomicRmw8CmpxchgU",
+	FEI64AtomicRmw16CmpxchgU: "I64AtomicRmw16CmpxchgU",
+	FEI64AtomicRmw32CmpxchgU: "I64AtomicRmw32CmpxchgU",
+}
@@ -1,6 +1,7 @@
 package wasm
 
 import (
+	"encoding/binary"
 	"fmt"
 
 	"tlog.app/go/errors"
@@ -12,6 +13,56 @@ type (
 		LowDecoder
 	}
 
+	// Instruction is a decoded instruction with its immediate arguments.
+	// Only the fields relevant to the Opcode are set.
+	Instruction struct {
+		Opcode Opcode
+		Ext    uint32 // prefixed instruction sub-opcode
+		Offset int    // opcode position in the code
+
+		BlockType BlockType // Block, Loop, If
+
+		Label  Index   // Br, BrIf label and BrTable default label
+		Labels []Index // BrTable labels
+
+		Index  Index // Call and RefFunc function, CallIndir type, LocalGet.. and GlobalGet.. index, data or elem segment
+		Table  Index // CallIndir, TableGet, TableSet and other table instructions table, TableCopy destination
+		Memory Index // MemorySize, MemoryGrow and bulk memory instructions memory, MemoryCopy destination
+		Src    Index // TableCopy and MemoryCopy source
+
+		MemArg MemArg
+		Lane   byte     // SIMD lane index
+		V128   [16]byte // V128Const value, I8x16Shuffle lanes
+
+		RefType Type       // RefNull
+		Types   ResultType // SelectT
+
+		I32 int32
+		I64 int64
+		F32 float32
+		F64 float64
+	}
+
+	// FCOpcode is a FCExt prefixed instruction sub-opcode.
+	FCOpcode uint32
+
+	// FDOpcode is a FDExt prefixed (SIMD) instruction sub-opcode.
+	FDOpcode uint32
+
+	// FEOpcode is a FEExt prefixed (threads) instruction sub-opcode.
+	FEOpcode uint32
+
+	// BlockType is a Block, Loop or If result type.
+	// It's stored the way it's encoded, as s33.
+	// Non-negative values are type indexes, BlockEmpty means no result,
+	// other negative values are single value types.
+	BlockType int64
+
+	MemArg struct {
+		Align  int
+		Offset int
+	}
+
 	UnsupportedOpcodeError struct {
 		Opcode Opcode
 		Args   []byte
@@ -38,8 +89,9 @@ const (
 	Call      = 0x10
 	CallIndir = 0x11
 
-	Drop   = 0x1a
-	Select = 0x1b
+	Drop    = 0x1a
+	Select  = 0x1b
+	SelectT = 0x1c
 
 	LocalGet  = 0x20
 	LocalSet  = 0x21
@@ -47,6 +99,9 @@ const (
 	GlobalGet = 0x23
 	GlobalSet = 0x24
 
+	TableGet = 0x25
+	TableSet = 0x26
+
 	I32Load    = 0x28
 	I64Load    = 0x29
 	F32Load    = 0x2a
@@ -185,86 +240,240 @@ const (
 	F64Max      = 0xa5
 	F64CopySign = 0xa6
 
+	I32WrapI64     = 0xa7
+	I32TruncF32S   = 0xa8
+	I32TruncF32U   = 0xa9
+	I32TruncF64S   = 0xaa
+	I32TruncF64U   = 0xab
+	I64ExtendI32S  = 0xac
+	I64ExtendI32U  = 0xad
+	I64TruncF32S   = 0xae
+	I64TruncF32U   = 0xaf
+	I64TruncF64S   = 0xb0
+	I64TruncF64U   = 0xb1
+	F32ConvertI32S = 0xb2
+	F32ConvertI32U = 0xb3
+	F32ConvertI64S = 0xb4
+	F32ConvertI64U = 0xb5
+	F32DemoteF64   = 0xb6
+	F64ConvertI32S = 0xb7
+	F64ConvertI32U = 0xb8
+	F64ConvertI64S = 0xb9
+	F64ConvertI64U = 0xba
+	F64PromoteF32  = 0xbb
+
+	I32ReinterpretF32 = 0xbc
+	I64ReinterpretF64 = 0xbd
+	F32ReinterpretI32 = 0xbe
+	F64ReinterpretI64 = 0xbf
+
+	I32Extend8S  = 0xc0
+	I32Extend16S = 0xc1
+	I64Extend8S  = 0xc2
+	I64Extend16S = 0xc3
+	I64Extend32S = 0xc4
+
+	RefNull   = 0xd0
+	RefIsNull = 0xd1
+	RefFunc   = 0xd2
+
 	FCExt = 0xfc
+	FDExt = 0xfd
+	FEExt = 0xfe
 )
 
+// BlockEmpty is a BlockType with no params and no results.
+const BlockEmpty BlockType = -0x40
+
 // FC ext opcodes
 const (
+	FCI32TruncSatF32S = 0x00
+	FCI32TruncSatF32U = 0x01
+	FCI32TruncSatF64S = 0x02
+	FCI32TruncSatF64U = 0x03
+	FCI64TruncSatF32S = 0x04
+	FCI64TruncSatF32U = 0x05
+	FCI64TruncSatF64S = 0x06
+	FCI64TruncSatF64U = 0x07
+
+	FCMemoryInit = 0x08
+	FCDataDrop   = 0x09
 	FCMemoryCopy = 0x0a
 	FCMemoryFill = 0x0b
+
+	FCTableInit = 0x0c
+	FCElemDrop  = 0x0d
+	FCTableCopy = 0x0e
+	FCTableGrow = 0x0f
+	FCTableSize = 0x10
+	FCTableFill = 0x11
 )
 
 func (d *InstructionsDecoder) Expr(b []byte, st int) (code []byte, i int, err error) {
+	var in Instruction
+
 	i = st
 	depth := 0
 
 	for i < len(b) {
-		opst := i
-		op := Opcode(b[i])
-		i++
+		in, i, err = d.Instruction(b, i, in)
+		if err != nil {
+			return nil, i, err
+		}
 
-		switch {
-		case op <= Nop || op == Else || op == Ret:
-		case op == Block || op == Loop || op == If:
+		switch in.Opcode {
+		case Block, Loop, If:
 			depth++
-		case op == End:
+		case End:
 			depth--
-		case op == Br || op == BrIf:
-			_, i, err = d.Int64(b, i)
-		case op == BrTable:
-			var l int
-			l, i, err = d.Int(b, i)
-			if err != nil {
-				return nil, opst, err
-			}
+		}
 
-			for j := 0; j < l+1; j++ {
-				_, i, err = d.Int(b, i)
-				if err != nil {
-					return nil, opst, err
-				}
-			}
-		case op == Call:
-			_, i, err = d.Int64(b, i)
-		case op == CallIndir:
-			_, i, err = d.Int64(b, i)
-			i++
-		case op == Drop || op == Select:
-		case op >= LocalGet && op <= GlobalSet:
-			_, i, err = d.Int64(b, i)
-		case op >= I32Load && op <= I64Store32:
-			_, i, err = d.Int64(b, i)
-			if err != nil {
-				return nil, opst, err
-			}
+		if depth < 0 {
+			return b[st:i], i, nil
+		}
+	}
+
+	return nil, st, ErrUnexpectedEOF
+}
+
+// Instruction decodes one instruction with its immediates starting at st.
+// buf is reused to avoid allocations.
+func (d *InstructionsDecoder) Instruction(b []byte, st int, buf Instruction) (in Instruction, i int, err error) {
+	in = Instruction{
+		Labels: buf.Labels[:0],
+		Types:  buf.Types[:0],
+		Offset: st,
+	}
 
-			_, i, err = d.Int64(b, i)
+	op, i, err := d.Byte(b, st)
+	if err != nil {
+		return in, st, err
+	}
+
+	in.Opcode = Opcode(op)
+
+	switch op := in.Opcode; {
+	case op <= Nop || op == Else || op == Ret:
+	case op == Block || op == Loop || op == If:
+		in.BlockType, i, err = d.BlockType(b, i)
+	case op == End:
+	case op == Br || op == BrIf:
+		in.Label, i, err = d.index(b, i)
+	case op == BrTable:
+		var l int
+		l, i, err = d.Int(b, i)
+		if err != nil {
+			return in, st, err
+		}
+
+		var x Index
+
+		for j := 0; j < l; j++ {
+			x, i, err = d.index(b, i)
 			if err != nil {
-				return nil, opst, err
+				return in, st, err
 			}
-		case op == MemorySize || op == MemoryGrow:
-		case op >= I32Const && op <= F64Const:
-			_, i, err = d.Int64(b, i)
-		case op >= I32EqZ && op <= F64CopySign:
-		case op == FCExt:
-			i, err = d.fcExt(b, opst)
-		default:
-			err = UnsupportedOpcodeError{Opcode: op}
-			err = errors.Wrap(err, "at pos 0x%x", opst)
+
+			in.Labels = append(in.Labels, x)
 		}
 
-		tlog.V("opcode").Printw("opcode", "i", tlog.NextAsHex, opst, "op", op, "code", tlog.NextAsHex, b[opst:i])
+		in.Label, i, err = d.index(b, i)
+	case op == Call:
+		in.Index, i, err = d.index(b, i)
+	case op == CallIndir:
+		in.Index, i, err = d.index(b, i)
+		if err != nil {
+			return in, st, err
+		}
 
+		in.Table, i, err = d.index(b, i)
+	case op == Drop || op == Select:
+	case op == SelectT:
+		var l int
+		l, i, err = d.Int(b, i)
 		if err != nil {
-			return nil, opst, err
+			return in, st, err
 		}
 
-		if depth < 0 {
-			return b[st:i], i, nil
+		var tp Type
+
+		for j := 0; j < l; j++ {
+			tp, i, err = d.ValueType(b, i)
+			if err != nil {
+				return in, st, err
+			}
+
+			in.Types = append(in.Types, tp)
 		}
+	case op >= LocalGet && op <= GlobalSet:
+		in.Index, i, err = d.index(b, i)
+	case op == TableGet || op == TableSet:
+		in.Table, i, err = d.index(b, i)
+	case op >= I32Load && op <= I64Store32:
+		in.MemArg, i, err = d.MemArg(b, i)
+	case op == MemorySize || op == MemoryGrow:
+		in.Memory, i, err = d.index(b, i)
+	case op == I32Const:
+		in.I32, i, err = d.Int32(b, i)
+	case op == I64Const:
+		in.I64, i, err = d.Int64(b, i)
+	case op == F32Const:
+		in.F32, i, err = d.Float32(b, i)
+	case op == F64Const:
+		in.F64, i, err = d.Float64(b, i)
+	case op >= I32EqZ && op <= I64Extend32S:
+	case op == RefNull:
+		in.RefType, i, err = d.RefType(b, i)
+	case op == RefIsNull:
+	case op == RefFunc:
+		in.Index, i, err = d.index(b, i)
+	case op == FCExt:
+		in, i, err = d.fcExt(b, st, in)
+	case op == FDExt:
+		in, i, err = d.fdExt(b, st, in)
+	case op == FEExt:
+		in, i, err = d.feExt(b, st, in)
+	default:
+		err = UnsupportedOpcodeError{Opcode: op}
+		err = errors.Wrap(err, "at pos 0x%x", st)
 	}
 
-	return nil, st, ErrUnexpectedEOF
+	if err != nil {
+		return in, st, err
+	}
+
+	tlog.V("opcode").Printw("opcode", "i", tlog.NextAsHex, st, "op", in.Opcode, "code", tlog.NextAsHex, b[st:i])
+
+	return in, i, nil
+}
+
+func (d *InstructionsDecoder) BlockType(b []byte, st int) (bt BlockType, i int, err error) {
+	x, i, err := d.Int33(b, st)
+	if err != nil {
+		return 0, st, errors.Wrap(err, "block type")
+	}
+
+	bt = BlockType(x)
+
+	if x < 0 && (i != st+1 || bt != BlockEmpty && !valueType(b[st])) {
+		return 0, st, errors.New("unsupported block type: 0x%02x", b[st])
+	}
+
+	return bt, i, nil
+}
+
+func (d *InstructionsDecoder) MemArg(b []byte, st int) (m MemArg, i int, err error) {
+	m.Align, i, err = d.Int(b, st)
+	if err != nil {
+		return m, st, errors.Wrap(err, "align")
+	}
+
+	m.Offset, i, err = d.Int(b, i)
+	if err != nil {
+		return m, st, errors.Wrap(err, "offset")
+	}
+
+	return m, i, nil
 }
 
 func (d *InstructionsDecoder) Func(b []byte, buf FuncCode) (f FuncCode, err error) {
@@ -308,33 +517,335 @@ func (d *InstructionsDecoder) Func(b []byte, buf FuncCode) (f FuncCode, err erro
 	return f, nil
 }
 
-func (d *InstructionsDecoder) fcExt(b []byte, st int) (i int, err error) {
+func (d *InstructionsDecoder) fcExt(b []byte, st int, in Instruction) (_ Instruction, i int, err error) {
 	op, i, err := d.Byte(b, st)
 	if err != nil {
-		return
+		return in, st, err
 	}
 	if op != FCExt {
-		return st, errors.New("fc ext expected")
+		return in, st, errors.New("fc ext expected")
+	}
+
+	ext, i, err := d.Uint32(b, i)
+	if err != nil {
+		return in, st, errors.Wrap(err, "fc ext opcode")
+	}
+
+	if ext > FCTableFill {
+		return in, st, UnsupportedOpcodeError{Opcode: FCExt, Args: b[st+1 : i]}
+	}
+
+	in.Ext = ext
+
+	switch in.Ext {
+	case FCI32TruncSatF32S, FCI32TruncSatF32U, FCI32TruncSatF64S, FCI32TruncSatF64U,
+		FCI64TruncSatF32S, FCI64TruncSatF32U, FCI64TruncSatF64S, FCI64TruncSatF64U:
+	case FCMemoryInit:
+		in.Index, i, err = d.index(b, i)
+		if err != nil {
+			return in, st, errors.Wrap(err, "data index")
+		}
+
+		in.Memory, i, err = d.index(b, i)
+	case FCDataDrop, FCElemDrop:
+		in.Index, i, err = d.index(b, i)
+	case FCMemoryCopy:
+		in.Memory, i, err = d.index(b, i)
+		if err != nil {
+			return in, st, errors.Wrap(err, "dst memory")
+		}
+
+		in.Src, i, err = d.index(b, i)
+	case FCMemoryFill:
+		in.Memory, i, err = d.index(b, i)
+	case FCTableInit:
+		in.Index, i, err = d.index(b, i)
+		if err != nil {
+			return in, st, errors.Wrap(err, "elem index")
+		}
+
+		in.Table, i, err = d.index(b, i)
+	case FCTableCopy:
+		in.Table, i, err = d.index(b, i)
+		if err != nil {
+			return in, st, errors.Wrap(err, "dst table")
+		}
+
+		in.Src, i, err = d.index(b, i)
+	case FCTableGrow, FCTableSize, FCTableFill:
+		in.Table, i, err = d.index(b, i)
 	}
 
-	op, i, err = d.Byte(b, i)
 	if err != nil {
-		return
+		return in, st, err
 	}
 
-	switch op {
-	case FCMemoryCopy, FCMemoryFill:
+	return in, i, nil
+}
+
+func (d *InstructionsDecoder) fdExt(b []byte, st int, in Instruction) (_ Instruction, i int, err error) {
+	op, i, err := d.Byte(b, st)
+	if err != nil {
+		return in, st, err
+	}
+	if op != FDExt {
+		return in, st, errors.New("fd ext expected")
+	}
+
+	ext, i, err := d.Uint32(b, i)
+	if err != nil {
+		return in, st, errors.Wrap(err, "fd ext opcode")
+	}
+
+	if ext >= uint32(len(fdNames)) || fdNames[ext] == "" {
+		return in, st, UnsupportedOpcodeError{Opcode: FDExt, Args: b[st+1 : i]}
+	}
+
+	in.Ext = ext
+
+	switch fdImmediates[ext] {
+	case immMemArg:
+		in.MemArg, i, err = d.MemArg(b, i)
+	case immMemArgLane:
+		in.MemArg, i, err = d.MemArg(b, i)
+		if err != nil {
+			return in, st, err
+		}
+
+		in.Lane, i, err = d.Byte(b, i)
+	case immLane:
+		in.Lane, i, err = d.Byte(b, i)
+	case immV128, immShuffle:
+		if i+len(in.V128) > len(b) {
+			return in, st, ErrUnexpectedEOF
+		}
+
+		i += copy(in.V128[:], b[i:])
+	}
+
+	if err != nil {
+		return in, st, err
+	}
+
+	return in, i, nil
+}
+
+func (d *InstructionsDecoder) feExt(b []byte, st int, in Instruction) (_ Instruction, i int, err error) {
+	op, i, err := d.Byte(b, st)
+	if err != nil {
+		return in, st, err
+	}
+	if op != FEExt {
+		return in, st, errors.New("fe ext expected")
+	}
+
+	ext, i, err := d.Uint32(b, i)
+	if err != nil {
+		return in, st, errors.Wrap(err, "fe ext opcode")
+	}
+
+	if ext >= uint32(len(feNames)) || feNames[ext] == "" {
+		return in, st, UnsupportedOpcodeError{Opcode: FEExt, Args: b[st+1 : i]}
+	}
+
+	in.Ext = ext
+
+	switch in.Ext {
+	case FEAtomicFence:
+		var x byte
+
+		x, i, err = d.Byte(b, i)
+		if err == nil && x != 0 {
+			err = errors.New("atomic.fence: zero byte expected")
+		}
 	default:
-		return st, UnsupportedOpcodeError{Opcode: FCExt, Args: b[i-1 : i]}
+		in.MemArg, i, err = d.MemArg(b, i)
+	}
+
+	if err != nil {
+		return in, st, err
 	}
 
-	return i, nil
+	return in, i, nil
+}
+
+func (d *InstructionsDecoder) index(b []byte, st int) (x Index, i int, err error) {
+	v, i, err := d.Int(b, st)
+
+	return Index(v), i, err
+}
+
+// ValueBlockType returns BlockType of a single value type result.
+func ValueBlockType(tp Type) BlockType {
+	return BlockType(tp) - 0x80
+}
+
+// TypeIndexBlockType returns BlockType referring to a function type.
+func TypeIndexBlockType(x Index) BlockType {
+	return BlockType(x)
+}
+
+// ValueType returns single value result type if it is one.
+func (bt BlockType) ValueType() (Type, bool) {
+	if bt >= 0 || bt == BlockEmpty {
+		return 0, false
+	}
+
+	return Type(bt + 0x80), true
+}
+
+// TypeIndex returns function type index if the block type refers to one.
+func (bt BlockType) TypeIndex() (Index, bool) {
+	if bt < 0 {
+		return 0, false
+	}
+
+	return Index(bt), true
 }
 
 func (e UnsupportedOpcodeError) Error() string {
 	return fmt.Sprintf("unsupported opcode: %v [% 02x]", e.Opcode, e.Args)
 }
 
+// Name returns instruction name including prefixed instructions.
+func (in *Instruction) Name() string {
+	switch in.Opcode {
+	case FCExt:
+		return FCOpcode(in.Ext).String()
+	case FDExt:
+		return FDOpcode(in.Ext).String()
+	case FEExt:
+		return FEOpcode(in.Ext).String()
+	}
+
+	return in.Opcode.String()
+}
+
+// AppendImmediates appends instruction immediate arguments in a human readable form.
+// Each argument is preceded by a space, nothing is appended if there are no arguments.
+func (in *Instruction) AppendImmediates(b []byte) []byte {
+	switch op := in.Opcode; {
+	case op == Block || op == Loop || op == If:
+		if tp, ok := in.BlockType.ValueType(); ok {
+			b = fmt.Appendf(b, " %v", tp)
+		} else if x, ok := in.BlockType.TypeIndex(); ok {
+			b = fmt.Appendf(b, " type[%d]", x)
+		}
+	case op == Br || op == BrIf:
+		b = fmt.Appendf(b, " %d", in.Label)
+	case op == BrTable:
+		for _, x := range in.Labels {
+			b = fmt.Appendf(b, " %d", x)
+		}
+
+		b = fmt.Appendf(b, " default=%d", in.Label)
+	case op == Call || op == RefFunc || op >= LocalGet && op <= GlobalSet:
+		b = fmt.Appendf(b, " %d", in.Index)
+	case op == CallIndir:
+		b = fmt.Appendf(b, " type=%d table=%d", in.Index, in.Table)
+	case op == SelectT:
+		for _, tp := range in.Types {
+			b = fmt.Appendf(b, " %v", tp)
+		}
+	case op == TableGet || op == TableSet:
+		b = fmt.Appendf(b, " %d", in.Table)
+	case op >= I32Load && op <= I64Store32:
+		b = in.MemArg.appendText(b)
+	case op == MemorySize || op == MemoryGrow:
+		b = fmt.Appendf(b, " %d", in.Memory)
+	case op == I32Const:
+		b = fmt.Appendf(b, " %d", in.I32)
+	case op == I64Const:
+		b = fmt.Appendf(b, " %d", in.I64)
+	case op == F32Const:
+		b = fmt.Appendf(b, " %v", in.F32)
+	case op == F64Const:
+		b = fmt.Appendf(b, " %v", in.F64)
+	case op == RefNull:
+		b = fmt.Appendf(b, " %v", in.RefType)
+	case op == FCExt:
+		switch in.Ext {
+		case FCMemoryInit:
+			b = fmt.Appendf(b, " %d %d", in.Index, in.Memory)
+		case FCDataDrop, FCElemDrop:
+			b = fmt.Appendf(b, " %d", in.Index)
+		case FCMemoryCopy, FCTableCopy:
+			dst := in.Memory
+			if in.Ext == FCTableCopy {
+				dst = in.Table
+			}
+
+			b = fmt.Appendf(b, " %d %d", dst, in.Src)
+		case FCMemoryFill:
+			b = fmt.Appendf(b, " %d", in.Memory)
+		case FCTableInit:
+			b = fmt.Appendf(b, " %d %d", in.Index, in.Table)
+		case FCTableGrow, FCTableSize, FCTableFill:
+			b = fmt.Appendf(b, " %d", in.Table)
+		}
+	case op == FDExt:
+		if in.Ext >= uint32(len(fdImmediates)) {
+			break
+		}
+
+		switch fdImmediates[in.Ext] {
+		case immMemArg:
+			b = in.MemArg.appendText(b)
+		case immMemArgLane:
+			b = in.MemArg.appendText(b)
+			b = fmt.Appendf(b, " %d", in.Lane)
+		case immLane:
+			b = fmt.Appendf(b, " %d", in.Lane)
+		case immV128:
+			for j := 0; j < len(in.V128); j += 4 {
+				b = fmt.Appendf(b, " 0x%08x", binary.LittleEndian.Uint32(in.V128[j:]))
+			}
+		case immShuffle:
+			for _, x := range in.V128 {
+				b = fmt.Appendf(b, " %d", x)
+			}
+		}
+	case op == FEExt:
+		if in.Ext != FEAtomicFence {
+			b = in.MemArg.appendText(b)
+		}
+	}
+
+	return b
+}
+
+func (m MemArg) appendText(b []byte) []byte {
+	if m.Align < 0 || m.Align >= 32 {
+		return fmt.Appendf(b, " align=2^%d offset=%d", m.Align, m.Offset)
+	}
+
+	return fmt.Appendf(b, " align=%d offset=%d", 1<<m.Align, m.Offset)
+}
+
+func (op FCOpcode) String() string {
+	if op < FCOpcode(len(fcNames)) && fcNames[op] != "" {
+		return fcNames[op]
+	}
+
+	return fmt.Sprintf("fc %02x", uint32(op))
+}
+
+func (op FDOpcode) String() string {
+	if op < FDOpcode(len(fdNames)) && fdNames[op] != "" {
+		return fdNames[op]
+	}
+
+	return fmt.Sprintf("fd %02x", uint32(op))
+}
+
+func (op FEOpcode) String() string {
+	if op < FEOpcode(len(feNames)) && feNames[op] != "" {
+		return feNames[op]
+	}
+
+	return fmt.Sprintf("fe %02x", uint32(op))
+}
+
 func (op Opcode) String() string {
 	if n := opNames[op]; n != "" {
 		return n
@@ -357,12 +868,22 @@ var opNames = [...]string{
 	BrTable: "BrTable",
 	Ret:     "Ret",
 
+	Call:      "Call",
+	CallIndir: "CallIndir",
+
+	Drop:    "Drop",
+	Select:  "Select",
+	SelectT: "SelectT",
+
 	LocalGet:  "LocalGet",
 	LocalSet:  "LocalSet",
 	LocalTee:  "LocalTee",
 	GlobalGet: "GlobalGet",
 	GlobalSet: "GlobalSet",
 
+	TableGet: "TableGet",
+	TableSet: "TableSet",
+
 	I32Load:    "I32Load",
 	I64Load:    "I64Load",
 	F32Load:    "F32Load",
@@ -501,5 +1022,69 @@ var opNames = [...]string{
 	F64Max:      "F64Max",
 	F64CopySign: "F64CopySign",
 
+	I32WrapI64:     "I32WrapI64",
+	I32TruncF32S:   "I32TruncF32S",
+	I32TruncF32U:   "I32TruncF32U",
+	I32TruncF64S:   "I32TruncF64S",
+	I32TruncF64U:   "I32TruncF64U",
+	I64ExtendI32S:  "I64ExtendI32S",
+	I64ExtendI32U:  "I64ExtendI32U",
+	I64TruncF32S:   "I64TruncF32S",
+	I64TruncF32U:   "I64TruncF32U",
+	I64TruncF64S:   "I64TruncF64S",
+	I64TruncF64U:   "I64TruncF64U",
+	F32ConvertI32S: "F32ConvertI32S",
+	F32ConvertI32U: "F32ConvertI32U",
+	F32ConvertI64S: "F32ConvertI64S",
+	F32ConvertI64U: "F32ConvertI64U",
+	F32DemoteF64:   "F32DemoteF64",
+	F64ConvertI32S: "F64ConvertI32S",
+	F64ConvertI32U: "F64ConvertI32U",
+	F64ConvertI64S: "F64ConvertI64S",
+	F64ConvertI64U: "F64ConvertI64U",
+	F64PromoteF32:  "F64PromoteF32",
+
+	I32ReinterpretF32: "I32ReinterpretF32",
+	I64ReinterpretF64: "I64ReinterpretF64",
+	F32ReinterpretI32: "F32ReinterpretI32",
+	F64ReinterpretI64: "F64ReinterpretI64",
+
+	I32Extend8S:  "I32Extend8S",
+	I32Extend16S: "I32Extend16S",
+	I64Extend8S:  "I64Extend8S",
+	I64Extend16S: "I64Extend16S",
+	I64Extend32S: "I64Extend32S",
+
+	RefNull:   "RefNull",
+	RefIsNull: "RefIsNull",
+	RefFunc:   "RefFunc",
+
+	FCExt: "FCExt",
+	FDExt: "FDExt",
+	FEExt: "FEExt",
+
 	255: "",
 }
+
+var fcNames = [...]string{
+	FCI32TruncSatF32S: "I32TruncSatF32S",
+	FCI32TruncSatF32U: "I32TruncSatF32U",
+	FCI32TruncSatF64S: "I32TruncSatF64S",
+	FCI32TruncSatF64U: "I32TruncSatF64U",
+	FCI64TruncSatF32S: "I64TruncSatF32S",
+	FCI64TruncSatF32U: "I64TruncSatF32U",
+	FCI64TruncSatF64S: "I64TruncSatF64S",
+	FCI64TruncSatF64U: "I64TruncSatF64U",
+
+	FCMemoryInit: "MemoryInit",
+	FCDataDrop:   "DataDrop",
+	FCMemoryCopy: "MemoryCopy",
+	FCMemoryFill: "MemoryFill",
+
+	FCTableInit: "TableInit",
+	FCElemDrop:  "ElemDrop",
+	FCTableCopy: "TableCopy",
+	FCTableGrow: "TableGrow",
+	FCTableSize: "TableSize",
+	FCTableFill: "TableFill",
+}
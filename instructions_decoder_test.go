@@ -0,0 +1,357 @@
+package wasm
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructionsDecoder(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for _, tc := range []struct {
+		Name string
+		Code []byte
+		Exp  Instruction
+	}{
+		{Name: "nop", Code: []byte{Nop}, Exp: Instruction{Opcode: Nop}},
+		{Name: "block_empty", Code: []byte{Block, 0x40}, Exp: Instruction{Opcode: Block, BlockType: BlockEmpty}},
+		{Name: "block_i32", Code: []byte{Block, I32}, Exp: Instruction{Opcode: Block, BlockType: ValueBlockType(I32)}},
+		{Name: "block_type_index", Code: []byte{Block, 0x83, 0x01}, Exp: Instruction{Opcode: Block, BlockType: 131}},
+		{Name: "loop_empty", Code: []byte{Loop, 0x40}, Exp: Instruction{Opcode: Loop, BlockType: BlockEmpty}},
+		{Name: "loop_f64", Code: []byte{Loop, F64}, Exp: Instruction{Opcode: Loop, BlockType: ValueBlockType(F64)}},
+		{Name: "loop_type_index", Code: []byte{Loop, 0x00}, Exp: Instruction{Opcode: Loop, BlockType: 0}},
+		{Name: "if_empty", Code: []byte{If, 0x40}, Exp: Instruction{Opcode: If, BlockType: BlockEmpty}},
+		{Name: "if_externref", Code: []byte{If, ExternRef}, Exp: Instruction{Opcode: If, BlockType: ValueBlockType(ExternRef)}},
+		{Name: "if_type_index", Code: []byte{If, 0x3f}, Exp: Instruction{Opcode: If, BlockType: 63}},
+		{Name: "br", Code: []byte{Br, 3}, Exp: Instruction{Opcode: Br, Label: 3}},
+		{Name: "br_if", Code: []byte{BrIf, 0x80, 0x01}, Exp: Instruction{Opcode: BrIf, Label: 128}},
+		{Name: "br_table", Code: []byte{BrTable, 2, 0, 1, 2}, Exp: Instruction{Opcode: BrTable, Labels: []Index{0, 1}, Label: 2}},
+		{Name: "call", Code: []byte{Call, 5}, Exp: Instruction{Opcode: Call, Index: 5}},
+		{Name: "call_indirect", Code: []byte{CallIndir, 2, 0}, Exp: Instruction{Opcode: CallIndir, Index: 2}},
+		{Name: "local_tee", Code: []byte{LocalTee, 7}, Exp: Instruction{Opcode: LocalTee, Index: 7}},
+		{Name: "i64_store", Code: []byte{I64Store, 3, 0x10}, Exp: Instruction{Opcode: I64Store, MemArg: MemArg{Align: 3, Offset: 16}}},
+		{Name: "memory_grow", Code: []byte{MemoryGrow, 0}, Exp: Instruction{Opcode: MemoryGrow}},
+		{Name: "i32_const", Code: []byte{I32Const, 0x7f}, Exp: Instruction{Opcode: I32Const, I32: -1}},
+		{Name: "i64_const", Code: []byte{I64Const, 0xc0, 0xbb, 0x78}, Exp: Instruction{Opcode: I64Const, I64: -123456}},
+		{Name: "f32_const", Code: []byte{F32Const, 0x00, 0x00, 0xc0, 0x3f}, Exp: Instruction{Opcode: F32Const, F32: 1.5}},
+		{Name: "f64_const", Code: []byte{F64Const, 0, 0, 0, 0, 0, 0, 0xf8, 0xbf}, Exp: Instruction{Opcode: F64Const, F64: -1.5}},
+		{Name: "select_t", Code: []byte{SelectT, 1, I64}, Exp: Instruction{Opcode: SelectT, Types: ResultType{I64}}},
+		{Name: "table_get", Code: []byte{TableGet, 1}, Exp: Instruction{Opcode: TableGet, Table: 1}},
+		{Name: "table_set", Code: []byte{TableSet, 2}, Exp: Instruction{Opcode: TableSet, Table: 2}},
+		{Name: "ref_null_func", Code: []byte{RefNull, FuncRef}, Exp: Instruction{Opcode: RefNull, RefType: FuncRef}},
+		{Name: "ref_null_extern", Code: []byte{RefNull, ExternRef}, Exp: Instruction{Opcode: RefNull, RefType: ExternRef}},
+		{Name: "ref_is_null", Code: []byte{RefIsNull}, Exp: Instruction{Opcode: RefIsNull}},
+		{Name: "ref_func", Code: []byte{RefFunc, 3}, Exp: Instruction{Opcode: RefFunc, Index: 3}},
+		{Name: "i32_add", Code: []byte{I32Add}, Exp: Instruction{Opcode: I32Add}},
+	} {
+		tb.Run(tc.Name, func(tb *testing.T) {
+			code := append([]byte{Nop}, tc.Code...)
+
+			in, i, err := d.Instruction(code, 1, Instruction{})
+			require.NoError(tb, err)
+			assert.Equal(tb, len(code), i)
+
+			tc.Exp.Offset = 1
+			assert.Equal(tb, tc.Exp, in)
+		})
+	}
+}
+
+func TestInstructionsDecoderFloatConst(tb *testing.T) {
+	var d InstructionsDecoder
+
+	f32 := func(tb *testing.T, code []byte) uint32 {
+		in, i, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err)
+		assert.Equal(tb, len(code), i)
+
+		return math.Float32bits(in.F32)
+	}
+
+	f64 := func(tb *testing.T, code []byte) uint64 {
+		in, i, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err)
+		assert.Equal(tb, len(code), i)
+
+		return math.Float64bits(in.F64)
+	}
+
+	assert.Equal(tb, uint32(0x80000000), f32(tb, []byte{F32Const, 0, 0, 0, 0x80}))
+	assert.Equal(tb, uint32(0x7fa00001), f32(tb, []byte{F32Const, 0x01, 0, 0xa0, 0x7f}))
+	assert.Equal(tb, uint64(0x8000000000000000), f64(tb, []byte{F64Const, 0, 0, 0, 0, 0, 0, 0, 0x80}))
+	assert.Equal(tb, uint64(0xfff4000000000123), f64(tb, []byte{F64Const, 0x23, 0x01, 0, 0, 0, 0, 0xf4, 0xff}))
+
+	// float constants are not LEB128 encoded, so 0x80 bytes must not continue the number
+	code := []byte{F32Const, 0x80, 0x80, 0x80, 0x80, Drop, End}
+
+	expr, i, err := d.Expr(code, 0)
+	require.NoError(tb, err)
+	assert.Equal(tb, len(code), i)
+	assert.Equal(tb, code, expr)
+
+	_, _, err = d.Instruction([]byte{F64Const, 0, 0, 0}, 0, Instruction{})
+	assert.ErrorIs(tb, err, ErrUnexpectedEOF)
+}
+
+func TestInstructionsDecoderConversions(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for op := Opcode(I32WrapI64); op <= I64Extend32S; op++ {
+		code := []byte{byte(op), End}
+
+		in, i, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err, "op %v", op)
+		assert.Equal(tb, 1, i)
+		assert.Equal(tb, op, in.Opcode)
+
+		assert.NotEqual(tb, fmt.Sprintf("%02x", int(op)), op.String())
+	}
+
+	code := []byte{LocalGet, 0, I64ExtendI32U, F64ConvertI64S, I64TruncF64S, I64Extend32S, I32WrapI64, End}
+
+	expr, i, err := d.Expr(code, 0)
+	require.NoError(tb, err)
+	assert.Equal(tb, len(code), i)
+	assert.Equal(tb, code, expr)
+
+	assert.Equal(tb, "F32ReinterpretI32", Opcode(F32ReinterpretI32).String())
+}
+
+func TestInstructionsDecoderFCExt(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for _, tc := range []struct {
+		Name string
+		Code []byte
+		Exp  Instruction
+	}{
+		{Name: "i32.trunc_sat_f32_s", Code: []byte{FCExt, FCI32TruncSatF32S}, Exp: Instruction{Ext: FCI32TruncSatF32S}},
+		{Name: "i64.trunc_sat_f64_u", Code: []byte{FCExt, FCI64TruncSatF64U}, Exp: Instruction{Ext: FCI64TruncSatF64U}},
+		{Name: "memory.init", Code: []byte{FCExt, FCMemoryInit, 3, 0}, Exp: Instruction{Ext: FCMemoryInit, Index: 3}},
+		{Name: "data.drop", Code: []byte{FCExt, FCDataDrop, 2}, Exp: Instruction{Ext: FCDataDrop, Index: 2}},
+		{Name: "memory.copy", Code: []byte{FCExt, FCMemoryCopy, 1, 2}, Exp: Instruction{Ext: FCMemoryCopy, Memory: 1, Src: 2}},
+		{Name: "memory.fill", Code: []byte{FCExt, FCMemoryFill, 0}, Exp: Instruction{Ext: FCMemoryFill}},
+		{Name: "table.init", Code: []byte{FCExt, FCTableInit, 4, 1}, Exp: Instruction{Ext: FCTableInit, Index: 4, Table: 1}},
+		{Name: "elem.drop", Code: []byte{FCExt, FCElemDrop, 5}, Exp: Instruction{Ext: FCElemDrop, Index: 5}},
+		{Name: "table.copy", Code: []byte{FCExt, FCTableCopy, 1, 0}, Exp: Instruction{Ext: FCTableCopy, Table: 1}},
+		{Name: "table.grow", Code: []byte{FCExt, FCTableGrow, 2}, Exp: Instruction{Ext: FCTableGrow, Table: 2}},
+		{Name: "table.size", Code: []byte{FCExt, FCTableSize, 3}, Exp: Instruction{Ext: FCTableSize, Table: 3}},
+		{Name: "table.fill", Code: []byte{FCExt, FCTableFill, 0}, Exp: Instruction{Ext: FCTableFill}},
+		{Name: "long_sub_opcode", Code: []byte{FCExt, 0x8b, 0x80, 0x00, 0}, Exp: Instruction{Ext: FCMemoryFill}},
+	} {
+		tb.Run(tc.Name, func(tb *testing.T) {
+			in, i, err := d.Instruction(tc.Code, 0, Instruction{})
+			require.NoError(tb, err)
+			assert.Equal(tb, len(tc.Code), i)
+
+			tc.Exp.Opcode = FCExt
+			assert.Equal(tb, tc.Exp, in)
+		})
+	}
+
+	in := Instruction{Opcode: FCExt, Ext: FCTableGrow}
+	assert.Equal(tb, "TableGrow", in.Name())
+	assert.Equal(tb, "I32TruncSatF64U", FCOpcode(FCI32TruncSatF64U).String())
+
+	_, _, err := d.Instruction([]byte{FCExt, 0x12}, 0, Instruction{})
+	assert.ErrorAs(tb, err, &UnsupportedOpcodeError{})
+}
+
+func TestInstructionsDecoderFDExt(tb *testing.T) {
+	var d InstructionsDecoder
+
+	lanes := [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	for _, tc := range []struct {
+		Name string
+		Code []byte
+		Exp  Instruction
+	}{
+		{Name: "v128.load", Code: []byte{FDExt, FDV128Load, 4, 0x10}, Exp: Instruction{Ext: FDV128Load, MemArg: MemArg{Align: 4, Offset: 16}}},
+		{Name: "v128.const", Code: append([]byte{FDExt, FDV128Const}, lanes[:]...), Exp: Instruction{Ext: FDV128Const, V128: lanes}},
+		{Name: "i8x16.shuffle", Code: append([]byte{FDExt, FDI8x16Shuffle}, lanes[:]...), Exp: Instruction{Ext: FDI8x16Shuffle, V128: lanes}},
+		{Name: "i16x8.extract_lane_u", Code: []byte{FDExt, FDI16x8ExtractLaneU, 7}, Exp: Instruction{Ext: FDI16x8ExtractLaneU, Lane: 7}},
+		{Name: "v128.load32_lane", Code: []byte{FDExt, FDV128Load32Lane, 2, 8, 3}, Exp: Instruction{Ext: FDV128Load32Lane, MemArg: MemArg{Align: 2, Offset: 8}, Lane: 3}},
+		{Name: "v128.store64_lane", Code: []byte{FDExt, FDV128Store64Lane, 3, 0, 1}, Exp: Instruction{Ext: FDV128Store64Lane, MemArg: MemArg{Align: 3}, Lane: 1}},
+		{Name: "i32x4.add", Code: []byte{FDExt, FDI32x4Add, 0x01}, Exp: Instruction{Ext: FDI32x4Add}},
+		{Name: "f64x2.convert_low_i32x4_u", Code: []byte{FDExt, FDF64x2ConvertLowI32x4U, 0x01}, Exp: Instruction{Ext: FDF64x2ConvertLowI32x4U}},
+	} {
+		tb.Run(tc.Name, func(tb *testing.T) {
+			in, i, err := d.Instruction(tc.Code, 0, Instruction{})
+			require.NoError(tb, err)
+			assert.Equal(tb, len(tc.Code), i)
+
+			tc.Exp.Opcode = FDExt
+			assert.Equal(tb, tc.Exp, in)
+		})
+	}
+
+	n := 0
+
+	for op := uint32(0); op < 0x100; op++ {
+		name := FDOpcode(op).String()
+		if name == fmt.Sprintf("fd %02x", op) {
+			continue
+		}
+
+		n++
+
+		code := []byte{FDExt}
+		code = (&LowEncoder{}).Uint64(code, uint64(op))
+		code = append(code, make([]byte, 16)...)
+
+		in, _, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err, "op %v", name)
+		assert.Equal(tb, name, in.Name())
+	}
+
+	assert.Equal(tb, 236, n)
+
+	_, _, err := d.Instruction([]byte{FDExt, 0x9a, 0x01}, 0, Instruction{})
+	assert.ErrorAs(tb, err, &UnsupportedOpcodeError{})
+}
+
+func TestInstructionsDecoderFEExt(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for _, tc := range []struct {
+		Name string
+		Code []byte
+		Exp  Instruction
+	}{
+		{Name: "memory.atomic.notify", Code: []byte{FEExt, FEMemoryAtomicNotify, 2, 0}, Exp: Instruction{Ext: FEMemoryAtomicNotify, MemArg: MemArg{Align: 2}}},
+		{Name: "memory.atomic.wait64", Code: []byte{FEExt, FEMemoryAtomicWait64, 3, 8}, Exp: Instruction{Ext: FEMemoryAtomicWait64, MemArg: MemArg{Align: 3, Offset: 8}}},
+		{Name: "atomic.fence", Code: []byte{FEExt, FEAtomicFence, 0}, Exp: Instruction{Ext: FEAtomicFence}},
+		{Name: "i64.atomic.load32_u", Code: []byte{FEExt, FEI64AtomicLoad32U, 2, 4}, Exp: Instruction{Ext: FEI64AtomicLoad32U, MemArg: MemArg{Align: 2, Offset: 4}}},
+		{Name: "i32.atomic.rmw.add", Code: []byte{FEExt, FEI32AtomicRmwAdd, 2, 0}, Exp: Instruction{Ext: FEI32AtomicRmwAdd, MemArg: MemArg{Align: 2}}},
+		{Name: "i64.atomic.rmw32.cmpxchg_u", Code: []byte{FEExt, FEI64AtomicRmw32CmpxchgU, 2, 0x80, 0x01}, Exp: Instruction{Ext: FEI64AtomicRmw32CmpxchgU, MemArg: MemArg{Align: 2, Offset: 128}}},
+	} {
+		tb.Run(tc.Name, func(tb *testing.T) {
+			in, i, err := d.Instruction(tc.Code, 0, Instruction{})
+			require.NoError(tb, err)
+			assert.Equal(tb, len(tc.Code), i)
+
+			tc.Exp.Opcode = FEExt
+			assert.Equal(tb, tc.Exp, in)
+		})
+	}
+
+	in := Instruction{Opcode: FEExt, Ext: FEI32AtomicRmw16XchgU}
+	assert.Equal(tb, "I32AtomicRmw16XchgU", in.Name())
+
+	for _, code := range [][]byte{
+		{FEExt, 0x04, 0, 0},
+		{FEExt, 0x4f, 0, 0},
+		{FEExt, FEAtomicFence, 1},
+	} {
+		_, _, err := d.Instruction(code, 0, Instruction{})
+		assert.Error(tb, err, "%x", code)
+	}
+}
+
+func TestInstructionsDecoderRefTypesErrors(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for _, code := range [][]byte{
+		{RefNull, I32},
+		{SelectT, 1, 0x40},
+		{SelectT, 2, I32},
+	} {
+		_, _, err := d.Instruction(code, 0, Instruction{})
+		assert.Error(tb, err, "%x", code)
+	}
+}
+
+func TestBlockType(tb *testing.T) {
+	var d InstructionsDecoder
+	var e LowEncoder
+
+	for _, bt := range []BlockType{BlockEmpty, ValueBlockType(I32), ValueBlockType(V128), 0, 63, 64, 1000, 1<<32 - 1} {
+		b := e.BlockType(nil, bt)
+
+		r, i, err := d.BlockType(b, 0)
+		require.NoError(tb, err, "%x", b)
+		assert.Equal(tb, len(b), i)
+		assert.Equal(tb, bt, r)
+	}
+
+	tp, ok := ValueBlockType(F32).ValueType()
+	assert.True(tb, ok)
+	assert.Equal(tb, Type(F32), tp)
+
+	_, ok = BlockEmpty.ValueType()
+	assert.False(tb, ok)
+
+	x, ok := TypeIndexBlockType(5).TypeIndex()
+	assert.True(tb, ok)
+	assert.Equal(tb, Index(5), x)
+
+	_, ok = ValueBlockType(I64).TypeIndex()
+	assert.False(tb, ok)
+
+	for _, b := range [][]byte{
+		{0xff, 0x7f},                   // value type in two bytes
+		{0x41},                         // unknown value type
+		{0x80, 0x80, 0x80, 0x80, 0x10}, // 1 << 32
+	} {
+		_, _, err := d.BlockType(b, 0)
+		assert.Error(tb, err, "%x", b)
+	}
+}
+
+func TestInstructionsDecoderExpr(tb *testing.T) {
+	var d InstructionsDecoder
+
+	code := []byte{Block, I32, LocalGet, 0, I32Const, 1, I32Add, End, LocalSet, 1, End, Nop}
+
+	var ops []Opcode
+
+	for i := 0; i < len(code); {
+		var in Instruction
+		var err error
+
+		in, i, err = d.Instruction(code, i, in)
+		require.NoError(tb, err)
+
+		ops = append(ops, in.Opcode)
+	}
+
+	assert.Equal(tb, []Opcode{Block, LocalGet, I32Const, I32Add, End, LocalSet, End, Nop}, ops)
+
+	expr, i, err := d.Expr(code, 0)
+	require.NoError(tb, err)
+	assert.Equal(tb, len(code)-1, i)
+	assert.Equal(tb, code[:len(code)-1], expr)
+}
+
+func TestInstructionAppendImmediates(tb *testing.T) {
+	var d InstructionsDecoder
+
+	for _, tc := range []struct {
+		code []byte
+		exp  string
+	}{
+		{[]byte{Nop}, "Nop"},
+		{[]byte{Block, I32}, "Block i32"},
+		{[]byte{Loop, 0x02}, "Loop type[2]"},
+		{[]byte{BrTable, 2, 0, 1, 2}, "BrTable 0 1 default=2"},
+		{[]byte{Call, 5}, "Call 5"},
+		{[]byte{CallIndir, 3, 0}, "CallIndir type=3 table=0"},
+		{[]byte{I32Load, 2, 16}, "I32Load align=4 offset=16"},
+		{[]byte{I32Const, 0x7f}, "I32Const -1"},
+		{[]byte{RefNull, ExternRef}, "RefNull externref"},
+		{[]byte{FCExt, FCMemoryCopy, 0, 1}, "MemoryCopy 0 1"},
+		{[]byte{FDExt, FDV128Load8Lane, 0, 0, 3}, "V128Load8Lane align=1 offset=0 3"},
+		{[]byte{FEExt, FEAtomicFence, 0}, "AtomicFence"},
+	} {
+		in, _, err := d.Instruction(tc.code, 0, Instruction{})
+		require.NoError(tb, err, "%x", tc.code)
+
+		b := append([]byte(in.Name()), in.AppendImmediates(nil)...)
+		assert.Equal(tb, tc.exp, string(b), "%x", tc.code)
+	}
+}
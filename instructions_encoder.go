@@ -0,0 +1,123 @@
+package wasm
+
+type (
+	InstructionsEncoder struct {
+		LowEncoder
+	}
+)
+
+// Instruction encodes instruction with its immediates.
+// It's the reverse of InstructionsDecoder.Instruction.
+func (e *InstructionsEncoder) Instruction(b []byte, in Instruction) []byte {
+	b = append(b, byte(in.Opcode))
+
+	switch op := in.Opcode; {
+	case op == Block || op == Loop || op == If:
+		b = e.BlockType(b, in.BlockType)
+	case op == Br || op == BrIf:
+		b = e.Int(b, int(in.Label))
+	case op == BrTable:
+		b = e.Int(b, len(in.Labels))
+
+		for _, x := range in.Labels {
+			b = e.Int(b, int(x))
+		}
+
+		b = e.Int(b, int(in.Label))
+	case op == Call || op == RefFunc:
+		b = e.Int(b, int(in.Index))
+	case op == CallIndir:
+		b = e.Int(b, int(in.Index))
+		b = e.Int(b, int(in.Table))
+	case op == SelectT:
+		b = e.ResultTypeOf(b, in.Types)
+	case op >= LocalGet && op <= GlobalSet:
+		b = e.Int(b, int(in.Index))
+	case op == TableGet || op == TableSet:
+		b = e.Int(b, int(in.Table))
+	case op >= I32Load && op <= I64Store32:
+		b = e.MemArg(b, in.MemArg)
+	case op == MemorySize || op == MemoryGrow:
+		b = e.Int(b, int(in.Memory))
+	case op == I32Const:
+		b = e.Int64(b, int64(in.I32))
+	case op == I64Const:
+		b = e.Int64(b, in.I64)
+	case op == F32Const:
+		b = e.Float32(b, in.F32)
+	case op == F64Const:
+		b = e.Float64(b, in.F64)
+	case op == RefNull:
+		b = append(b, byte(in.RefType))
+	case op == FCExt:
+		b = e.fcExt(b, in)
+	case op == FDExt:
+		b = e.fdExt(b, in)
+	case op == FEExt:
+		b = e.Uint64(b, uint64(in.Ext))
+
+		if in.Ext == FEAtomicFence {
+			b = append(b, 0)
+		} else {
+			b = e.MemArg(b, in.MemArg)
+		}
+	}
+
+	return b
+}
+
+func (e *InstructionsEncoder) MemArg(b []byte, m MemArg) []byte {
+	b = e.Int(b, m.Align)
+	b = e.Int(b, m.Offset)
+
+	return b
+}
+
+func (e *InstructionsEncoder) fcExt(b []byte, in Instruction) []byte {
+	b = e.Uint64(b, uint64(in.Ext))
+
+	switch in.Ext {
+	case FCMemoryInit:
+		b = e.Int(b, int(in.Index))
+		b = e.Int(b, int(in.Memory))
+	case FCDataDrop, FCElemDrop:
+		b = e.Int(b, int(in.Index))
+	case FCMemoryCopy:
+		b = e.Int(b, int(in.Memory))
+		b = e.Int(b, int(in.Src))
+	case FCMemoryFill:
+		b = e.Int(b, int(in.Memory))
+	case FCTableInit:
+		b = e.Int(b, int(in.Index))
+		b = e.Int(b, int(in.Table))
+	case FCTableCopy:
+		b = e.Int(b, int(in.Table))
+		b = e.Int(b, int(in.Src))
+	case FCTableGrow, FCTableSize, FCTableFill:
+		b = e.Int(b, int(in.Table))
+	}
+
+	return b
+}
+
+func (e *InstructionsEncoder) fdExt(b []byte, in Instruction) []byte {
+	b = e.Uint64(b, uint64(in.Ext))
+
+	if in.Ext >= uint32(len(fdImmediates)) {
+		return b
+	}
+
+	switch fdImmediates[in.Ext] {
+	case immMemArg:
+		b = e.MemArg(b, in.MemArg)
+	case immMemArgLane:
+		b = e.MemArg(b, in.MemArg)
+		b = append(b, in.Lane)
+	case immLane:
+		b = append(b, in.Lane)
+	case immV128, immShuffle:
+		b = append(b, in.V128[:]...)
+	}
+
+	return b
+}
@@ -0,0 +1,65 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructionsEncoder(tb *testing.T) {
+	var (
+		d InstructionsDecoder
+		e InstructionsEncoder
+	)
+
+	codes := [][]byte{
+		{Unreachable},
+		{Block, 0x40},
+		{Loop, I64},
+		{If, 0x03},
+		{Br, 2},
+		{BrTable, 3, 0, 1, 2, 0x80, 0x01},
+		{Call, 0xa4, 0x0a},
+		{CallIndir, 1, 0},
+		{SelectT, 1, I32},
+		{LocalTee, 5},
+		{GlobalSet, 1},
+		{TableSet, 2},
+		{I64Store32, 2, 0x80, 0x80, 0x04},
+		{MemoryGrow, 0},
+		{I32Const, 0x80, 0x80, 0x80, 0x80, 0x78},
+		{I64Const, 0x7f},
+		{F32Const, 0, 0, 0xc0, 0x7f},
+		{F64Const, 1, 0, 0, 0, 0, 0, 0xf0, 0xff},
+		{RefNull, ExternRef},
+		{RefFunc, 7},
+		{FCExt, FCMemoryInit, 3, 0},
+		{FCExt, FCTableCopy, 1, 2},
+		{FCExt, FCTableSize, 1},
+		{FEExt, FEAtomicFence, 0},
+		{FEExt, FEI32AtomicRmwCmpxchg, 2, 16},
+	}
+
+	for op := uint32(0); op < uint32(len(fdNames)); op++ {
+		if fdNames[op] == "" {
+			continue
+		}
+
+		code := e.Uint64([]byte{FDExt}, uint64(op))
+		code = append(code, 3, 8, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16)
+
+		_, i, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err)
+
+		codes = append(codes, code[:i])
+	}
+
+	for _, code := range codes {
+		in, i, err := d.Instruction(code, 0, Instruction{})
+		require.NoError(tb, err, "%x", code)
+		require.Equal(tb, len(code), i, "%x", code)
+
+		assert.Equal(tb, code, e.Instruction(nil, in), "%v", in.Name())
+	}
+}
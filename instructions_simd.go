@@ -0,0 +1,613 @@
+package wasm
+
+// FD ext opcodes, fixed-width SIMD
+const (
+	FDV128Load        = 0x00
+	FDV128Load8x8S    = 0x01
+	FDV128Load8x8U    = 0x02
+	FDV128Load16x4S   = 0x03
+	FDV128Load16x4U   = 0x04
+	FDV128Load32x2S   = 0x05
+	FDV128Load32x2U   = 0x06
+	FDV128Load8Splat  = 0x07
+	FDV128Load16Splat = 0x08
+	FDV128Load32Splat = 0x09
+	FDV128Load64Splat = 0x0a
+	FDV128Store       = 0x0b
+	FDV128Const       = 0x0c
+
+	FDI8x16Shuffle = 0x0d
+	FDI8x16Swizzle = 0x0e
+	FDI8x16Splat   = 0x0f
+
+	FDI16x8Splat = 0x10
+
+	FDI32x4Splat = 0x11
+
+	FDI64x2Splat = 0x12
+
+	FDF32x4Splat = 0x13
+
+	FDF64x2Splat = 0x14
+
+	FDI8x16ExtractLaneS = 0x15
+	FDI8x16ExtractLaneU = 0x16
+	FDI8x16ReplaceLane  = 0x17
+
+	FDI16x8ExtractLaneS = 0x18
+	FDI16x8ExtractLaneU = 0x19
+	FDI16x8ReplaceLane  = 0x1a
+
+	FDI32x4ExtractLane = 0x1b
+	FDI32x4ReplaceLane = 0x1c
+
+	FDI64x2ExtractLane = 0x1d
+	FDI64x2ReplaceLane = 0x1e
+
+	FDF32x4ExtractLane = 0x1f
+	FDF32x4ReplaceLane = 0x20
+
+	FDF64x2ExtractLane = 0x21
+	FDF64x2ReplaceLane = 0x22
+
+	FDI8x16Eq  = 0x23
+	FDI8x16Ne  = 0x24
+	FDI8x16LtS = 0x25
+	FDI8x16LtU = 0x26
+	FDI8x16GtS = 0x27
+	FDI8x16GtU = 0x28
+	FDI8x16LeS = 0x29
+	FDI8x16LeU = 0x2a
+	FDI8x16GeS = 0x2b
+	FDI8x16GeU = 0x2c
+
+	FDI16x8Eq  = 0x2d
+	FDI16x8Ne  = 0x2e
+	FDI16x8LtS = 0x2f
+	FDI16x8LtU = 0x30
+	FDI16x8GtS = 0x31
+	FDI16x8GtU = 0x32
+	FDI16x8LeS = 0x33
+	FDI16x8LeU = 0x34
+	FDI16x8GeS = 0x35
+	FDI16x8GeU = 0x36
+
+	FDI32x4Eq  = 0x37
+	FDI32x4Ne  = 0x38
+	FDI32x4LtS = 0x39
+	FDI32x4LtU = 0x3a
+	FDI32x4GtS = 0x3b
+	FDI32x4GtU = 0x3c
+	FDI32x4LeS = 0x3d
+	FDI32x4LeU = 0x3e
+	FDI32x4GeS = 0x3f
+	FDI32x4GeU = 0x40
+
+	FDF32x4Eq = 0x41
+	FDF32x4Ne = 0x42
+	FDF32x4Lt = 0x43
+	FDF32x4Gt = 0x44
+	FDF32x4Le = 0x45
+	FDF32x4Ge = 0x46
+
+	FDF64x2Eq = 0x47
+	FDF64x2Ne = 0x48
+	FDF64x2Lt = 0x49
+	FDF64x2Gt = 0x4a
+	FDF64x2Le = 0x4b
+	FDF64x2Ge = 0x4c
+
+	FDV128Not         = 0x4d
+	FDV128And         = 0x4e
+	FDV128Andnot      = 0x4f
+	FDV128Or          = 0x50
+	FDV128Xor         = 0x51
+	FDV128Bitselect   = 0x52
+	FDV128AnyTrue     = 0x53
+	FDV128Load8Lane   = 0x54
+	FDV128Load16Lane  = 0x55
+	FDV128Load32Lane  = 0x56
+	FDV128Load64Lane  = 0x57
+	FDV128Store8Lane  = 0x58
+	FDV128Store16Lane = 0x59
+	FDV128Store32Lane = 0x5a
+	FDV128Store64Lane = 0x5b
+	FDV128Load32Zero  = 0x5c
+	FDV128Load64Zero  = 0x5d
+
+	FDF32x4DemoteF64x2Zero = 0x5e
+
+	FDF64x2PromoteLowF32x4 = 0x5f
+
+	FDI8x16Abs          = 0x60
+	FDI8x16Neg          = 0x61
+	FDI8x16Popcnt       = 0x62
+	FDI8x16AllTrue      = 0x63
+	FDI8x16Bitmask      = 0x64
+	FDI8x16NarrowI16x8S = 0x65
+	FDI8x16NarrowI16x8U = 0x66
+
+	FDF32x4Ceil    = 0x67
+	FDF32x4Floor   = 0x68
+	FDF32x4Trunc   = 0x69
+	FDF32x4Nearest = 0x6a
+
+	FDI8x16Shl     = 0x6b
+	FDI8x16ShrS    = 0x6c
+	FDI8x16ShrU    = 0x6d
+	FDI8x16Add     = 0x6e
+	FDI8x16AddSatS = 0x6f
+	FDI8x16AddSatU = 0x70
+	FDI8x16Sub     = 0x71
+	FDI8x16SubSatS = 0x72
+	FDI8x16SubSatU = 0x73
+
+	FDF64x2Ceil  = 0x74
+	FDF64x2Floor = 0x75
+
+	FDI8x16MinS = 0x76
+	FDI8x16MinU = 0x77
+	FDI8x16MaxS = 0x78
+	FDI8x16MaxU = 0x79
+
+	FDF64x2Trunc = 0x7a
+
+	FDI8x16AvgrU = 0x7b
+
+	FDI16x8ExtaddPairwiseI8x16S = 0x7c
+	FDI16x8ExtaddPairwiseI8x16U = 0x7d
+
+	FDI32x4ExtaddPairwiseI16x8S = 0x7e
+	FDI32x4ExtaddPairwiseI16x8U = 0x7f
+
+	FDI16x8Abs              = 0x80
+	FDI16x8Neg              = 0x81
+	FDI16x8Q15mulrSatS      = 0x82
+	FDI16x8AllTrue          = 0x83
+	FDI16x8Bitmask          = 0x84
+	FDI16x8NarrowI32x4S     = 0x85
+	FDI16x8NarrowI32x4U     = 0x86
+	FDI16x8ExtendLowI8x16S  = 0x87
+	FDI16x8ExtendHighI8x16S = 0x88
+	FDI16x8ExtendLowI8x16U  = 0x89
+	FDI16x8ExtendHighI8x16U = 0x8a
+	FDI16x8Shl              = 0x8b
+	FDI16x8ShrS             = 0x8c
+	FDI16x8ShrU             = 0x8d
+	FDI16x8Add              = 0x8e
+	FDI16x8AddSatS          = 0x8f
+	FDI16x8AddSatU          = 0x90
+	FDI16x8Sub              = 0x91
+	FDI16x8SubSatS          = 0x92
+	FDI16x8SubSatU          = 0x93
+
+	FDF64x2Nearest = 0x94
+
+	FDI16x8Mul              = 0x95
+	FDI16x8MinS             = 0x96
+	FDI16x8MinU             = 0x97
+	FDI16x8MaxS             = 0x98
+	FDI16x8MaxU             = 0x99
+	FDI16x8AvgrU            = 0x9b
+	FDI16x8ExtmulLowI8x16S  = 0x9c
+	FDI16x8ExtmulHighI8x16S = 0x9d
+	FDI16x8ExtmulLowI8x16U  = 0x9e
+	FDI16x8ExtmulHighI8x16U = 0x9f
+
+	FDI32x4Abs              = 0xa0
+	FDI32x4Neg              = 0xa1
+	FDI32x4AllTrue          = 0xa3
+	FDI32x4Bitmask          = 0xa4
+	FDI32x4ExtendLowI16x8S  = 0xa7
+	FDI32x4ExtendHighI16x8S = 0xa8
+	FDI32x4ExtendLowI16x8U  = 0xa9
+	FDI32x4ExtendHighI16x8U = 0xaa
+	FDI32x4Shl              = 0xab
+	FDI32x4ShrS             = 0xac
+	FDI32x4ShrU             = 0xad
+	FDI32x4Add              = 0xae
+	FDI32x4Sub              = 0xb1
+	FDI32x4Mul              = 0xb5
+	FDI32x4MinS             = 0xb6
+	FDI32x4MinU             = 0xb7
+	FDI32x4MaxS             = 0xb8
+	FDI32x4MaxU             = 0xb9
+	FDI32x4DotI16x8S        = 0xba
+	FDI32x4ExtmulLowI16x8S  = 0xbc
+	FDI32x4ExtmulHighI16x8S = 0xbd
+	FDI32x4ExtmulLowI16x8U  = 0xbe
+	FDI32x4ExtmulHighI16x8U = 0xbf
+
+	FDI64x2Abs              = 0xc0
+	FDI64x2Neg              = 0xc1
+	FDI64x2AllTrue          = 0xc3
+	FDI64x2Bitmask          = 0xc4
+	FDI64x2ExtendLowI32x4S  = 0xc7
+	FDI64x2ExtendHighI32x4S = 0xc8
+	FDI64x2ExtendLowI32x4U  = 0xc9
+	FDI64x2ExtendHighI32x4U = 0xca
+	FDI64x2Shl              = 0xcb
+	FDI64x2ShrS             = 0xcc
+	FDI64x2ShrU             = 0xcd
+	FDI64x2Add              = 0xce
+	FDI64x2Sub              = 0xd1
+	FDI64x2Mul              = 0xd5
+	FDI64x2Eq               = 0xd6
+	FDI64x2Ne               = 0xd7
+	FDI64x2LtS              = 0xd8
+	FDI64x2GtS              = 0xd9
+	FDI64x2LeS              = 0xda
+	FDI64x2GeS              = 0xdb
+	FDI64x2ExtmulLowI32x4S  = 0xdc
+	FDI64x2ExtmulHighI32x4S = 0xdd
+	FDI64x2ExtmulLowI32x4U  = 0xde
+	FDI64x2ExtmulHighI32x4U = 0xdf
+
+	FDF32x4Abs  = 0xe0
+	FDF32x4Neg  = 0xe1
+	FDF32x4Sqrt = 0xe3
+	FDF32x4Add  = 0xe4
+	FDF32x4Sub  = 0xe5
+	FDF32x4Mul  = 0xe6
+	FDF32x4Div  = 0xe7
+	FDF32x4Min  = 0xe8
+	FDF32x4Max  = 0xe9
+	FDF32x4Pmin = 0xea
+	FDF32x4Pmax = 0xeb
+
+	FDF64x2Abs  = 0xec
+	FDF64x2Neg  = 0xed
+	FDF64x2Sqrt = 0xef
+	FDF64x2Add  = 0xf0
+	FDF64x2Sub  = 0xf1
+	FDF64x2Mul  = 0xf2
+	FDF64x2Div  = 0xf3
+	FDF64x2Min  = 0xf4
+	FDF64x2Max  = 0xf5
+	FDF64x2Pmin = 0xf6
+	FDF64x2Pmax = 0xf7
+
+	FDI32x4TruncSatF32x4S = 0xf8
+	FDI32x4TruncSatF32x4U = 0xf9
+
+	FDF32x4ConvertI32x4S = 0xfa
+	FDF32x4ConvertI32x4U = 0xfb
+
+	FDI32x4TruncSatF64x2SZero = 0xfc
+	FDI32x4TruncSatF64x2UZero = 0xfd
+
+	FDF64x2ConvertLowI32x4S = 0xfe
+	FDF64x2ConvertLowI32x4U = 0xff
+)
+
+// FD ext instructions immediate kinds.
+const (
+	immNone = iota
+	immMemArg
+	immMemArgLane
+	immLane
+	immV128
+	immShuffle
+)
+
+var fdImmediates = [...]byte{
+	FDV128Load:          immMemArg,
+	FDV128Load8x8S:      immMemArg,
+	FDV128Load8x8U:      immMemArg,
+	FDV128Load16x4S:     immMemArg,
+	FDV128Load16x4U:     immMemArg,
+	FDV128Load32x2S:     immMemArg,
+	FDV128Load32x2U:     immMemArg,
+	FDV128Load8Splat:    immMemArg,
+	FDV128Load16Splat:   immMemArg,
+	FDV128Load32Splat:   immMemArg,
+	FDV128Load64Splat:   immMemArg,
+	FDV128Store:         immMemArg,
+	FDV128Const:         immV128,
+	FDI8x16Shuffle:      immShuffle,
+	FDI8x16ExtractLaneS: immLane,
+	FDI8x16ExtractLaneU: immLane,
+	FDI8x16ReplaceLane:  immLane,
+	FDI16x8ExtractLaneS: immLane,
+	FDI16x8ExtractLaneU: immLane,
+	FDI16x8ReplaceLane:  immLane,
+	FDI32x4ExtractLane:  immLane,
+	FDI32x4ReplaceLane:  immLane,
+	FDI64x2ExtractLane:  immLane,
+	FDI64x2ReplaceLane:  immLane,
+	FDF32x4ExtractLane:  immLane,
+	FDF32x4ReplaceLane:  immLane,
+	FDF64x2ExtractLane:  immLane,
+	FDF64x2ReplaceLane:  immLane,
+	FDV128Load8Lane:     immMemArgLane,
+	FDV128Load16Lane:    immMemArgLane,
+	FDV128Load32Lane:    immMemArgLane,
+	FDV128Load64Lane:    immMemArgLane,
+	FDV128Store8Lane:    immMemArgLane,
+	FDV128Store16Lane:   immMemArgLane,
+	FDV128Store32Lane:   immMemArgLane,
+	FDV128Store64Lane:   immMemArgLane,
+	FDV128Load32Zero:    immMemArg,
+	FDV128Load64Zero:    immMemArg,
+
+	0xff: immNone,
+}
+
+var fdNames = [...]string{
+	FDV128Load:        "V128Load",
+	FDV128Load8x8S:    "V128Load8x8S",
+	FDV128Load8x8U:    "V128Load8x8U",
+	FDV128Load16x4S:   "V128Load16x4S",
+	FDV128Load16x4U:   "V128Load16x4U",
+	FDV128Load32x2S:   "V128Load32x2S",
+	FDV128Load32x2U:   "V128Load32x2U",
+	FDV128Load8Splat:  "V128Load8Splat",
+	FDV128Load16Splat: "V128Load16Splat",
+	FDV128Load32Splat: "V128Load32Splat",
+	FDV128Load64Splat: "V128Load64Splat",
+	FDV128Store:       "V128Store",
+	FDV128Const:       "V128Const",
+
+	FDI8x16Shuffle: "I8x16Shuffle",
+	FDI8x16Swizzle: "I8x16Swizzle",
+	FDI8x16Splat:   "I8x16Splat",
+
+	FDI16x8Splat: "I16x8Splat",
+
+	FDI32x4Splat: "I32x4Splat",
+
+	FDI64x2Splat: "I64x2Splat",
+
+	FDF32x4Splat: "F32x4Splat",
+
+	FDF64x2Splat: "F64x2Splat",
+
+	FDI8x16ExtractLaneS: "I8x16ExtractLaneS",
+	FDI8x16ExtractLaneU: "I8x16ExtractLaneU",
+	FDI8x16ReplaceLane:  "I8x16ReplaceLane",
+
+	FDI16x8ExtractLaneS: "I16x8ExtractLaneS",
+	FDI16x8ExtractLaneU: "I16x8ExtractLaneU",
+	FDI16x8ReplaceLane:  "I16x8ReplaceLane",
+
+	FDI32x4ExtractLane: "I32x4ExtractLane",
+	FDI32x4ReplaceLane: "I32x4ReplaceLane",
+
+	FDI64x2ExtractLane: "I64x2ExtractLane",
+	FDI64x2ReplaceLane: "I64x2ReplaceLane",
+
+	FDF32x4ExtractLane: "F32x4ExtractLane",
+	FDF32x4ReplaceLane: "F32x4ReplaceLane",
+
+	FDF64x2ExtractLane: "F64x2ExtractLane",
+	FDF64x2ReplaceLane: "F64x2ReplaceLane",
+
+	FDI8x16Eq:  "I8x16Eq",
+	FDI8x16Ne:  "I8x16Ne",
+	FDI8x16LtS: "I8x16LtS",
+	FDI8x16LtU: "I8x16LtU",
+	FDI8x16GtS: "I8x16GtS",
+	FDI8x16GtU: "I8x16GtU",
+	FDI8x16LeS: "I8x16LeS",
+	FDI8x16LeU: "I8x16LeU",
+	FDI8x16GeS: "I8x16GeS",
+	FDI8x16GeU: "I8x16GeU",
+
+	FDI16x8Eq:  "I16x8Eq",
+	FDI16x8Ne:  "I16x8Ne",
+	FDI16x8LtS: "I16x8LtS",
+	FDI16x8LtU: "I16x8LtU",
+	FDI16x8GtS: "I16x8GtS",
+	FDI16x8GtU: "I16x8GtU",
+	FDI16x8LeS: "I16x8LeS",
+	FDI16x8LeU: "I16x8LeU",
+	FDI16x8GeS: "I16x8GeS",
+	FDI16x8GeU: "I16x8GeU",
+
+	FDI32x4Eq:  "I32x4Eq",
+	FDI32x4Ne:  "I32x4Ne",
+	FDI32x4LtS: "I32x4LtS",
+	FDI32x4LtU: "I32x4LtU",
+	FDI32x4GtS: "I32x4GtS",
+	FDI32x4GtU: "I32x4GtU",
+	FDI32x4LeS: "I32x4LeS",
+	FDI32x4LeU: "I32x4LeU",
+	FDI32x4GeS: "I32x4GeS",
+	FDI32x4GeU: "I32x4GeU",
+
+	FDF32x4Eq: "F32x4Eq",
+	FDF32x4Ne: "F32x4Ne",
+	FDF32x4Lt: "F32x4Lt",
+	FDF32x4Gt: "F32x4Gt",
+	FDF32x4Le: "F32x4Le",
+	FDF32x4Ge: "F32x4Ge",
+
+	FDF64x2Eq: "F64x2Eq",
+	FDF64x2Ne: "F64x2Ne",
+	FDF64x2Lt: "F64x2Lt",
+	FDF64x2Gt: "F64x2Gt",
+	FDF64x2Le: "F64x2Le",
+	FDF64x2Ge: "F64x2Ge",
+
+	FDV128Not:         "V128Not",
+	FDV128And:         "V128And",
+	FDV128Andnot:      "V128Andnot",
+	FDV128Or:          "V128Or",
+	FDV128Xor:         "V128Xor",
+	FDV128Bitselect:   "V128Bitselect",
+	FDV128AnyTrue:     "V128AnyTrue",
+	FDV128Load8Lane:   "V128Load8Lane",
+	FDV128Load16Lane:  "V128Load16Lane",
+	FDV128Load32Lane:  "V128Load32Lane",
+	FDV128Load64Lane:  "V128Load64Lane",
+	FDV128Store8Lane:  "V128Store8Lane",
+	FDV128Store16Lane: "V128Store16Lane",
+	FDV128Store32Lane: "V128Store32Lane",
+	FDV128Store64Lane: "V128Store64Lane",
+	FDV128Load32Zero:  "V128Load32Zero",
+	FDV128Load64Zero:  "V128Load64Zero",
+
+	FDF32x4DemoteF64x2Zero: "F32x4DemoteF64x2Zero",
+
+	FDF64x2PromoteLowF32x4: "F64x2PromoteLowF32x4",
+
+	FDI8x16Abs:          "I8x16Abs",
+	FDI8x16Neg:          "I8x16Neg",
+	FDI8x16Popcnt:       "I8x16Popcnt",
+	FDI8x16AllTrue:      "I8x16AllTrue",
+	FDI8x16Bitmask:      "I8x16Bitmask",
+	FDI8x16NarrowI16x8S: "I8x16NarrowI16x8S",
+	FDI8x16NarrowI16x8U: "I8x16NarrowI16x8U",
+
+	FDF32x4Ceil:    "F32x4Ceil",
+	FDF32x4Floor:   "F32x4Floor",
+	FDF32x4Trunc:   "F32x4Trunc",
+	FDF32x4Nearest: "F32x4Nearest",
+
+	FDI8x16Shl:     "I8x16Shl",
+	FDI8x16ShrS:    "I8x16ShrS",
+	FDI8x16ShrU:    "I8x16ShrU",
+	FDI8x16Add:     "I8x16Add",
+	FDI8x16AddSatS: "I8x16AddSatS",
+	FDI8x16AddSatU: "I8x16AddSatU",
+	FDI8x16Sub:     "I8x16Sub",
+	FDI8x16SubSatS: "I8x16SubSatS",
+	FDI8x16SubSatU: "I8x16SubSatU",
+
+	FDF64x2Ceil:  "F64x2Ceil",
+	FDF64x2Floor: "F64x2Floor",
+
+	FDI8x16MinS: "I8x16MinS",
+	FDI8x16MinU: "I8x16MinU",
+	FDI8x16MaxS: "I8x16MaxS",
+	FDI8x16MaxU: "I8x16MaxU",
+
+	FDF64x2Trunc: "F64x2Trunc",
+
+	FDI8x16AvgrU: "I8x16AvgrU",
+
+	FDI16x8ExtaddPairwiseI8x16S: "I16x8ExtaddPairwiseI8x16S",
+	FDI16x8ExtaddPairwiseI8x16U: "I16x8ExtaddPairwiseI8x16U",
+
+	FDI32x4ExtaddPairwiseI16x8S: "I32x4ExtaddPairwiseI16x8S",
+	FDI32x4ExtaddPairwiseI16x8U: "I32x4ExtaddPairwiseI16x8U",
+
+	FDI16x8Abs:              "I16x8Abs",
+	FDI16x8Neg:              "I16x8Neg",
+	FDI16x8Q15mulrSatS:      "I16x8Q15mulrSatS",
+	FDI16x8AllTrue:          "I16x8AllTrue",
+	FDI16x8Bitmask:          "I16x8Bitmask",
+	FDI16x8NarrowI32x4S:     "I16x8NarrowI32x4S",
+	FDI16x8NarrowI32x4U:     "I16x8NarrowI32x4U",
+	FDI16x8ExtendLowI8x16S:  "I16x8ExtendLowI8x16S",
+	FDI16x8ExtendHighI8x16S: "I16x8ExtendHighI8x16S",
+	FDI16x8ExtendLowI8x16U:  "I16x8ExtendLowI8x16U",
+	FDI16x8ExtendHighI8x16U: "I16x8ExtendHighI8x16U",
+	FDI16x8Shl:              "I16x8Shl",
+	FDI16x8ShrS:             "I16x8ShrS",
+	FDI16x8ShrU:             "I16x8ShrU",
+	FDI16x8Add:              "I16x8Add",
+	FDI16x8AddSatS:          "I16x8AddSatS",
+	FDI16x8AddSatU:          "I16x8AddSatU",
+	FDI16x8Sub:              "I16x8Sub",
+	FDI16x8SubSatS:          "I16x8SubSatS",
+	FDI16x8SubSatU:          "I16x8SubSatU",
+
+	FDF64x2Nearest: "F64x2Nearest",
+
+	FDI16x8Mul:              "I16x8Mul",
+	FDI16x8MinS:             "I16x8MinS",
+	FDI16x8MinU:             "I16x8MinU",
+	FDI16x8MaxS:             "I16x8MaxS",
+	FDI16x8MaxU:             "I16x8MaxU",
+	FDI16x8AvgrU:            "I16x8AvgrU",
+	FDI16x8ExtmulLowI8x16S:  "I16x8ExtmulLowI8x16S",
+	FDI16x8ExtmulHighI8x16S: "I16x8ExtmulHighI8x16S",
+	FDI16x8ExtmulLowI8x16U:  "I16x8ExtmulLowI8x16U",
+	FDI16x8ExtmulHighI8x16U: "I16x8ExtmulHighI8x16U",
+
+	FDI32x4Abs:              "I32x4Abs",
+	FDI32x4Neg:              "I32x4Neg",
+	FDI32x4AllTrue:          "I32x4AllTrue",
+	FDI32x4Bitmask:          "I32x4Bitmask",
+	FDI32x4ExtendLowI16x8S:  "I32x4ExtendLowI16x8S",
+	FDI32x4ExtendHighI16x8S: "I32x4ExtendHighI16x8S",
+	FDI32x4ExtendLowI16x8U:  "I32x4ExtendLowI16x8U",
+	FDI32x4ExtendHighI16x8U: "I32x4ExtendHighI16x8U",
+	FDI32x4Shl:              "I32x4Shl",
+	FDI32x4ShrS:             "I32x4ShrS",
+	FDI32x4ShrU:             "I32x4ShrU",
+	FDI32x4Add:              "I32x4Add",
+	FDI32x4Sub:              "I32x4Sub",
+	FDI32x4Mul:              "I32x4Mul",
+	FDI32x4MinS:             "I32x4MinS",
+	FDI32x4MinU:             "I32x4MinU",
+	FDI32x4MaxS:             "I32x4MaxS",
+	FDI32x4MaxU:             "I32x4MaxU",
+	FDI32x4DotI16x8S:        "I32x4DotI16x8S",
+	FDI32x4ExtmulLowI16x8S:  "I32x4ExtmulLowI16x8S",
+	FDI32x4ExtmulHighI16x8S: "I32x4ExtmulHighI16x8S",
+	FDI32x4ExtmulLowI16x8U:  "I32x4ExtmulLowI16x8U",
+	FDI32x4ExtmulHighI16x8U: "I32x4ExtmulHighI16x8U",
+
+	FDI64x2Abs:              "I64x2Abs",
+	FDI64x2Neg:              "I64x2Neg",
+	FDI64x2AllTrue:          "I64x2AllTrue",
+	FDI64x2Bitmask:          "I64x2Bitmask",
+	FDI64x2ExtendLowI32x4S:  "I64x2ExtendLowI32x4S",
+	FDI64x2ExtendHighI32x4S: "I64x2ExtendHighI32x4S",
+	FDI64x2ExtendLowI32x4U:  "I64x2ExtendLowI32x4U",
+	FDI64x2ExtendHighI32x4U: "I64x2ExtendHighI32x4U",
+	FDI64x2Shl:              "I64x2Shl",
+	FDI64x2ShrS:             "I64x2ShrS",
+	FDI64x2ShrU:             "I64x2ShrU",
+	FDI64x2Add:              "I64x2Add",
+	FDI64x2Sub:              "I64x2Sub",
+	FDI64x2Mul:              "I64x2Mul",
+	FDI64x2Eq:               "I64x2Eq",
+	FDI64x2Ne:               "I64x2Ne",
+	FDI64x2LtS:              "I64x2LtS",
+	FDI64x2GtS:              "I64x2GtS",
+	FDI64x2LeS:              "I64x2LeS",
+	FDI64x2GeS:              "I64x2GeS",
+	FDI64x2ExtmulLowI32x4S:  "I64x2ExtmulLowI32x4S",
+	FDI64x2ExtmulHighI32x4S: "I64x2ExtmulHighI32x4S",
+	FDI64x2ExtmulLowI32x4U:  "I64x2ExtmulLowI32x4U",
+	FDI64x2ExtmulHighI32x4U: "I64x2ExtmulHighI32x4U",
+
+	FDF32x4Abs:  "F32x4Abs",
+	FDF32x4Neg:  "F32x4Neg",
+	FDF32x4Sqrt: "F32x4Sqrt",
+	FDF32x4Add:  "F32x4Add",
+	FDF32x4Sub:  "F32x4Sub",
+	FDF32x4Mul:  "F32x4Mul",
+	FDF32x4Div:  "F32x4Div",
+	FDF32x4Min:  "F32x4Min",
+	FDF32x4Max:  "F32x4Max",
+	FDF32x4Pmin: "F32x4Pmin",
+	FDF32x4Pmax: "F32x4Pmax",
+
+	FDF64x2Abs:  "F64x2Abs",
+	FDF64x2Neg:  "F64x2Neg",
+	FDF64x2Sqrt: "F64x2Sqrt",
+	FDF64x2Add:  "F64x2Add",
+	FDF64x2Sub:  "F64x2Sub",
+	FDF64x2Mul:  "F64x2Mul",
+	FDF64x2Div:  "F64x2Div",
+	FDF64x2Min:  "F64x2Min",
+	FDF64x2Max:  "F64x2Max",
+	FDF64x2Pmin: "F64x2Pmin",
+	FDF64x2Pmax: "F64x2Pmax",
+
+	FDI32x4TruncSatF32x4S: "I32x4TruncSatF32x4S",
+	FDI32x4TruncSatF32x4U: "I32x4TruncSatF32x4U",
+
+	FDF32x4ConvertI32x4S: "F32x4ConvertI32x4S",
+	FDF32x4ConvertI32x4U: "F32x4ConvertI32x4U",
+
+	FDI32x4TruncSatF64x2SZero: "I32x4TruncSatF64x2SZero",
+	FDI32x4TruncSatF64x2UZero: "I32x4TruncSatF64x2UZero",
+
+	FDF64x2ConvertLowI32x4S: "F64x2ConvertLowI32x4S",
+	FDF64x2ConvertLowI32x4U: "F64x2ConvertLowI32x4U",
+}
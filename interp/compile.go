@@ -0,0 +1,239 @@
+package interp
+
+import (
+	"math"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// funcCode is a function body prepared for execution.
+	funcCode struct {
+		locals int // not including params
+		height int // maximum operand stack height
+		code   []instr
+
+		brTables [][]uint32 // BrTable labels with the default label at the end
+	}
+
+	// instr is a decoded instruction.
+	//
+	//	Block, Loop:   a is End pc, v is params << 32 | results
+	//	If:            a is End pc, b is Else pc or zero, v is params << 32 | results
+	//	Else:          a is End pc
+	//	Br, BrIf:      a is label depth
+	//	BrTable:       a is brTables index
+	//	CallIndir:     a is type index, b is table index
+	//	loads, stores: a is memarg offset
+	//	consts:        v is the value
+	//	prefixed:      op is fcOp | ext, a and b are the index immediates in the encoding order
+	//	others:        a is the index immediate if any
+	instr struct {
+		op  uint16
+		a   uint32
+		b   uint32
+		off uint32 // instruction offset in the function body
+		v   uint64
+	}
+)
+
+const fcOp = wasm.FCExt << 8
+
+// compile prepares validated function body for execution.
+// types are the module functions types.
+func (r *Runtime) compile(m *wasm.Module, types []wasm.FuncType, c wasm.Code) (fc *funcCode, err error) {
+	f, err := r.d.Func(c, wasm.FuncCode{})
+	if err != nil {
+		return nil, err
+	}
+
+	fc = &funcCode{
+		locals: len(f.Locals),
+	}
+
+	base := len(c) - len(f.Expr)
+
+	var in wasm.Instruction
+	var blocks []int // open Block, Loop and If pcs
+
+	for i := 0; i < len(f.Expr); {
+		in, i, err = r.d.Instruction(f.Expr, i, in)
+		if err != nil {
+			return nil, err
+		}
+
+		pc := len(fc.code)
+
+		x := instr{
+			op:  uint16(in.Opcode),
+			off: uint32(base + in.Offset),
+		}
+
+		switch op := in.Opcode; {
+		case op == wasm.Block || op == wasm.Loop || op == wasm.If:
+			params, results := blockArity(m, in.BlockType)
+			x.v = uint64(params)<<32 | uint64(results)
+
+			blocks = append(blocks, pc)
+		case op == wasm.Else:
+			open := blocks[len(blocks)-1]
+			fc.code[open].b = uint32(pc)
+		case op == wasm.End:
+			if len(blocks) != 0 {
+				open := blocks[len(blocks)-1]
+				blocks = blocks[:len(blocks)-1]
+
+				fc.code[open].a = uint32(pc)
+
+				if els := fc.code[open].b; els != 0 {
+					fc.code[els].a = uint32(pc)
+				}
+			}
+		case op == wasm.Br || op == wasm.BrIf:
+			x.a = uint32(in.Label)
+		case op == wasm.BrTable:
+			t := make([]uint32, 0, len(in.Labels)+1)
+
+			for _, l := range in.Labels {
+				t = append(t, uint32(l))
+			}
+
+			t = append(t, uint32(in.Label))
+
+			x.a = uint32(len(fc.brTables))
+			fc.brTables = append(fc.brTables, t)
+		case op == wasm.Call || op == wasm.RefFunc || op >= wasm.LocalGet && op <= wasm.GlobalSet:
+			x.a = uint32(in.Index)
+		case op == wasm.CallIndir:
+			x.a = uint32(in.Index)
+			x.b = uint32(in.Table)
+		case op == wasm.TableGet || op == wasm.TableSet:
+			x.a = uint32(in.Table)
+		case op >= wasm.I32Load && op <= wasm.I64Store32:
+			x.a = uint32(in.MemArg.Offset)
+		case op == wasm.I32Const:
+			x.v = uint64(uint32(in.I32))
+		case op == wasm.I64Const:
+			x.v = uint64(in.I64)
+		case op == wasm.F32Const:
+			x.v = uint64(math.Float32bits(in.F32))
+		case op == wasm.F64Const:
+			x.v = math.Float64bits(in.F64)
+		case op == wasm.FCExt:
+			x.op = fcOp | uint16(in.Ext)
+
+			switch in.Ext {
+			case wasm.FCMemoryInit, wasm.FCDataDrop, wasm.FCElemDrop:
+				x.a = uint32(in.Index)
+			case wasm.FCTableInit:
+				x.a = uint32(in.Index)
+				x.b = uint32(in.Table)
+			case wasm.FCTableCopy:
+				x.a = uint32(in.Table)
+				x.b = uint32(in.Src)
+			case wasm.FCTableGrow, wasm.FCTableSize, wasm.FCTableFill:
+				x.a = uint32(in.Table)
+			}
+		case op == wasm.FDExt || op == wasm.FEExt:
+			return nil, errors.New("offset 0x%x: unsupported instruction: %v", x.off, in.Name())
+		}
+
+		fc.code = append(fc.code, x)
+	}
+
+	fc.height = stackHeight(m, types, fc.code)
+
+	return fc, nil
+}
+
+// stackHeight returns the maximum operand stack height of the compiled body.
+func stackHeight(m *wasm.Module, types []wasm.FuncType, code []instr) (height int) {
+	type block struct {
+		pc   int
+		base int // height below params
+		dead bool
+	}
+
+	var blocks []block
+	var h int
+	var dead bool // unreachable code
+
+	for pc, x := range code {
+		switch x.op {
+		case wasm.Block, wasm.Loop, wasm.If:
+			if x.op == wasm.If {
+				h--
+			}
+
+			blocks = append(blocks, block{pc: pc, base: h - int(x.v>>32), dead: dead})
+		case wasm.Else:
+			b := blocks[len(blocks)-1]
+
+			h, dead = b.base+int(code[b.pc].v>>32), b.dead
+		case wasm.End:
+			if len(blocks) == 0 {
+				break
+			}
+
+			b := blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+
+			h, dead = b.base+int(uint32(code[b.pc].v)), b.dead
+		case wasm.Unreachable, wasm.Br, wasm.BrTable, wasm.Ret:
+			dead = true
+		default:
+			if !dead {
+				h += stackEffect(m, types, x)
+			}
+		}
+
+		height = max(height, h)
+	}
+
+	return height
+}
+
+// stackEffect returns the number of values pushed minus the number of values popped by the instruction.
+// Control instructions are handled by stackHeight.
+func stackEffect(m *wasm.Module, types []wasm.FuncType, x instr) int {
+	switch op := x.op; {
+	case op == wasm.Call:
+		tp := types[x.a]
+		return len(tp.Result) - len(tp.Params)
+	case op == wasm.CallIndir:
+		tp := m.Type[x.a]
+		return len(tp.Result) - len(tp.Params) - 1
+	case op == wasm.LocalGet, op == wasm.GlobalGet, op == wasm.RefNull, op == wasm.RefFunc, op == wasm.MemorySize,
+		op >= wasm.I32Const && op <= wasm.F64Const,
+		op == fcOp|wasm.FCTableSize:
+		return 1
+	case op == wasm.Drop, op == wasm.LocalSet, op == wasm.GlobalSet, op == wasm.BrIf,
+		op == fcOp|wasm.FCTableGrow:
+		return -1
+	case op == wasm.Select, op == wasm.SelectT, op == wasm.TableSet,
+		op >= wasm.I32Store && op <= wasm.I64Store32:
+		return -2
+	case op == fcOp|wasm.FCMemoryInit, op == fcOp|wasm.FCMemoryCopy, op == fcOp|wasm.FCMemoryFill,
+		op == fcOp|wasm.FCTableInit, op == fcOp|wasm.FCTableCopy, op == fcOp|wasm.FCTableFill:
+		return -3
+	case isBinop(op):
+		return -1
+	default:
+		return 0
+	}
+}
+
+func blockArity(m *wasm.Module, bt wasm.BlockType) (params, results int) {
+	if x, ok := bt.TypeIndex(); ok {
+		tp := m.Type[x]
+
+		return len(tp.Params), len(tp.Result)
+	}
+
+	if _, ok := bt.ValueType(); ok {
+		return 0, 1
+	}
+
+	return 0, 0
+}
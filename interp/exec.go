@@ -0,0 +1,586 @@
+package interp
+
+import (
+	"context"
+	"encoding/binary"
+
+	"nikand.dev/go/wasm"
+)
+
+type (
+	// machine is a single call execution state.
+	machine struct {
+		st     []uint64 // value stack, function locals included
+		labels []label
+		depth  int
+
+		ctx  context.Context
+		done <-chan struct{} // nil if can't be canceled
+
+		costs *Costs // nil if fuel is not metered
+		fuel  int64
+	}
+
+	label struct {
+		pc     int // continuation
+		height int // value stack height
+		arity  int
+		loop   bool
+	}
+)
+
+var le = binary.LittleEndian
+
+// memSize is the number of bytes accessed by load and store instructions.
+var memSize = [256]uint8{
+	wasm.I32Load:    4,
+	wasm.I64Load:    8,
+	wasm.F32Load:    4,
+	wasm.F64Load:    8,
+	wasm.I32Load8S:  1,
+	wasm.I32Load8U:  1,
+	wasm.I32Load16S: 2,
+	wasm.I32Load16U: 2,
+	wasm.I64Load8S:  1,
+	wasm.I64Load8U:  1,
+	wasm.I64Load16S: 2,
+	wasm.I64Load16U: 2,
+	wasm.I64Load32S: 4,
+	wasm.I64Load32U: 4,
+	wasm.I32Store:   4,
+	wasm.I64Store:   8,
+	wasm.F32Store:   4,
+	wasm.F64Store:   8,
+	wasm.I32Store8:  1,
+	wasm.I32Store16: 2,
+	wasm.I64Store8:  1,
+	wasm.I64Store16: 2,
+	wasm.I64Store32: 4,
+}
+
+// call calls f with its arguments on top of the stack
+// and leaves its results there.
+// caller is the calling instance, nil if called from Go.
+func (m *machine) call(f *Function, caller *Instance) (err error) {
+	depth, stack := MaxCallDepth, MaxStackSize
+
+	if f.inst != nil {
+		depth, stack = f.inst.lim.CallDepth, f.inst.lim.StackSize
+	}
+
+	if m.depth >= depth {
+		return ErrCallStackExhausted
+	}
+
+	if f.code != nil && len(m.st)+f.code.locals+f.code.height > stack {
+		return ErrValueStackExhausted
+	}
+
+	m.depth++
+
+	base := len(m.st) - len(f.Type.Params)
+
+	if f.host != nil {
+		err = m.callHost(f, caller, base)
+	} else {
+		for j := 0; j < f.code.locals; j++ {
+			m.st = append(m.st, 0)
+		}
+
+		err = m.exec(f, base)
+	}
+
+	m.depth--
+
+	return err
+}
+
+func (m *machine) callHost(f *Function, caller *Instance, base int) error {
+	res := len(f.Type.Result)
+
+	for len(m.st) < base+res {
+		m.st = append(m.st, 0)
+	}
+
+	c := Caller{inst: caller, m: m}
+	st := m.st[base:]
+
+	err := f.host(&c, st)
+	if err != nil {
+		return err
+	}
+
+	// the stack may have been reallocated by a Caller.Call
+	copy(m.st[base:], st[:res])
+
+	m.st = m.st[:base+res]
+
+	return nil
+}
+
+func (m *machine) exec(f *Function, base int) (err error) {
+	inst := f.inst
+	code := f.code.code
+	costs := m.costs
+	st := m.st
+
+	if err := m.interrupted(); err != nil {
+		return m.trap(f, 0, err)
+	}
+
+	var mem *Memory
+	if len(inst.mems) != 0 {
+		mem = inst.mems[0]
+	}
+
+	lbase := len(m.labels)
+	m.labels = append(m.labels, label{pc: len(code), height: len(st), arity: len(f.Type.Result)})
+
+	for pc := 0; pc < len(code); pc++ {
+		in := &code[pc]
+
+		if costs != nil && !m.charge(costs.Op[opcode(in.op)]) {
+			return m.trap(f, pc, ErrFuelExhausted)
+		}
+
+		switch in.op {
+		case wasm.Unreachable:
+			return m.trap(f, pc, ErrUnreachable)
+		case wasm.Nop:
+		case wasm.Block:
+			if costs != nil && !m.charge(costs.Block) {
+				return m.trap(f, pc, ErrFuelExhausted)
+			}
+
+			m.labels = append(m.labels, label{pc: int(in.a) + 1, height: len(st) - int(in.v>>32), arity: int(uint32(in.v))})
+		case wasm.Loop:
+			if costs != nil && !m.charge(costs.Block) {
+				return m.trap(f, pc, ErrFuelExhausted)
+			}
+
+			params := int(in.v >> 32)
+			m.labels = append(m.labels, label{pc: pc + 1, height: len(st) - params, arity: params, loop: true})
+		case wasm.If:
+			if costs != nil && !m.charge(costs.Block) {
+				return m.trap(f, pc, ErrFuelExhausted)
+			}
+
+			c := uint32(st[len(st)-1])
+			st = st[:len(st)-1]
+
+			m.labels = append(m.labels, label{pc: int(in.a) + 1, height: len(st) - int(in.v>>32), arity: int(uint32(in.v))})
+
+			switch {
+			case c != 0:
+			case in.b != 0:
+				pc = int(in.b)
+			default:
+				pc = int(in.a) - 1
+			}
+		case wasm.Else:
+			pc = int(in.a) - 1
+		case wasm.End:
+			m.labels = m.labels[:len(m.labels)-1]
+		case wasm.Br:
+			st, pc, err = m.br(f, st, pc, int(in.a))
+			if err != nil {
+				return err
+			}
+		case wasm.BrIf:
+			c := uint32(st[len(st)-1])
+			st = st[:len(st)-1]
+
+			if c != 0 {
+				st, pc, err = m.br(f, st, pc, int(in.a))
+				if err != nil {
+					return err
+				}
+			}
+		case wasm.BrTable:
+			t := f.code.brTables[in.a]
+
+			x := uint64(uint32(st[len(st)-1]))
+			st = st[:len(st)-1]
+
+			if x >= uint64(len(t)) {
+				x = uint64(len(t) - 1)
+			}
+
+			st, pc, err = m.br(f, st, pc, int(t[x]))
+			if err != nil {
+				return err
+			}
+		case wasm.Ret:
+			st, pc, _ = m.br(f, st, pc, len(m.labels)-1-lbase)
+		case wasm.Call:
+			m.st = st
+			err = m.call(inst.funcs[in.a], inst)
+			st = m.st
+
+			if err != nil {
+				return m.trap(f, pc, err)
+			}
+		case wasm.CallIndir:
+			x := uint64(uint32(st[len(st)-1]))
+			st = st[:len(st)-1]
+
+			t := inst.tables[in.b]
+			if x >= uint64(len(t.elems)) {
+				return m.trap(f, pc, ErrUndefinedElement)
+			}
+
+			callee := inst.r.funcRef(t.elems[x])
+			if callee == nil {
+				return m.trap(f, pc, ErrUninitializedElement)
+			}
+
+			if !equalTypes(callee.Type, inst.m.Type[in.a]) {
+				return m.trap(f, pc, ErrIndirectCallTypeMismatch)
+			}
+
+			m.st = st
+			err = m.call(callee, inst)
+			st = m.st
+
+			if err != nil {
+				return m.trap(f, pc, err)
+			}
+		case wasm.Drop:
+			st = st[:len(st)-1]
+		case wasm.Select, wasm.SelectT:
+			n := len(st) - 1
+
+			if uint32(st[n]) == 0 {
+				st[n-2] = st[n-1]
+			}
+
+			st = st[:n-1]
+		case wasm.LocalGet:
+			st = append(st, st[base+int(in.a)])
+		case wasm.LocalSet:
+			st[base+int(in.a)] = st[len(st)-1]
+			st = st[:len(st)-1]
+		case wasm.LocalTee:
+			st[base+int(in.a)] = st[len(st)-1]
+		case wasm.GlobalGet:
+			st = append(st, inst.globals[in.a].val)
+		case wasm.GlobalSet:
+			inst.globals[in.a].val = st[len(st)-1]
+			st = st[:len(st)-1]
+		case wasm.TableGet:
+			t := inst.tables[in.a]
+			x := uint64(uint32(st[len(st)-1]))
+
+			if x >= uint64(len(t.elems)) {
+				return m.trap(f, pc, ErrTableOutOfBounds)
+			}
+
+			st[len(st)-1] = t.elems[x]
+		case wasm.TableSet:
+			t := inst.tables[in.a]
+			x := uint64(uint32(st[len(st)-2]))
+
+			if x >= uint64(len(t.elems)) {
+				return m.trap(f, pc, ErrTableOutOfBounds)
+			}
+
+			t.elems[x] = st[len(st)-1]
+			st = st[:len(st)-2]
+		case wasm.I32Load, wasm.I64Load, wasm.F32Load, wasm.F64Load,
+			wasm.I32Load8S, wasm.I32Load8U, wasm.I32Load16S, wasm.I32Load16U,
+			wasm.I64Load8S, wasm.I64Load8U, wasm.I64Load16S, wasm.I64Load16U, wasm.I64Load32S, wasm.I64Load32U:
+			v, ok := mem.load(st[len(st)-1], in.a, int(memSize[in.op]))
+			if !ok {
+				return m.trap(f, pc, ErrMemoryOutOfBounds)
+			}
+
+			st[len(st)-1] = extendLoad(in.op, v)
+		case wasm.I32Store, wasm.I64Store, wasm.F32Store, wasm.F64Store,
+			wasm.I32Store8, wasm.I32Store16, wasm.I64Store8, wasm.I64Store16, wasm.I64Store32:
+			ok := mem.store(st[len(st)-2], in.a, int(memSize[in.op]), st[len(st)-1])
+			if !ok {
+				return m.trap(f, pc, ErrMemoryOutOfBounds)
+			}
+
+			st = st[:len(st)-2]
+		case wasm.MemorySize:
+			st = append(st, uint64(mem.Size()))
+		case wasm.MemoryGrow:
+			n := uint32(st[len(st)-1])
+
+			if costs != nil && !m.chargeN(int64(n), costs.MemoryGrow) {
+				return m.trap(f, pc, ErrFuelExhausted)
+			}
+
+			prev := -1
+			if int64(n) <= int64(inst.lim.MemoryPages-mem.Size()) {
+				prev = mem.Grow(int(n))
+			}
+
+			st[len(st)-1] = uint64(uint32(int32(prev)))
+		case wasm.I32Const, wasm.I64Const, wasm.F32Const, wasm.F64Const:
+			st = append(st, in.v)
+		case wasm.RefNull:
+			st = append(st, 0)
+		case wasm.RefIsNull:
+			st[len(st)-1] = b2u(st[len(st)-1] == 0)
+		case wasm.RefFunc:
+			st = append(st, inst.funcs[in.a].ref())
+		case fcOp | wasm.FCMemoryInit:
+			n := len(st) - 3
+			d, s, l := uint64(uint32(st[n])), uint64(uint32(st[n+1])), uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			data := inst.data[in.a]
+
+			if s+l > uint64(len(data)) || d+l > uint64(len(mem.buf)) {
+				return m.trap(f, pc, ErrMemoryOutOfBounds)
+			}
+
+			copy(mem.buf[d:], data[s:s+l])
+		case fcOp | wasm.FCDataDrop:
+			inst.data[in.a] = nil
+		case fcOp | wasm.FCMemoryCopy:
+			n := len(st) - 3
+			d, s, l := uint64(uint32(st[n])), uint64(uint32(st[n+1])), uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			if s+l > uint64(len(mem.buf)) || d+l > uint64(len(mem.buf)) {
+				return m.trap(f, pc, ErrMemoryOutOfBounds)
+			}
+
+			copy(mem.buf[d:], mem.buf[s:s+l])
+		case fcOp | wasm.FCMemoryFill:
+			n := len(st) - 3
+			d, v, l := uint64(uint32(st[n])), byte(st[n+1]), uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			if d+l > uint64(len(mem.buf)) {
+				return m.trap(f, pc, ErrMemoryOutOfBounds)
+			}
+
+			for j := range mem.buf[d : d+l] {
+				mem.buf[d+uint64(j)] = v
+			}
+		case fcOp | wasm.FCTableInit:
+			n := len(st) - 3
+			d, s, l := uint64(uint32(st[n])), uint64(uint32(st[n+1])), uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			refs := inst.elems[in.a]
+			t := inst.tables[in.b]
+
+			if s+l > uint64(len(refs)) || d+l > uint64(len(t.elems)) {
+				return m.trap(f, pc, ErrTableOutOfBounds)
+			}
+
+			copy(t.elems[d:], refs[s:s+l])
+		case fcOp | wasm.FCElemDrop:
+			inst.elems[in.a] = nil
+		case fcOp | wasm.FCTableCopy:
+			n := len(st) - 3
+			d, s, l := uint64(uint32(st[n])), uint64(uint32(st[n+1])), uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			dst, src := inst.tables[in.a], inst.tables[in.b]
+
+			if s+l > uint64(len(src.elems)) || d+l > uint64(len(dst.elems)) {
+				return m.trap(f, pc, ErrTableOutOfBounds)
+			}
+
+			copy(dst.elems[d:], src.elems[s:s+l])
+		case fcOp | wasm.FCTableGrow:
+			n := len(st) - 2
+			ref, l := st[n], uint32(st[n+1])
+			st = st[:n+1]
+
+			t := inst.tables[in.a]
+
+			prev := -1
+			if int64(l) <= int64(inst.lim.TableSize-t.Size()) {
+				prev = t.Grow(int(l), ref)
+			}
+
+			st[n] = uint64(uint32(int32(prev)))
+		case fcOp | wasm.FCTableSize:
+			st = append(st, uint64(len(inst.tables[in.a].elems)))
+		case fcOp | wasm.FCTableFill:
+			n := len(st) - 3
+			d, ref, l := uint64(uint32(st[n])), st[n+1], uint64(uint32(st[n+2]))
+			st = st[:n]
+
+			t := inst.tables[in.a]
+
+			if d+l > uint64(len(t.elems)) {
+				return m.trap(f, pc, ErrTableOutOfBounds)
+			}
+
+			for j := range t.elems[d : d+l] {
+				t.elems[d+uint64(j)] = ref
+			}
+		default:
+			n := len(st) - 1
+
+			if isBinop(in.op) {
+				st[n-1], err = binop(in.op, st[n-1], st[n])
+				st = st[:n]
+			} else {
+				st[n], err = unop(in.op, st[n])
+			}
+
+			if err != nil {
+				return m.trap(f, pc, err)
+			}
+		}
+	}
+
+	m.labels = m.labels[:lbase]
+
+	res := len(f.Type.Result)
+	copy(st[base:], st[len(st)-res:])
+	m.st = st[:base+res]
+
+	return nil
+}
+
+// br branches to the label at depth and returns the next pc minus one.
+// Loop back-edges are charged and checked for cancellation.
+func (m *machine) br(f *Function, st []uint64, pc, depth int) ([]uint64, int, error) {
+	top := len(m.labels) - 1 - depth
+	l := m.labels[top]
+
+	copy(st[l.height:], st[len(st)-l.arity:])
+	st = st[:l.height+l.arity]
+
+	if l.loop {
+		top++
+
+		if m.costs != nil && !m.charge(m.costs.Block) {
+			return st, pc, m.trap(f, pc, ErrFuelExhausted)
+		}
+
+		if err := m.interrupted(); err != nil {
+			return st, pc, m.trap(f, pc, err)
+		}
+	}
+
+	m.labels = m.labels[:top]
+
+	return st, l.pc - 1, nil
+}
+
+// charge subtracts cost from fuel and reports if it's not exhausted.
+func (m *machine) charge(cost int64) bool {
+	m.fuel -= cost
+
+	return m.fuel >= 0
+}
+
+// chargeN charges n times cost.
+func (m *machine) chargeN(n, cost int64) bool {
+	if cost > 0 && n > m.fuel/cost {
+		m.fuel = -1
+		return false
+	}
+
+	return m.charge(n * cost)
+}
+
+// interrupted returns the context error if it's canceled.
+func (m *machine) interrupted() error {
+	if m.done == nil {
+		return nil
+	}
+
+	select {
+	case <-m.done:
+		return m.ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// opcode returns the instruction opcode or its prefix.
+func opcode(op uint16) uint8 {
+	if op > 0xff {
+		return uint8(op >> 8)
+	}
+
+	return uint8(op)
+}
+
+// trap wraps err with the place it happened unless it's a Trap already.
+func (m *machine) trap(f *Function, pc int, err error) error {
+	if _, ok := err.(Trap); ok {
+		return err
+	}
+
+	return Trap{
+		Func:   f.index,
+		Offset: int(f.code.code[pc].off),
+		Err:    err,
+	}
+}
+
+// addr returns effective address of n bytes access if it's in bounds.
+func (m *Memory) addr(base uint64, off uint32, n int) (uint64, bool) {
+	ea := uint64(uint32(base)) + uint64(off)
+
+	return ea, ea+uint64(n) <= uint64(len(m.buf))
+}
+
+func (m *Memory) load(base uint64, off uint32, n int) (uint64, bool) {
+	ea, ok := m.addr(base, off, n)
+	if !ok {
+		return 0, false
+	}
+
+	switch n {
+	case 1:
+		return uint64(m.buf[ea]), true
+	case 2:
+		return uint64(le.Uint16(m.buf[ea:])), true
+	case 4:
+		return uint64(le.Uint32(m.buf[ea:])), true
+	default:
+		return le.Uint64(m.buf[ea:]), true
+	}
+}
+
+func (m *Memory) store(base uint64, off uint32, n int, v uint64) bool {
+	ea, ok := m.addr(base, off, n)
+	if !ok {
+		return false
+	}
+
+	switch n {
+	case 1:
+		m.buf[ea] = byte(v)
+	case 2:
+		le.PutUint16(m.buf[ea:], uint16(v))
+	case 4:
+		le.PutUint32(m.buf[ea:], uint32(v))
+	default:
+		le.PutUint64(m.buf[ea:], v)
+	}
+
+	return true
+}
+
+// extendLoad sign extends loaded value if needed.
+func extendLoad(op uint16, v uint64) uint64 {
+	switch op {
+	case wasm.I32Load8S:
+		return uint64(uint32(int8(v)))
+	case wasm.I32Load16S:
+		return uint64(uint32(int16(v)))
+	case wasm.I64Load8S:
+		return uint64(int8(v))
+	case wasm.I64Load16S:
+		return uint64(int16(v))
+	case wasm.I64Load32S:
+		return uint64(int32(v))
+	default:
+		return v
+	}
+}
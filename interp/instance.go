@@ -0,0 +1,327 @@
+package interp
+
+import (
+	"math"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+// MaxTableSize is the table size limit if the maximum is not set.
+const MaxTableSize = 10_000_000
+
+// alloc creates functions, tables, memories and globals,
+// and evaluates segments.
+// Imports go first in the index spaces.
+func (inst *Instance) alloc(imports []Extern) (err error) {
+	m := inst.m
+
+	for _, ext := range imports {
+		switch ext := ext.(type) {
+		case *Function:
+			inst.funcs = append(inst.funcs, ext)
+		case *Table:
+			if ext.Size() > inst.lim.TableSize {
+				return errors.New("table %d: size %d is over the limit %d", len(inst.tables), ext.Size(), inst.lim.TableSize)
+			}
+
+			inst.tables = append(inst.tables, ext)
+		case *Memory:
+			if ext.Size() > inst.lim.MemoryPages {
+				return errors.New("memory %d: size %d is over the limit %d", len(inst.mems), ext.Size(), inst.lim.MemoryPages)
+			}
+
+			inst.mems = append(inst.mems, ext)
+		case *Global:
+			inst.globals = append(inst.globals, ext)
+		}
+	}
+
+	types := make([]wasm.FuncType, 0, len(inst.funcs)+len(m.Function))
+
+	for _, f := range inst.funcs {
+		types = append(types, f.Type)
+	}
+
+	for _, tx := range m.Function {
+		types = append(types, m.Type[tx])
+	}
+
+	for i, tx := range m.Function {
+		f := &Function{
+			Type:  m.Type[tx],
+			inst:  inst,
+			index: wasm.Index(len(inst.funcs)),
+		}
+
+		f.code, err = inst.r.compile(m, types, m.Code[i])
+		if err != nil {
+			return errors.Wrap(err, "func %d", f.index)
+		}
+
+		inst.r.addFunc(f)
+		inst.funcs = append(inst.funcs, f)
+	}
+
+	for _, t := range m.Table {
+		t.Limits, err = clampLimits(t.Limits, inst.lim.TableSize)
+		if err != nil {
+			return errors.Wrap(err, "table %d", len(inst.tables))
+		}
+
+		inst.tables = append(inst.tables, NewTable(t))
+	}
+
+	for _, l := range m.Memory {
+		l, err = clampLimits(l, inst.lim.MemoryPages)
+		if err != nil {
+			return errors.Wrap(err, "memory %d", len(inst.mems))
+		}
+
+		inst.mems = append(inst.mems, NewMemory(l))
+	}
+
+	for _, g := range m.Global {
+		val, err := inst.constExpr(g.Expr)
+		if err != nil {
+			return errors.Wrap(err, "global %d", len(inst.globals))
+		}
+
+		inst.globals = append(inst.globals, &Global{Type: g.Type, Mut: g.Mut != 0, val: val})
+	}
+
+	for _, e := range m.Export {
+		inst.exports[string(e.Name)] = e
+	}
+
+	for i, el := range m.Element {
+		refs, err := inst.elemRefs(el)
+		if err != nil {
+			return errors.Wrap(err, "elem %d", i)
+		}
+
+		inst.elems = append(inst.elems, refs)
+	}
+
+	for _, d := range m.Data {
+		inst.data = append(inst.data, d.Init)
+	}
+
+	return nil
+}
+
+// clampLimits sets the maximum to be no more than limit.
+func clampLimits(l wasm.Limits, limit int) (wasm.Limits, error) {
+	if l.Lo > limit {
+		return l, errors.New("minimum %d is over the limit %d", l.Lo, limit)
+	}
+
+	if l.Hi < 0 || l.Hi > limit {
+		l.Hi = limit
+	}
+
+	return l, nil
+}
+
+// init copies active segments and calls the start function with e.
+func (inst *Instance) init(e *Exec) error {
+	m := inst.m
+
+	for i, el := range m.Element {
+		if el.Mode == wasm.ElemPassive {
+			continue
+		}
+
+		refs := inst.elems[i]
+		inst.elems[i] = nil
+
+		if el.Mode != wasm.ElemActive {
+			continue
+		}
+
+		off, err := inst.constExpr(el.Expr)
+		if err != nil {
+			return errors.Wrap(err, "elem %d", i)
+		}
+
+		t := inst.tables[el.Table]
+
+		if uint64(uint32(off))+uint64(len(refs)) > uint64(len(t.elems)) {
+			return errors.Wrap(ErrTableOutOfBounds, "elem %d", i)
+		}
+
+		copy(t.elems[uint32(off):], refs)
+	}
+
+	for i, d := range m.Data {
+		if d.Mode != wasm.DataActive {
+			continue
+		}
+
+		inst.data[i] = nil
+
+		off, err := inst.constExpr(d.Expr)
+		if err != nil {
+			return errors.Wrap(err, "data %d", i)
+		}
+
+		mem := inst.mems[d.Memory]
+
+		if uint64(uint32(off))+uint64(len(d.Init)) > uint64(len(mem.buf)) {
+			return errors.Wrap(ErrMemoryOutOfBounds, "data %d", i)
+		}
+
+		copy(mem.buf[uint32(off):], d.Init)
+	}
+
+	if m.HasStart {
+		if e == nil {
+			e = &Exec{}
+		}
+
+		_, err := e.Call(inst.funcs[m.Start])
+		if err != nil {
+			return errors.Wrap(err, "start")
+		}
+	}
+
+	return nil
+}
+
+// elemRefs evaluates element segment references.
+func (inst *Instance) elemRefs(el wasm.Element) (refs []uint64, err error) {
+	for _, x := range el.Funcs {
+		refs = append(refs, inst.funcs[x].ref())
+	}
+
+	for _, e := range el.Init {
+		ref, err := inst.constExpr(e)
+		if err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// constExpr evaluates a validated constant expression.
+func (inst *Instance) constExpr(e wasm.Code) (val uint64, err error) {
+	var in wasm.Instruction
+
+	for i := 0; i < len(e); {
+		in, i, err = inst.r.d.Instruction(e, i, in)
+		if err != nil {
+			return 0, err
+		}
+
+		switch in.Opcode {
+		case wasm.I32Const:
+			val = uint64(uint32(in.I32))
+		case wasm.I64Const:
+			val = uint64(in.I64)
+		case wasm.F32Const:
+			val = uint64(math.Float32bits(in.F32))
+		case wasm.F64Const:
+			val = math.Float64bits(in.F64)
+		case wasm.RefNull:
+			val = 0
+		case wasm.RefFunc:
+			val = inst.funcs[in.Index].ref()
+		case wasm.GlobalGet:
+			val = inst.globals[in.Index].val
+		case wasm.End:
+			return val, nil
+		default:
+			return 0, errors.New("unsupported constant expression: %v", in.Name())
+		}
+	}
+
+	return 0, wasm.ErrUnexpectedEOF
+}
+
+func (r *Runtime) addFunc(f *Function) {
+	f.r = r
+	f.addr = len(r.funcs)
+	r.funcs = append(r.funcs, f)
+}
+
+// release removes the functions added to the Runtime by a failed instantiation.
+// References to them left in the imported tables and globals are reset to null,
+// so they don't point to the functions added later.
+func (inst *Instance) release(funcs int) {
+	r := inst.r
+
+	clear(r.funcs[funcs:])
+	r.funcs = r.funcs[:funcs]
+
+	dangling := func(ref uint64) bool { return ref > uint64(funcs) }
+
+	for _, t := range inst.tables {
+		if t.Type != wasm.FuncRef {
+			continue
+		}
+
+		for i, ref := range t.elems {
+			if dangling(ref) {
+				t.elems[i] = 0
+			}
+		}
+	}
+
+	for _, g := range inst.globals {
+		if g.Type == wasm.FuncRef && dangling(g.val) {
+			g.val = 0
+		}
+	}
+}
+
+// bindHost returns the host function copy bound to r.
+// The same copy is returned for the same function, so its references are equal.
+func (r *Runtime) bindHost(f *Function) *Function {
+	if b, ok := r.hosts[f]; ok {
+		return b
+	}
+
+	if r.hosts == nil {
+		r.hosts = make(map[*Function]*Function)
+	}
+
+	b := NewFunction(f.Type, f.host)
+	r.addFunc(b)
+	r.hosts[f] = b
+
+	return b
+}
+
+// ref returns funcref value of the function.
+func (f *Function) ref() uint64 { return uint64(f.addr) + 1 }
+
+// funcRef returns function by its funcref value.
+func (r *Runtime) funcRef(ref uint64) *Function {
+	if ref == 0 || ref > uint64(len(r.funcs)) {
+		return nil
+	}
+
+	return r.funcs[ref-1]
+}
+
+// equalTypes reports whether function types are the same.
+func equalTypes(a, b wasm.FuncType) bool {
+	return equalResultTypes(a.Params, b.Params) && equalResultTypes(a.Result, b.Result)
+}
+
+func equalResultTypes(a, b wasm.ResultType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,547 @@
+// Package interp implements a WebAssembly interpreter.
+//
+// Values are passed as uint64:
+// i32 is zero extended, f32 and f64 are IEEE 754 bits (math.Float32bits, math.Float64bits),
+// funcref is an opaque non-zero handle, externref is any value chosen by the host,
+// null references are zero.
+package interp
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// Runtime is a store of instances and their functions, tables, memories and globals.
+	// Zero value is ready to use. It's not safe for concurrent use.
+	Runtime struct {
+		v wasm.Validator
+		d wasm.InstructionsDecoder
+
+		funcs []*Function             // funcref is an index here plus one
+		hosts map[*Function]*Function // host functions bound to the runtime
+	}
+
+	// Instance is an instantiated module.
+	Instance struct {
+		r *Runtime
+		m *wasm.Module
+
+		funcs   []*Function
+		tables  []*Table
+		mems    []*Memory
+		globals []*Global
+
+		elems [][]uint64 // element segments refs, nil if dropped
+		data  [][]byte   // data segments, nil if dropped
+
+		exports map[string]wasm.Export
+
+		lim Limits
+	}
+
+	// Limits are instance resource limits.
+	// Memory and table limits clamp the maximums declared by the module,
+	// instantiation fails if the minimum is over the limit.
+	// Imported memories and tables must not be over the limits when imported,
+	// and the instance code doesn't grow them over the limits.
+	// Call depth and value stack size are checked when the instance functions are called.
+	// Zero fields mean the defaults.
+	// The default memory limit is kept low for untrusted modules,
+	// set MemoryPages up to wasm.MaxPages for modules that need more.
+	Limits struct {
+		MemoryPages int // MaxMemoryPages by default
+		TableSize   int // MaxTableSize by default
+		CallDepth   int // MaxCallDepth by default
+		StackSize   int // in values, locals included, MaxStackSize by default
+	}
+
+	// Function is a function instance.
+	// It's either defined by a module or is a host function.
+	Function struct {
+		Type wasm.FuncType
+
+		r     *Runtime   // nil for host function not bound to a Runtime
+		addr  int        // index in the Runtime store
+		inst  *Instance  // defining instance
+		index wasm.Index // index in the defining instance
+
+		code *funcCode
+		host HostFunc
+	}
+
+	// HostFunc is a Go function called by the module.
+	// stack holds the arguments, results are to be written at its start.
+	// It's long enough for both.
+	// Returned error traps the execution.
+	HostFunc func(c *Caller, stack []uint64) error
+
+	// Caller is a host function call context.
+	Caller struct {
+		inst *Instance
+		m    *machine
+	}
+
+	// Exec is a call configuration.
+	// Zero value calls the function without limits.
+	Exec struct {
+		// Context cancellation is checked at function calls and loop back-edges.
+		Context context.Context
+
+		// Costs enables fuel metering.
+		// Fuel is the available fuel, it's decreased by the call.
+		Costs *Costs
+		Fuel  int64
+	}
+
+	// Options are instantiation options.
+	// Zero value uses the default Limits and calls the start function without fuel metering and Context.
+	Options struct {
+		Limits Limits
+
+		// Exec is used to call the start function.
+		Exec *Exec
+	}
+
+	// Costs is a fuel cost model.
+	// Prefixed instructions are charged by their prefix opcode.
+	Costs struct {
+		Op         [256]int64
+		Block      int64 // block, loop and if entry and loop iteration
+		MemoryGrow int64 // per page
+	}
+
+	// Extern is an imported or exported value:
+	// *Function, *Table, *Memory or *Global.
+	Extern interface {
+		externKind() byte
+	}
+
+	// Memory is a linear memory instance.
+	Memory struct {
+		buf []byte
+		max int // pages, -1 if not limited
+	}
+
+	// Table is a table instance.
+	Table struct {
+		Type wasm.Type
+
+		elems []uint64
+		max   int // -1 if not limited
+	}
+
+	// Global is a global variable instance.
+	Global struct {
+		Type wasm.Type
+		Mut  bool
+
+		val uint64
+	}
+
+	// Trap is an execution error with the place where it happened.
+	Trap struct {
+		Func   wasm.Index // function index in its instance
+		Offset int        // instruction offset in the function body
+		Err    error
+	}
+)
+
+// PageSize is the linear memory page size.
+const PageSize = 1 << 16
+
+// MaxMemoryPages is the default memory size limit in pages, 256MiB.
+const MaxMemoryPages = 1 << 12
+
+// MaxCallDepth is the default maximum number of nested calls.
+const MaxCallDepth = 1 << 14
+
+// MaxStackSize is the default maximum value stack size.
+const MaxStackSize = 1 << 24
+
+// Traps.
+var (
+	ErrUnreachable              = stderrors.New("unreachable")
+	ErrMemoryOutOfBounds        = stderrors.New("out of bounds memory access")
+	ErrTableOutOfBounds         = stderrors.New("out of bounds table access")
+	ErrIntegerDivideByZero      = stderrors.New("integer divide by zero")
+	ErrIntegerOverflow          = stderrors.New("integer overflow")
+	ErrInvalidConversion        = stderrors.New("invalid conversion to integer")
+	ErrUndefinedElement         = stderrors.New("undefined element")
+	ErrUninitializedElement     = stderrors.New("uninitialized element")
+	ErrIndirectCallTypeMismatch = stderrors.New("indirect call type mismatch")
+	ErrCallStackExhausted       = stderrors.New("call stack exhausted")
+	ErrValueStackExhausted      = stderrors.New("value stack exhausted")
+	ErrFuelExhausted            = stderrors.New("fuel exhausted")
+)
+
+// Instantiate validates the module and creates its instance.
+// Active data and element segments are copied and the start function is called.
+// The module must not be changed while the instance is used.
+// Modules with imports are instantiated by Linker.
+func (r *Runtime) Instantiate(m *wasm.Module) (*Instance, error) {
+	return r.InstantiateWith(m, Options{})
+}
+
+// InstantiateWith is the same as Instantiate but takes options.
+func (r *Runtime) InstantiateWith(m *wasm.Module, opts Options) (*Instance, error) {
+	err := r.v.Module(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "validate")
+	}
+
+	if len(m.Import) != 0 {
+		im := m.Import[0]
+		return nil, errors.New("unresolved import %q.%q", im.Module, im.Name)
+	}
+
+	return r.instantiate(m, nil, opts)
+}
+
+// instantiate creates an instance of validated module with resolved imports.
+func (r *Runtime) instantiate(m *wasm.Module, imports []Extern, opts Options) (*Instance, error) {
+	inst := &Instance{
+		r:       r,
+		m:       m,
+		exports: make(map[string]wasm.Export, len(m.Export)),
+		lim:     opts.Limits.withDefaults(),
+	}
+
+	funcs := len(r.funcs)
+
+	err := inst.alloc(imports)
+	if err == nil {
+		err = inst.init(opts.Exec)
+	}
+
+	if err != nil {
+		inst.release(funcs)
+		return nil, err
+	}
+
+	return inst, nil
+}
+
+// withDefaults returns the limits with zero fields set to the defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MemoryPages <= 0 {
+		l.MemoryPages = MaxMemoryPages
+	}
+
+	if l.TableSize <= 0 {
+		l.TableSize = MaxTableSize
+	}
+
+	if l.CallDepth <= 0 {
+		l.CallDepth = MaxCallDepth
+	}
+
+	if l.StackSize <= 0 {
+		l.StackSize = MaxStackSize
+	}
+
+	return l
+}
+
+// Module returns the module the instance was created from.
+func (inst *Instance) Module() *wasm.Module { return inst.m }
+
+// Func returns exported function or nil.
+func (inst *Instance) Func(name string) *Function {
+	e, ok := inst.exports[name]
+	if !ok || e.ExportType != wasm.ExternFunc {
+		return nil
+	}
+
+	return inst.funcs[e.Index]
+}
+
+// Memory returns exported memory or nil.
+func (inst *Instance) Memory(name string) *Memory {
+	e, ok := inst.exports[name]
+	if !ok || e.ExportType != wasm.ExternMemory {
+		return nil
+	}
+
+	return inst.mems[e.Index]
+}
+
+// Table returns exported table or nil.
+func (inst *Instance) Table(name string) *Table {
+	e, ok := inst.exports[name]
+	if !ok || e.ExportType != wasm.ExternTable {
+		return nil
+	}
+
+	return inst.tables[e.Index]
+}
+
+// Global returns exported global or nil.
+func (inst *Instance) Global(name string) *Global {
+	e, ok := inst.exports[name]
+	if !ok || e.ExportType != wasm.ExternGlobal {
+		return nil
+	}
+
+	return inst.globals[e.Index]
+}
+
+// Export returns exported value or nil.
+func (inst *Instance) Export(name string) Extern {
+	e, ok := inst.exports[name]
+	if !ok {
+		return nil
+	}
+
+	switch e.ExportType {
+	case wasm.ExternFunc:
+		return inst.funcs[e.Index]
+	case wasm.ExternTable:
+		return inst.tables[e.Index]
+	case wasm.ExternMemory:
+		return inst.mems[e.Index]
+	case wasm.ExternGlobal:
+		return inst.globals[e.Index]
+	}
+
+	return nil
+}
+
+// Call calls exported function.
+func (inst *Instance) Call(name string, args ...uint64) ([]uint64, error) {
+	f := inst.Func(name)
+	if f == nil {
+		return nil, errors.New("no exported function %q", name)
+	}
+
+	return f.Call(args...)
+}
+
+// NewFunction creates a host function.
+func NewFunction(tp wasm.FuncType, fn HostFunc) *Function {
+	return &Function{
+		Type:  tp,
+		index: -1,
+		host:  fn,
+	}
+}
+
+// Call calls the function and returns its results.
+// Returned error is a Trap if execution failed.
+func (f *Function) Call(args ...uint64) ([]uint64, error) {
+	var e Exec
+
+	return e.Call(f, args...)
+}
+
+// Call calls the function and returns its results.
+// Returned error is a Trap if execution failed,
+// its Err is ErrFuelExhausted if fuel is over or the Context error if it's canceled.
+func (e *Exec) Call(f *Function, args ...uint64) ([]uint64, error) {
+	if len(args) != len(f.Type.Params) {
+		return nil, errors.New("expected %d arguments, got %d", len(f.Type.Params), len(args))
+	}
+
+	m := &machine{
+		st:    make([]uint64, 0, 1024),
+		ctx:   e.Context,
+		costs: e.Costs,
+		fuel:  e.Fuel,
+	}
+
+	if m.ctx != nil {
+		m.done = m.ctx.Done()
+	}
+
+	m.st = append(m.st, args...)
+
+	err := m.call(f, nil)
+
+	if e.Costs != nil {
+		e.Fuel = max(m.fuel, 0)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m.st, nil
+}
+
+// Call calls f within the current call, so it shares the fuel, the Context and the call depth.
+// Host functions use it to call back into the module.
+func (c *Caller) Call(f *Function, args ...uint64) ([]uint64, error) {
+	if c.m == nil {
+		return f.Call(args...)
+	}
+
+	if len(args) != len(f.Type.Params) {
+		return nil, errors.New("expected %d arguments, got %d", len(f.Type.Params), len(args))
+	}
+
+	m := c.m
+	h := len(m.st)
+
+	m.st = append(m.st, args...)
+
+	err := m.call(f, c.inst)
+
+	var res []uint64
+
+	if err == nil {
+		res = append(res, m.st[h:]...)
+	}
+
+	m.st = m.st[:h]
+
+	return res, err
+}
+
+// Instance returns the calling instance or nil if called from Go.
+func (c *Caller) Instance() *Instance { return c.inst }
+
+// Context returns the call Context.
+func (c *Caller) Context() context.Context {
+	if c.m == nil || c.m.ctx == nil {
+		return context.Background()
+	}
+
+	return c.m.ctx
+}
+
+// Memory returns the caller memory or nil.
+// It's the memory exported as "memory" or the first one.
+func (c *Caller) Memory() *Memory {
+	if c.inst == nil {
+		return nil
+	}
+
+	if mem := c.inst.Memory("memory"); mem != nil {
+		return mem
+	}
+
+	if len(c.inst.mems) != 0 {
+		return c.inst.mems[0]
+	}
+
+	return nil
+}
+
+// NewMemory creates a memory.
+func NewMemory(l wasm.Limits) *Memory {
+	return &Memory{
+		buf: make([]byte, l.Lo*PageSize),
+		max: l.Hi,
+	}
+}
+
+// Size returns memory size in pages.
+func (m *Memory) Size() int { return len(m.buf) / PageSize }
+
+// Bytes returns memory contents.
+// The slice is invalidated by Grow.
+func (m *Memory) Bytes() []byte { return m.buf }
+
+// Grow grows memory by n pages and returns the previous size or -1.
+func (m *Memory) Grow(n int) int {
+	prev := m.Size()
+
+	max := m.max
+	if max < 0 {
+		max = wasm.MaxPages
+	}
+
+	if n < 0 || n > max-prev {
+		return -1
+	}
+
+	m.buf = append(m.buf, make([]byte, n*PageSize)...)
+
+	return prev
+}
+
+// NewTable creates a table with null elements.
+func NewTable(t wasm.Table) *Table {
+	return &Table{
+		Type:  t.Type,
+		elems: make([]uint64, t.Limits.Lo),
+		max:   t.Limits.Hi,
+	}
+}
+
+// Size returns the number of table elements.
+func (t *Table) Size() int { return len(t.elems) }
+
+// Get returns table element.
+func (t *Table) Get(i int) (uint64, error) {
+	if i < 0 || i >= len(t.elems) {
+		return 0, ErrTableOutOfBounds
+	}
+
+	return t.elems[i], nil
+}
+
+// Set sets table element.
+func (t *Table) Set(i int, ref uint64) error {
+	if i < 0 || i >= len(t.elems) {
+		return ErrTableOutOfBounds
+	}
+
+	t.elems[i] = ref
+
+	return nil
+}
+
+// Grow grows table by n elements set to ref and returns the previous size or -1.
+func (t *Table) Grow(n int, ref uint64) int {
+	prev := len(t.elems)
+
+	max := t.max
+	if max < 0 {
+		max = MaxTableSize
+	}
+
+	if n < 0 || n > max-prev {
+		return -1
+	}
+
+	for j := 0; j < n; j++ {
+		t.elems = append(t.elems, ref)
+	}
+
+	return prev
+}
+
+// NewGlobal creates a global.
+func NewGlobal(tp wasm.Type, mut bool, val uint64) *Global {
+	return &Global{Type: tp, Mut: mut, val: val}
+}
+
+// Get returns global value.
+func (g *Global) Get() uint64 { return g.val }
+
+// Set sets global value.
+func (g *Global) Set(v uint64) error {
+	if !g.Mut {
+		return errors.New("global is immutable")
+	}
+
+	g.val = v
+
+	return nil
+}
+
+func (f *Function) externKind() byte { return wasm.ExternFunc }
+func (t *Table) externKind() byte    { return wasm.ExternTable }
+func (m *Memory) externKind() byte   { return wasm.ExternMemory }
+func (g *Global) externKind() byte   { return wasm.ExternGlobal }
+
+func (t Trap) Error() string {
+	return fmt.Sprintf("trap: func %d: offset 0x%x: %v", t.Func, t.Offset, t.Err)
+}
+
+func (t Trap) Unwrap() error { return t.Err }
@@ -0,0 +1,518 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+	"nikand.dev/go/wasm/wat"
+)
+
+func parseModule(tb testing.TB, text string) *wasm.Module {
+	tb.Helper()
+
+	var p wat.Parser
+	var m wasm.Module
+
+	err := p.Module([]byte(text), &m)
+	require.NoError(tb, err)
+
+	return &m
+}
+
+func instantiate(tb testing.TB, text string) *Instance {
+	tb.Helper()
+
+	var r Runtime
+
+	inst, err := r.Instantiate(parseModule(tb, text))
+	require.NoError(tb, err)
+
+	return inst
+}
+
+func TestCall(tb *testing.T) {
+	inst := instantiate(tb, `(module
+  (func $fac (export "fac") (param i64) (result i64)
+    (if (result i64) (i64.eqz (local.get 0))
+      (then (i64.const 1))
+      (else (i64.mul (local.get 0) (call $fac (i64.sub (local.get 0) (i64.const 1)))))))
+  (func (export "fib") (param i32) (result i32) (local i32 i32)
+    (local.set 1 (i32.const 0))
+    (local.set 2 (i32.const 1))
+    (block $done
+      (loop $next
+        (br_if $done (i32.eqz (local.get 0)))
+        (local.set 2 (i32.add (local.get 1) (local.tee 1 (local.get 2))))
+        (local.set 0 (i32.sub (local.get 0) (i32.const 1)))
+        (br $next)))
+    (local.get 1))
+  (func (export "swap") (param i32 i32) (result i32 i32)
+    (local.get 1) (local.get 0))
+  (func (export "switch") (param i32) (result i32)
+    (block $d
+      (block $b1
+        (block $b0
+          (br_table $b0 $b1 $d (local.get 0)))
+        (return (i32.const 10)))
+      (return (i32.const 11)))
+    (i32.const -1))
+  (func (export "select") (param i32) (result i64)
+    (select (i64.const 1) (i64.const 2) (local.get 0)))
+  (func (export "count") (param $n i32) (result i32) (local $v i32)
+    (i32.const 0)
+    (loop $l (param i32) (result i32)
+      (local.set $v (i32.add (i32.const 1)))
+      (local.get $v)
+      (br_if $l (i32.lt_u (local.get $v) (local.get $n))))))
+`)
+
+	res, err := inst.Call("fac", 20)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{2432902008176640000}, res)
+
+	res, err = inst.Call("fib", 10)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{55}, res)
+
+	res, err = inst.Call("swap", 1, 2)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{2, 1}, res)
+
+	for x, exp := range []uint32{10, 11, math.MaxUint32, math.MaxUint32} {
+		res, err = inst.Call("switch", uint64(x))
+		require.NoError(tb, err)
+		assert.Equal(tb, []uint64{uint64(exp)}, res, "switch %d", x)
+	}
+
+	res, err = inst.Call("select", 0)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{2}, res)
+
+	res, err = inst.Call("count", 5)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{5}, res)
+
+	_, err = inst.Call("fac")
+	assert.Error(tb, err)
+
+	_, err = inst.Call("none")
+	assert.Error(tb, err)
+}
+
+func TestNumeric(tb *testing.T) {
+	f32 := func(f float32) uint64 { return uint64(math.Float32bits(f)) }
+	f64 := math.Float64bits
+
+	for _, tc := range []struct {
+		op   string
+		tp   string
+		args []uint64
+		exp  uint64
+		err  error
+	}{
+		{"i32.add", "(param i32 i32) (result i32)", []uint64{math.MaxUint32, 2}, 1, nil},
+		{"i32.div_s", "(param i32 i32) (result i32)", []uint64{uint64(uint32(0xfffffff9)), 2}, uint64(uint32(0xfffffffd)), nil},
+		{"i32.div_s", "(param i32 i32) (result i32)", []uint64{1 << 31, math.MaxUint32}, 0, ErrIntegerOverflow},
+		{"i32.rem_s", "(param i32 i32) (result i32)", []uint64{1 << 31, math.MaxUint32}, 0, nil},
+		{"i32.div_u", "(param i32 i32) (result i32)", []uint64{1, 0}, 0, ErrIntegerDivideByZero},
+		{"i32.shr_s", "(param i32 i32) (result i32)", []uint64{1 << 31, 33}, 0xc0000000, nil},
+		{"i32.rotr", "(param i32 i32) (result i32)", []uint64{1, 1}, 1 << 31, nil},
+		{"i32.clz", "(param i32) (result i32)", []uint64{1}, 31, nil},
+		{"i64.extend_i32_s", "(param i32) (result i64)", []uint64{math.MaxUint32}, math.MaxUint64, nil},
+		{"i32.extend8_s", "(param i32) (result i32)", []uint64{0x80}, 0xffffff80, nil},
+		{"i32.wrap_i64", "(param i64) (result i32)", []uint64{1<<32 | 5}, 5, nil},
+		{"i64.rem_u", "(param i64 i64) (result i64)", []uint64{10, 3}, 1, nil},
+		{"f32.add", "(param f32 f32) (result f32)", []uint64{f32(1.5), f32(2)}, f32(3.5), nil},
+		{"f32.nearest", "(param f32) (result f32)", []uint64{f32(2.5)}, f32(2), nil},
+		{"f64.min", "(param f64 f64) (result f64)", []uint64{f64(0), f64(math.Copysign(0, -1))}, f64(math.Copysign(0, -1)), nil},
+		{"f32.max", "(param f32 f32) (result f32)", []uint64{f32(float32(math.Inf(1))), f32(float32(math.NaN()))}, f32(float32(math.NaN())), nil},
+		{"f64.copysign", "(param f64 f64) (result f64)", []uint64{f64(2), f64(-1)}, f64(-2), nil},
+		{"f64.convert_i64_u", "(param i64) (result f64)", []uint64{1 << 63}, f64(1 << 63), nil},
+		{"i32.trunc_f32_s", "(param f32) (result i32)", []uint64{f32(-3.9)}, uint64(uint32(0xfffffffd)), nil},
+		{"i32.trunc_f64_u", "(param f64) (result i32)", []uint64{f64(-1)}, 0, ErrIntegerOverflow},
+		{"i64.trunc_f64_s", "(param f64) (result i64)", []uint64{f64(math.NaN())}, 0, ErrInvalidConversion},
+		{"i32.trunc_sat_f64_s", "(param f64) (result i32)", []uint64{f64(1e10)}, math.MaxInt32, nil},
+		{"i64.trunc_sat_f32_u", "(param f32) (result i64)", []uint64{f32(-5)}, 0, nil},
+		{"f32.demote_f64", "(param f64) (result f32)", []uint64{f64(0.1)}, f32(0.1), nil},
+		{"i64.reinterpret_f64", "(param f64) (result i64)", []uint64{f64(1)}, 0x3ff0000000000000, nil},
+	} {
+		args := ""
+		for j := range tc.args {
+			args += " (local.get " + string(rune('0'+j)) + ")"
+		}
+
+		inst := instantiate(tb, `(module (func (export "f") `+tc.tp+` (`+tc.op+args+`)))`)
+
+		res, err := inst.Call("f", tc.args...)
+		if tc.err != nil {
+			assert.ErrorIs(tb, err, tc.err, "%v %x", tc.op, tc.args)
+			continue
+		}
+
+		if assert.NoError(tb, err, "%v %x", tc.op, tc.args) {
+			assert.Equal(tb, []uint64{tc.exp}, res, "%v %x", tc.op, tc.args)
+		}
+	}
+}
+
+func TestInstantiate(tb *testing.T) {
+	inst := instantiate(tb, `(module
+  (memory (export "mem") 1 2)
+  (table (export "tab") 4 funcref)
+  (global $g (export "g") (mut i32) (i32.const 1))
+  (global $base (export "base") i32 (i32.const 8))
+  (data (global.get $base) "hello")
+  (data $passive "world")
+  (elem (i32.const 1) $get $double)
+  (func $get (result i32) (global.get $g))
+  (func $double (result i32) (i32.mul (global.get $g) (i32.const 2)))
+  (func $start (global.set $g (i32.const 21)))
+  (func (export "indirect") (param i32) (result i32)
+    (call_indirect (result i32) (local.get 0)))
+  (func (export "grow") (param i32) (result i32)
+    (memory.grow (local.get 0)))
+  (func (export "size") (result i32)
+    (memory.size))
+  (func (export "load") (param i32) (result i64)
+    (i64.load8_s offset=1 (local.get 0)))
+  (func (export "init") (param i32)
+    (memory.init $passive (local.get 0) (i32.const 0) (i32.const 5))
+    (data.drop $passive))
+  (func (export "fill") (param i32 i32 i32)
+    (memory.fill (local.get 0) (local.get 1) (local.get 2)))
+  (start $start))
+`)
+
+	mem := inst.Memory("mem")
+	require.NotNil(tb, mem)
+	assert.Equal(tb, "hello", string(mem.Bytes()[8:13]))
+
+	assert.Equal(tb, uint64(21), inst.Global("g").Get())
+	assert.Error(tb, inst.Global("base").Set(1))
+
+	res, err := inst.Call("indirect", 2)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{42}, res)
+
+	_, err = inst.Call("indirect", 0)
+	assert.ErrorIs(tb, err, ErrUninitializedElement)
+
+	_, err = inst.Call("indirect", 4)
+	assert.ErrorIs(tb, err, ErrUndefinedElement)
+
+	res, err = inst.Call("grow", 1)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{1}, res)
+
+	res, err = inst.Call("grow", 1)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{math.MaxUint32}, res)
+
+	res, err = inst.Call("size")
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{2}, res)
+
+	mem.Bytes()[PageSize+1] = 0x80
+
+	res, err = inst.Call("load", PageSize)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{0xffffffffffffff80}, res)
+
+	_, err = inst.Call("load", 2*PageSize-1)
+	assert.ErrorIs(tb, err, ErrMemoryOutOfBounds)
+
+	_, err = inst.Call("init", 100)
+	require.NoError(tb, err)
+	assert.Equal(tb, "world", string(mem.Bytes()[100:105]))
+
+	_, err = inst.Call("init", 100)
+	assert.ErrorIs(tb, err, ErrMemoryOutOfBounds)
+
+	_, err = inst.Call("fill", 200, 'x', 3)
+	require.NoError(tb, err)
+	assert.Equal(tb, "xxx", string(mem.Bytes()[200:203]))
+
+	tab := inst.Table("tab")
+	require.NotNil(tb, tab)
+	assert.Equal(tb, 4, tab.Size())
+	assert.Equal(tb, 4, tab.Grow(1, 0))
+}
+
+func TestTrap(tb *testing.T) {
+	inst := instantiate(tb, `(module
+  (table 1 funcref)
+  (elem (i32.const 0) $f)
+  (func $f (param i32) (result i32)
+    (if (local.get 0) (then (unreachable)))
+    (i32.const 0))
+  (func (export "call") (param i32) (result i32)
+    (call $f (local.get 0)))
+  (func (export "indirect") (result i32)
+    (call_indirect (result i32) (i32.const 0)))
+  (func $rec (export "rec")
+    (call $rec)))
+`)
+
+	_, err := inst.Call("call", 0)
+	require.NoError(tb, err)
+
+	_, err = inst.Call("call", 1)
+
+	var trap Trap
+	require.True(tb, errors.As(err, &trap), "error: %v", err)
+
+	assert.ErrorIs(tb, err, ErrUnreachable)
+	assert.Equal(tb, wasm.Index(0), trap.Func)
+	assert.Equal(tb, 5, trap.Offset)
+	assert.Equal(tb, "trap: func 0: offset 0x5: unreachable", err.Error())
+
+	_, err = inst.Call("indirect")
+	assert.ErrorIs(tb, err, ErrIndirectCallTypeMismatch)
+
+	_, err = inst.Call("rec")
+	assert.ErrorIs(tb, err, ErrCallStackExhausted)
+}
+
+func TestExec(tb *testing.T) {
+	inst := instantiate(tb, `(module
+  (memory 0)
+  (func (export "loop") (param i32)
+    (loop $l
+      (br_if $l (local.tee 0 (i32.sub (local.get 0) (i32.const 1))))))
+  (func (export "grow") (param i32) (result i32)
+    (memory.grow (local.get 0)))
+  (func (export "forever")
+    (loop $l (br $l))))
+`)
+
+	costs := &Costs{Block: 10, MemoryGrow: 100}
+
+	for i := range costs.Op {
+		costs.Op[i] = 1
+	}
+
+	e := Exec{Costs: costs, Fuel: 1000}
+
+	// loop and block entry, 3 iterations of 5 instructions, 2 back-edges, 2 ends
+	_, err := e.Call(inst.Func("loop"), 3)
+	require.NoError(tb, err)
+	assert.Equal(tb, int64(1000-48), e.Fuel)
+
+	e.Fuel = 47
+
+	_, err = e.Call(inst.Func("loop"), 3)
+	assert.ErrorIs(tb, err, ErrFuelExhausted)
+	assert.Equal(tb, int64(0), e.Fuel)
+
+	var trap Trap
+	assert.True(tb, errors.As(err, &trap), "error: %v", err)
+
+	e = Exec{Costs: &Costs{MemoryGrow: 100}, Fuel: 150}
+
+	res, err := e.Call(inst.Func("grow"), 1)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{0}, res)
+	assert.Equal(tb, int64(50), e.Fuel)
+
+	_, err = e.Call(inst.Func("grow"), 1<<31)
+	assert.ErrorIs(tb, err, ErrFuelExhausted)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	e = Exec{Context: ctx}
+
+	_, err = e.Call(inst.Func("forever"))
+	assert.ErrorIs(tb, err, context.DeadlineExceeded)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	e = Exec{Context: ctx}
+
+	_, err = e.Call(inst.Func("loop"), 3)
+	assert.ErrorIs(tb, err, context.Canceled)
+
+	// start function
+	var r Runtime
+
+	e = Exec{Costs: costs, Fuel: 1000}
+
+	_, err = r.InstantiateWith(parseModule(tb, `(module (func $start (loop $l (br $l))) (start $start))`), Options{Exec: &e})
+	assert.ErrorIs(tb, err, ErrFuelExhausted)
+	assert.Equal(tb, int64(0), e.Fuel)
+
+	// host callback shares the fuel
+	var l Linker
+
+	l.DefineFunc("env", "cb", wasm.FuncType{Params: wasm.ResultType{wasm.I32}, Result: wasm.ResultType{wasm.I32}}, func(c *Caller, st []uint64) error {
+		_, err := c.Call(c.Instance().Func("loop"), st[0])
+		st[0] = 7
+
+		return err
+	})
+
+	inst, err = l.Instantiate(&r, parseModule(tb, `(module
+  (import "env" "cb" (func $cb (param i32) (result i32)))
+  (func (export "loop") (param i32)
+    (loop $l
+      (br_if $l (local.tee 0 (i32.sub (local.get 0) (i32.const 1))))))
+  (func (export "run") (param i32) (result i32)
+    (call $cb (local.get 0))))
+`))
+	require.NoError(tb, err)
+
+	e = Exec{Costs: costs, Fuel: 1000}
+
+	res, err = e.Call(inst.Func("run"), 3)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{7}, res)
+	assert.Equal(tb, int64(1000-48-3), e.Fuel)
+
+	e.Fuel = 50
+
+	_, err = e.Call(inst.Func("run"), 3)
+	assert.ErrorIs(tb, err, ErrFuelExhausted)
+}
+
+func TestLimits(tb *testing.T) {
+	var r Runtime
+
+	opts := Options{Limits: Limits{MemoryPages: 2, TableSize: 3, CallDepth: 10, StackSize: 100}}
+
+	inst, err := r.InstantiateWith(parseModule(tb, `(module
+  (memory 1 10)
+  (table 1 funcref)
+  (func (export "grow") (param i32) (result i32)
+    (memory.grow (local.get 0)))
+  (func (export "table.grow") (param i32) (result i32)
+    (table.grow (ref.null func) (local.get 0)))
+  (func $rec (export "rec") (param i32)
+    (if (local.get 0) (then (call $rec (i32.sub (local.get 0) (i32.const 1))))))
+  (func $wide (export "wide") (param i32) (local i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64 i64)
+    (if (local.get 0) (then (call $wide (i32.sub (local.get 0) (i32.const 1)))))))
+`), opts)
+	require.NoError(tb, err)
+
+	for _, tc := range []struct {
+		name string
+		arg  uint64
+		res  []uint64
+		err  error
+	}{
+		{name: "grow", arg: 1, res: []uint64{1}},
+		{name: "grow", arg: 1, res: []uint64{math.MaxUint32}},
+		{name: "table.grow", arg: 2, res: []uint64{1}},
+		{name: "table.grow", arg: 1, res: []uint64{math.MaxUint32}},
+		{name: "rec", arg: 9, res: []uint64{}},
+		{name: "rec", arg: 10, err: ErrCallStackExhausted},
+		{name: "wide", arg: 3, res: []uint64{}},
+		{name: "wide", arg: 4, err: ErrValueStackExhausted},
+	} {
+		res, err := inst.Call(tc.name, tc.arg)
+		if tc.err != nil {
+			assert.ErrorIs(tb, err, tc.err, "%v(%v)", tc.name, tc.arg)
+
+			var trap Trap
+			assert.True(tb, errors.As(err, &trap), "error: %v", err)
+
+			continue
+		}
+
+		if assert.NoError(tb, err, "%v(%v)", tc.name, tc.arg) {
+			assert.Equal(tb, tc.res, res, "%v(%v)", tc.name, tc.arg)
+		}
+	}
+
+	_, err = r.InstantiateWith(parseModule(tb, `(module (memory 3))`), opts)
+	assert.EqualError(tb, err, "memory 0: minimum 3 is over the limit 2")
+
+	_, err = r.InstantiateWith(parseModule(tb, `(module (table 4 funcref))`), opts)
+	assert.EqualError(tb, err, "table 0: minimum 4 is over the limit 3")
+
+	// limits are per instantiation
+	_, err = r.Instantiate(parseModule(tb, `(module (memory 3))`))
+	assert.NoError(tb, err)
+
+	// default memory limit
+	_, err = r.Instantiate(parseModule(tb, `(module (memory 65536))`))
+	assert.EqualError(tb, err, "memory 0: minimum 65536 is over the limit 4096")
+
+	// imported memories and tables
+	var l Linker
+
+	mem := NewMemory(wasm.Limits{Lo: 1, Hi: -1})
+	tab := NewTable(wasm.Table{Type: wasm.FuncRef, Limits: wasm.Limits{Lo: 1, Hi: -1}})
+
+	l.Define("env", "mem", mem)
+	l.Define("env", "tab", tab)
+
+	inst, err = l.InstantiateWith(&r, parseModule(tb, `(module
+  (import "env" "mem" (memory 1))
+  (import "env" "tab" (table 1 funcref))
+  (func (export "grow") (param i32) (result i32)
+    (memory.grow (local.get 0)))
+  (func (export "table.grow") (param i32) (result i32)
+    (table.grow (ref.null func) (local.get 0))))
+`), opts)
+	require.NoError(tb, err)
+
+	for _, name := range []string{"grow", "table.grow"} {
+		res, err := inst.Call(name, 5)
+		require.NoError(tb, err)
+		assert.Equal(tb, []uint64{math.MaxUint32}, res, name)
+	}
+
+	res, err := inst.Call("grow", 1)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{1}, res)
+
+	mem.Grow(1)
+	tab.Grow(3, 0)
+
+	_, err = l.InstantiateWith(&r, parseModule(tb, `(module (import "env" "mem" (memory 1)))`), opts)
+	assert.EqualError(tb, err, "memory 0: size 3 is over the limit 2")
+
+	_, err = l.InstantiateWith(&r, parseModule(tb, `(module (import "env" "tab" (table 1 funcref)))`), opts)
+	assert.EqualError(tb, err, "table 0: size 4 is over the limit 3")
+}
+
+func TestInstantiateErrors(tb *testing.T) {
+	var r Runtime
+
+	_, err := r.Instantiate(parseModule(tb, `(module (import "env" "f" (func)))`))
+	assert.ErrorContains(tb, err, `unresolved import "env"."f"`)
+
+	_, err = r.Instantiate(parseModule(tb, `(module (memory 1) (data (i32.const 65535) "ab"))`))
+	assert.ErrorIs(tb, err, ErrMemoryOutOfBounds)
+
+	_, err = r.Instantiate(parseModule(tb, `(module (func (result i32) (i64.const 0)))`))
+	assert.ErrorContains(tb, err, "validate")
+	// failed instantiation doesn't leave functions in the runtime
+	var l Linker
+
+	tab := NewTable(wasm.Table{Type: wasm.FuncRef, Limits: wasm.Limits{Lo: 2, Hi: -1}})
+	l.Define("env", "tab", tab)
+
+	funcs := len(r.funcs)
+
+	for _, text := range []string{
+		`(module (func) (memory 1) (data (i32.const 65535) "ab"))`,
+		`(module (import "env" "tab" (table 2 funcref)) (func $f) (elem (i32.const 0) $f) (func $start unreachable) (start $start))`,
+	} {
+		_, err = l.Instantiate(&r, parseModule(tb, text))
+		assert.Error(tb, err, text)
+		assert.Len(tb, r.funcs, funcs, text)
+	}
+
+	ref, err := tab.Get(0)
+	require.NoError(tb, err)
+	assert.Zero(tb, ref)
+}
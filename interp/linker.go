@@ -0,0 +1,174 @@
+package interp
+
+import (
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// Linker resolves module imports by module and name.
+	// Imported module functions must belong to the same Runtime the module is instantiated in.
+	// Host functions are bound to each Runtime they are imported in.
+	// Zero value is ready to use.
+	Linker struct {
+		defs map[importKey]Extern
+	}
+
+	importKey struct {
+		module, name string
+	}
+)
+
+// Define defines an import, it replaces the previous definition.
+func (l *Linker) Define(module, name string, ext Extern) {
+	if l.defs == nil {
+		l.defs = make(map[importKey]Extern)
+	}
+
+	l.defs[importKey{module: module, name: name}] = ext
+}
+
+// DefineFunc defines a host function import.
+func (l *Linker) DefineFunc(module, name string, tp wasm.FuncType, fn HostFunc) {
+	l.Define(module, name, NewFunction(tp, fn))
+}
+
+// DefineInstance defines all the instance exports under the module name.
+func (l *Linker) DefineInstance(module string, inst *Instance) {
+	for _, e := range inst.m.Export {
+		name := string(e.Name)
+
+		l.Define(module, name, inst.Export(name))
+	}
+}
+
+// Instantiate validates the module, resolves its imports and instantiates it in r.
+// See Runtime.Instantiate for details.
+func (l *Linker) Instantiate(r *Runtime, m *wasm.Module) (*Instance, error) {
+	return l.InstantiateWith(r, m, Options{})
+}
+
+// InstantiateWith is the same as Instantiate but takes options.
+func (l *Linker) InstantiateWith(r *Runtime, m *wasm.Module, opts Options) (*Instance, error) {
+	err := r.v.Module(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "validate")
+	}
+
+	imports := make([]Extern, 0, len(m.Import))
+
+	for _, im := range m.Import {
+		ext, err := l.resolve(r, m, im)
+		if err != nil {
+			return nil, errors.Wrap(err, "import %q.%q", im.Module, im.Name)
+		}
+
+		imports = append(imports, ext)
+	}
+
+	return r.instantiate(m, imports, opts)
+}
+
+func (l *Linker) resolve(r *Runtime, m *wasm.Module, im wasm.Import) (Extern, error) {
+	ext, ok := l.defs[importKey{module: string(im.Module), name: string(im.Name)}]
+	if !ok || ext == nil {
+		return nil, errors.New("not defined")
+	}
+
+	if kind := ext.externKind(); kind != im.Kind() {
+		return nil, errors.New("expected %v, got %v", externName(im.Kind()), externName(kind))
+	}
+
+	switch ext := ext.(type) {
+	case *Function:
+		tp := m.Type[im.FuncType()]
+
+		if !equalTypes(ext.Type, tp) {
+			return nil, errors.New("function type mismatch: expected %v -> %v, got %v -> %v",
+				tp.Params, tp.Result, ext.Type.Params, ext.Type.Result)
+		}
+
+		switch {
+		case ext.r == r:
+		case ext.host != nil:
+			return r.bindHost(ext), nil
+		default:
+			return nil, errors.New("function belongs to another runtime")
+		}
+	case *Table:
+		tp := im.Table()
+
+		if ext.Type != tp.Type {
+			return nil, errors.New("table type mismatch: expected %v, got %v", tp.Type, ext.Type)
+		}
+
+		err := matchLimits(wasm.Limits{Lo: ext.Size(), Hi: ext.max}, tp.Limits)
+		if err != nil {
+			return nil, err
+		}
+	case *Memory:
+		lim := im.Memory()
+
+		if lim.Shared {
+			return nil, errors.New("shared memory is not supported")
+		}
+
+		err := matchLimits(wasm.Limits{Lo: ext.Size(), Hi: ext.max}, lim)
+		if err != nil {
+			return nil, err
+		}
+	case *Global:
+		g := im.Global()
+
+		if ext.Type != g.Type || ext.Mut != (g.Mut != 0) {
+			return nil, errors.New("global type mismatch: expected %v, got %v", globalType(g.Type, g.Mut != 0), globalType(ext.Type, ext.Mut))
+		}
+	}
+
+	return ext, nil
+}
+
+// matchLimits checks that have limits are within want limits.
+// Hi is -1 if there is no maximum.
+func matchLimits(have, want wasm.Limits) error {
+	if have.Lo < want.Lo {
+		return errors.New("limits mismatch: minimum %d is less than %d", have.Lo, want.Lo)
+	}
+
+	if want.Hi >= 0 && (have.Hi < 0 || have.Hi > want.Hi) {
+		return errors.New("limits mismatch: maximum %v is more than %d", limitMax(have.Hi), want.Hi)
+	}
+
+	return nil
+}
+
+func limitMax(hi int) interface{} {
+	if hi < 0 {
+		return "unlimited"
+	}
+
+	return hi
+}
+
+func globalType(tp wasm.Type, mut bool) string {
+	if mut {
+		return "mut " + tp.String()
+	}
+
+	return tp.String()
+}
+
+func externName(kind byte) string {
+	switch kind {
+	case wasm.ExternFunc:
+		return "func"
+	case wasm.ExternTable:
+		return "table"
+	case wasm.ExternMemory:
+		return "memory"
+	case wasm.ExternGlobal:
+		return "global"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,132 @@
+package interp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+)
+
+func TestLinker(tb *testing.T) {
+	var r Runtime
+	var l Linker
+	var logs []string
+
+	errStop := errors.New("stop")
+
+	l.DefineFunc("env", "log", wasm.FuncType{Params: wasm.ResultType{wasm.I32, wasm.I32}}, func(c *Caller, st []uint64) error {
+		mem := c.Memory()
+		logs = append(logs, string(mem.Bytes()[st[0]:st[0]+st[1]]))
+
+		return nil
+	})
+
+	l.DefineFunc("env", "add", wasm.FuncType{Params: wasm.ResultType{wasm.I64, wasm.I64}, Result: wasm.ResultType{wasm.I64}}, func(c *Caller, st []uint64) error {
+		st[0] = st[0] + st[1]
+
+		return nil
+	})
+
+	l.DefineFunc("env", "stop", wasm.FuncType{}, func(c *Caller, st []uint64) error {
+		return errStop
+	})
+
+	l.Define("env", "memory", NewMemory(wasm.Limits{Lo: 1, Hi: 2}))
+	l.Define("env", "base", NewGlobal(wasm.I32, false, 16))
+
+	lib, err := l.Instantiate(&r, parseModule(tb, `(module
+  (import "env" "memory" (memory 1))
+  (import "env" "log" (func $log (param i32 i32)))
+  (import "env" "base" (global $base i32))
+  (data (global.get $base) "hello")
+  (func (export "hello")
+    (call $log (global.get $base) (i32.const 5))))
+`))
+	require.NoError(tb, err)
+
+	l.DefineInstance("lib", lib)
+
+	inst, err := l.Instantiate(&r, parseModule(tb, `(module
+  (import "lib" "hello" (func $hello))
+  (import "env" "add" (func $add (param i64 i64) (result i64)))
+  (import "env" "stop" (func $stop))
+  (table 2 funcref)
+  (elem (i32.const 0) $hello $add)
+  (func (export "run") (param i64) (result i64)
+    (call $hello)
+    (call_indirect (i32.const 0))
+    (call_indirect (param i64 i64) (result i64) (local.get 0) (i64.const 1) (i32.const 1)))
+  (func (export "stop")
+    (call $stop)))
+`))
+	require.NoError(tb, err)
+
+	res, err := inst.Call("run", 41)
+	require.NoError(tb, err)
+	assert.Equal(tb, []uint64{42}, res)
+	assert.Equal(tb, []string{"hello", "hello"}, logs)
+
+	res, err = lib.Call("hello")
+	require.NoError(tb, err)
+	assert.Empty(tb, res)
+
+	_, err = inst.Call("stop")
+	assert.ErrorIs(tb, err, errStop)
+
+	var trap Trap
+	if assert.True(tb, errors.As(err, &trap)) {
+		assert.Equal(tb, wasm.Index(4), trap.Func)
+	}
+}
+
+func TestLinkerErrors(tb *testing.T) {
+	var r, r2 Runtime
+	var l Linker
+
+	l.DefineFunc("env", "f", wasm.FuncType{Params: wasm.ResultType{wasm.I32}}, func(c *Caller, st []uint64) error { return nil })
+	l.Define("env", "mem", NewMemory(wasm.Limits{Lo: 1, Hi: -1}))
+	l.Define("env", "tab", NewTable(wasm.Table{Type: wasm.FuncRef, Limits: wasm.Limits{Lo: 1, Hi: 1}}))
+	l.Define("env", "g", NewGlobal(wasm.I32, true, 0))
+
+	for _, tc := range []struct {
+		text string
+		err  string
+	}{
+		{`(import "env" "none" (func))`, `import "env"."none": not defined`},
+		{`(import "env" "f" (func (param i64)))`, "function type mismatch: expected [i64] -> [], got [i32] -> []"},
+		{`(import "env" "f" (memory 1))`, "expected memory, got func"},
+		{`(import "env" "mem" (memory 2))`, "minimum 1 is less than 2"},
+		{`(import "env" "mem" (memory 1 4))`, "maximum unlimited is more than 4"},
+		{`(import "env" "tab" (table 1 externref))`, "table type mismatch: expected externref, got funcref"},
+		{`(import "env" "g" (global i32))`, "global type mismatch: expected i32, got mut i32"},
+	} {
+		_, err := l.Instantiate(&r, parseModule(tb, `(module `+tc.text+`)`))
+		assert.ErrorContains(tb, err, tc.err, "%s", tc.text)
+	}
+
+	_, err := l.Instantiate(&r, parseModule(tb, `(module (import "env" "tab" (table 1 2 funcref)) (import "env" "f" (func (param i32))))`))
+	assert.NoError(tb, err)
+
+	lib, err := l.Instantiate(&r, parseModule(tb, `(module (import "env" "f" (func (param i32))) (export "f" (func 0)) (func (export "g")))`))
+	require.NoError(tb, err)
+
+	// host functions are bound to each runtime
+	inst, err := l.Instantiate(&r2, parseModule(tb, `(module (import "env" "f" (func (param i32))) (export "f" (func 0)))`))
+	require.NoError(tb, err)
+
+	assert.Same(tb, lib.Func("f"), r.funcRef(lib.Func("f").ref()))
+	assert.Same(tb, inst.Func("f"), r2.funcRef(inst.Func("f").ref()))
+	assert.NotSame(tb, lib.Func("f"), inst.Func("f"))
+
+	inst2, err := l.Instantiate(&r2, parseModule(tb, `(module (import "env" "f" (func (param i32))) (export "f" (func 0)))`))
+	require.NoError(tb, err)
+	assert.Same(tb, inst.Func("f"), inst2.Func("f"))
+
+	l.DefineInstance("lib", lib)
+
+	_, err = l.Instantiate(&r2, parseModule(tb, `(module (import "lib" "g" (func)))`))
+	assert.ErrorContains(tb, err, "another runtime")
+}
@@ -0,0 +1,499 @@
+package interp
+
+import (
+	"math"
+	"math/bits"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+// isBinop reports whether numeric instruction takes two operands.
+func isBinop(op uint16) bool {
+	switch {
+	case op >= wasm.I32Eq && op <= wasm.I32GeU,
+		op >= wasm.I64Eq && op <= wasm.I64GeU,
+		op >= wasm.F32Eq && op <= wasm.F64Ge,
+		op >= wasm.I32Add && op <= wasm.I32RotR,
+		op >= wasm.I64Add && op <= wasm.I64RotR,
+		op >= wasm.F32Add && op <= wasm.F32CopySign,
+		op >= wasm.F64Add && op <= wasm.F64CopySign:
+		return true
+	default:
+		return false
+	}
+}
+
+// unop executes test, unary and conversion instructions.
+func unop(op uint16, x uint64) (uint64, error) {
+	switch op {
+	case wasm.I32EqZ:
+		return b2u(uint32(x) == 0), nil
+	case wasm.I64EqZ:
+		return b2u(x == 0), nil
+
+	case wasm.I32Clz:
+		return uint64(bits.LeadingZeros32(uint32(x))), nil
+	case wasm.I32Ctz:
+		return uint64(bits.TrailingZeros32(uint32(x))), nil
+	case wasm.I32Popcnt:
+		return uint64(bits.OnesCount32(uint32(x))), nil
+	case wasm.I64Clz:
+		return uint64(bits.LeadingZeros64(x)), nil
+	case wasm.I64Ctz:
+		return uint64(bits.TrailingZeros64(x)), nil
+	case wasm.I64Popcnt:
+		return uint64(bits.OnesCount64(x)), nil
+
+	case wasm.F32Abs:
+		return x &^ (1 << 31), nil
+	case wasm.F32Neg:
+		return x ^ (1 << 31), nil
+	case wasm.F32Ceil:
+		return f32u(math.Ceil(u2f32(x))), nil
+	case wasm.F32Floor:
+		return f32u(math.Floor(u2f32(x))), nil
+	case wasm.F32Trunc:
+		return f32u(math.Trunc(u2f32(x))), nil
+	case wasm.F32Near:
+		return f32u(math.RoundToEven(u2f32(x))), nil
+	case wasm.F32Sqrt:
+		return f32u(math.Sqrt(u2f32(x))), nil
+
+	case wasm.F64Abs:
+		return x &^ (1 << 63), nil
+	case wasm.F64Neg:
+		return x ^ (1 << 63), nil
+	case wasm.F64Ceil:
+		return f64u(math.Ceil(u2f64(x))), nil
+	case wasm.F64Floor:
+		return f64u(math.Floor(u2f64(x))), nil
+	case wasm.F64Trunc:
+		return f64u(math.Trunc(u2f64(x))), nil
+	case wasm.F64Near:
+		return f64u(math.RoundToEven(u2f64(x))), nil
+	case wasm.F64Sqrt:
+		return f64u(math.Sqrt(u2f64(x))), nil
+
+	case wasm.I32WrapI64:
+		return uint64(uint32(x)), nil
+	case wasm.I32TruncF32S:
+		return truncS32(u2f32(x))
+	case wasm.I32TruncF32U:
+		return truncU32(u2f32(x))
+	case wasm.I32TruncF64S:
+		return truncS32(u2f64(x))
+	case wasm.I32TruncF64U:
+		return truncU32(u2f64(x))
+	case wasm.I64ExtendI32S:
+		return uint64(int32(x)), nil
+	case wasm.I64ExtendI32U:
+		return uint64(uint32(x)), nil
+	case wasm.I64TruncF32S:
+		return truncS64(u2f32(x))
+	case wasm.I64TruncF32U:
+		return truncU64(u2f32(x))
+	case wasm.I64TruncF64S:
+		return truncS64(u2f64(x))
+	case wasm.I64TruncF64U:
+		return truncU64(u2f64(x))
+	case wasm.F32ConvertI32S:
+		return uint64(math.Float32bits(float32(int32(x)))), nil
+	case wasm.F32ConvertI32U:
+		return uint64(math.Float32bits(float32(uint32(x)))), nil
+	case wasm.F32ConvertI64S:
+		return uint64(math.Float32bits(float32(int64(x)))), nil
+	case wasm.F32ConvertI64U:
+		return uint64(math.Float32bits(float32(x))), nil
+	case wasm.F32DemoteF64:
+		return f32u(u2f64(x)), nil
+	case wasm.F64ConvertI32S:
+		return f64u(float64(int32(x))), nil
+	case wasm.F64ConvertI32U:
+		return f64u(float64(uint32(x))), nil
+	case wasm.F64ConvertI64S:
+		return f64u(float64(int64(x))), nil
+	case wasm.F64ConvertI64U:
+		return f64u(float64(x)), nil
+	case wasm.F64PromoteF32:
+		return f64u(u2f32(x)), nil
+
+	case wasm.I32ReinterpretF32, wasm.F32ReinterpretI32:
+		return uint64(uint32(x)), nil
+	case wasm.I64ReinterpretF64, wasm.F64ReinterpretI64:
+		return x, nil
+
+	case wasm.I32Extend8S:
+		return uint64(uint32(int8(x))), nil
+	case wasm.I32Extend16S:
+		return uint64(uint32(int16(x))), nil
+	case wasm.I64Extend8S:
+		return uint64(int8(x)), nil
+	case wasm.I64Extend16S:
+		return uint64(int16(x)), nil
+	case wasm.I64Extend32S:
+		return uint64(int32(x)), nil
+
+	case fcOp | wasm.FCI32TruncSatF32S:
+		return satS32(u2f32(x)), nil
+	case fcOp | wasm.FCI32TruncSatF32U:
+		return satU32(u2f32(x)), nil
+	case fcOp | wasm.FCI32TruncSatF64S:
+		return satS32(u2f64(x)), nil
+	case fcOp | wasm.FCI32TruncSatF64U:
+		return satU32(u2f64(x)), nil
+	case fcOp | wasm.FCI64TruncSatF32S:
+		return satS64(u2f32(x)), nil
+	case fcOp | wasm.FCI64TruncSatF32U:
+		return satU64(u2f32(x)), nil
+	case fcOp | wasm.FCI64TruncSatF64S:
+		return satS64(u2f64(x)), nil
+	case fcOp | wasm.FCI64TruncSatF64U:
+		return satU64(u2f64(x)), nil
+	}
+
+	return 0, errors.New("unsupported instruction: %x", op)
+}
+
+// binop executes comparison and binary instructions.
+func binop(op uint16, x, y uint64) (uint64, error) {
+	switch op {
+	case wasm.I32Eq:
+		return b2u(uint32(x) == uint32(y)), nil
+	case wasm.I32Ne:
+		return b2u(uint32(x) != uint32(y)), nil
+	case wasm.I32LtS:
+		return b2u(int32(x) < int32(y)), nil
+	case wasm.I32LtU:
+		return b2u(uint32(x) < uint32(y)), nil
+	case wasm.I32GtS:
+		return b2u(int32(x) > int32(y)), nil
+	case wasm.I32GtU:
+		return b2u(uint32(x) > uint32(y)), nil
+	case wasm.I32LeS:
+		return b2u(int32(x) <= int32(y)), nil
+	case wasm.I32LeU:
+		return b2u(uint32(x) <= uint32(y)), nil
+	case wasm.I32GeS:
+		return b2u(int32(x) >= int32(y)), nil
+	case wasm.I32GeU:
+		return b2u(uint32(x) >= uint32(y)), nil
+
+	case wasm.I64Eq:
+		return b2u(x == y), nil
+	case wasm.I64Ne:
+		return b2u(x != y), nil
+	case wasm.I64LtS:
+		return b2u(int64(x) < int64(y)), nil
+	case wasm.I64LtU:
+		return b2u(x < y), nil
+	case wasm.I64GtS:
+		return b2u(int64(x) > int64(y)), nil
+	case wasm.I64GtU:
+		return b2u(x > y), nil
+	case wasm.I64LeS:
+		return b2u(int64(x) <= int64(y)), nil
+	case wasm.I64LeU:
+		return b2u(x <= y), nil
+	case wasm.I64GeS:
+		return b2u(int64(x) >= int64(y)), nil
+	case wasm.I64GeU:
+		return b2u(x >= y), nil
+
+	case wasm.F32Eq:
+		return b2u(u2f32(x) == u2f32(y)), nil
+	case wasm.F32Ne:
+		return b2u(u2f32(x) != u2f32(y)), nil
+	case wasm.F32Lt:
+		return b2u(u2f32(x) < u2f32(y)), nil
+	case wasm.F32Gt:
+		return b2u(u2f32(x) > u2f32(y)), nil
+	case wasm.F32Le:
+		return b2u(u2f32(x) <= u2f32(y)), nil
+	case wasm.F32Ge:
+		return b2u(u2f32(x) >= u2f32(y)), nil
+
+	case wasm.F64Eq:
+		return b2u(u2f64(x) == u2f64(y)), nil
+	case wasm.F64Ne:
+		return b2u(u2f64(x) != u2f64(y)), nil
+	case wasm.F64Lt:
+		return b2u(u2f64(x) < u2f64(y)), nil
+	case wasm.F64Gt:
+		return b2u(u2f64(x) > u2f64(y)), nil
+	case wasm.F64Le:
+		return b2u(u2f64(x) <= u2f64(y)), nil
+	case wasm.F64Ge:
+		return b2u(u2f64(x) >= u2f64(y)), nil
+
+	case wasm.I32Add:
+		return uint64(uint32(x) + uint32(y)), nil
+	case wasm.I32Sub:
+		return uint64(uint32(x) - uint32(y)), nil
+	case wasm.I32Mul:
+		return uint64(uint32(x) * uint32(y)), nil
+	case wasm.I32DivS:
+		if int32(y) == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		if int32(x) == math.MinInt32 && int32(y) == -1 {
+			return 0, ErrIntegerOverflow
+		}
+
+		return uint64(uint32(int32(x) / int32(y))), nil
+	case wasm.I32DivU:
+		if uint32(y) == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return uint64(uint32(x) / uint32(y)), nil
+	case wasm.I32RemS:
+		if int32(y) == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return uint64(uint32(int32(x) % int32(y))), nil
+	case wasm.I32RemU:
+		if uint32(y) == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return uint64(uint32(x) % uint32(y)), nil
+	case wasm.I32And:
+		return uint64(uint32(x) & uint32(y)), nil
+	case wasm.I32Or:
+		return uint64(uint32(x) | uint32(y)), nil
+	case wasm.I32Xor:
+		return uint64(uint32(x) ^ uint32(y)), nil
+	case wasm.I32Shl:
+		return uint64(uint32(x) << (y & 31)), nil
+	case wasm.I32ShrS:
+		return uint64(uint32(int32(x) >> (y & 31))), nil
+	case wasm.I32ShrU:
+		return uint64(uint32(x) >> (y & 31)), nil
+	case wasm.I32RotL:
+		return uint64(bits.RotateLeft32(uint32(x), int(y&31))), nil
+	case wasm.I32RotR:
+		return uint64(bits.RotateLeft32(uint32(x), -int(y&31))), nil
+
+	case wasm.I64Add:
+		return x + y, nil
+	case wasm.I64Sub:
+		return x - y, nil
+	case wasm.I64Mul:
+		return x * y, nil
+	case wasm.I64DivS:
+		if y == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		if int64(x) == math.MinInt64 && int64(y) == -1 {
+			return 0, ErrIntegerOverflow
+		}
+
+		return uint64(int64(x) / int64(y)), nil
+	case wasm.I64DivU:
+		if y == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return x / y, nil
+	case wasm.I64RemS:
+		if y == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return uint64(int64(x) % int64(y)), nil
+	case wasm.I64RemU:
+		if y == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+
+		return x % y, nil
+	case wasm.I64And:
+		return x & y, nil
+	case wasm.I64Or:
+		return x | y, nil
+	case wasm.I64Xor:
+		return x ^ y, nil
+	case wasm.I64Shl:
+		return x << (y & 63), nil
+	case wasm.I64ShrS:
+		return uint64(int64(x) >> (y & 63)), nil
+	case wasm.I64ShrU:
+		return x >> (y & 63), nil
+	case wasm.I64RotL:
+		return bits.RotateLeft64(x, int(y&63)), nil
+	case wasm.I64RotR:
+		return bits.RotateLeft64(x, -int(y&63)), nil
+
+	case wasm.F32Add:
+		return uint64(math.Float32bits(float32(u2f32(x)) + float32(u2f32(y)))), nil
+	case wasm.F32Sub:
+		return uint64(math.Float32bits(float32(u2f32(x)) - float32(u2f32(y)))), nil
+	case wasm.F32Mul:
+		return uint64(math.Float32bits(float32(u2f32(x)) * float32(u2f32(y)))), nil
+	case wasm.F32Div:
+		return uint64(math.Float32bits(float32(u2f32(x)) / float32(u2f32(y)))), nil
+	case wasm.F32Min:
+		return f32u(fmin(u2f32(x), u2f32(y))), nil
+	case wasm.F32Max:
+		return f32u(fmax(u2f32(x), u2f32(y))), nil
+	case wasm.F32CopySign:
+		return x&^(1<<31) | y&(1<<31), nil
+
+	case wasm.F64Add:
+		return f64u(u2f64(x) + u2f64(y)), nil
+	case wasm.F64Sub:
+		return f64u(u2f64(x) - u2f64(y)), nil
+	case wasm.F64Mul:
+		return f64u(u2f64(x) * u2f64(y)), nil
+	case wasm.F64Div:
+		return f64u(u2f64(x) / u2f64(y)), nil
+	case wasm.F64Min:
+		return f64u(fmin(u2f64(x), u2f64(y))), nil
+	case wasm.F64Max:
+		return f64u(fmax(u2f64(x), u2f64(y))), nil
+	case wasm.F64CopySign:
+		return x&^(1<<63) | y&(1<<63), nil
+	}
+
+	return 0, errors.New("unsupported instruction: %x", op)
+}
+
+func truncS32(f float64) (uint64, error) {
+	if f != f {
+		return 0, ErrInvalidConversion
+	}
+
+	t := math.Trunc(f)
+	if t < math.MinInt32 || t > math.MaxInt32 {
+		return 0, ErrIntegerOverflow
+	}
+
+	return uint64(uint32(int32(t))), nil
+}
+
+func truncU32(f float64) (uint64, error) {
+	if f != f {
+		return 0, ErrInvalidConversion
+	}
+
+	t := math.Trunc(f)
+	if t < 0 || t > math.MaxUint32 {
+		return 0, ErrIntegerOverflow
+	}
+
+	return uint64(uint32(t)), nil
+}
+
+func truncS64(f float64) (uint64, error) {
+	if f != f {
+		return 0, ErrInvalidConversion
+	}
+
+	t := math.Trunc(f)
+	if t < math.MinInt64 || t >= 1<<63 {
+		return 0, ErrIntegerOverflow
+	}
+
+	return uint64(int64(t)), nil
+}
+
+func truncU64(f float64) (uint64, error) {
+	if f != f {
+		return 0, ErrInvalidConversion
+	}
+
+	t := math.Trunc(f)
+	if t < 0 || t >= 1<<64 {
+		return 0, ErrIntegerOverflow
+	}
+
+	return uint64(t), nil
+}
+
+func satS32(f float64) uint64 {
+	switch {
+	case f != f:
+		return 0
+	case f < math.MinInt32:
+		return 1 << 31
+	case f > math.MaxInt32:
+		return math.MaxInt32
+	}
+
+	return uint64(uint32(int32(f)))
+}
+
+func satU32(f float64) uint64 {
+	switch {
+	case f != f, f < 0:
+		return 0
+	case f > math.MaxUint32:
+		return math.MaxUint32
+	}
+
+	return uint64(uint32(f))
+}
+
+func satS64(f float64) uint64 {
+	switch {
+	case f != f:
+		return 0
+	case f < math.MinInt64:
+		return 1 << 63
+	case f >= 1<<63:
+		return math.MaxInt64
+	}
+
+	return uint64(int64(f))
+}
+
+func satU64(f float64) uint64 {
+	switch {
+	case f != f, f < 0:
+		return 0
+	case f >= 1<<64:
+		return math.MaxUint64
+	}
+
+	return uint64(f)
+}
+
+// fmin is math.Min except it returns NaN if any argument is NaN.
+func fmin(x, y float64) float64 {
+	if x != x || y != y {
+		return math.NaN()
+	}
+
+	return math.Min(x, y)
+}
+
+// fmax is math.Max except it returns NaN if any argument is NaN.
+func fmax(x, y float64) float64 {
+	if x != x || y != y {
+		return math.NaN()
+	}
+
+	return math.Max(x, y)
+}
+
+// u2f32 converts f32 value to float64 which is exact.
+func u2f32(x uint64) float64 { return float64(math.Float32frombits(uint32(x))) }
+
+func u2f64(x uint64) float64 { return math.Float64frombits(x) }
+
+// f32u rounds float64 to f32 value.
+func f32u(f float64) uint64 { return uint64(math.Float32bits(float32(f))) }
+
+func f64u(f float64) uint64 { return math.Float64bits(f) }
+
+func b2u(c bool) uint64 {
+	if c {
+		return 1
+	}
+
+	return 0
+}
@@ -1,13 +1,22 @@
 package wasm
 
-import "tlog.app/go/tlog/tlwire"
+import (
+	"fmt"
+
+	"tlog.app/go/tlog/tlwire"
+)
 
 type (
+	// Module is a decoded WebAssembly module.
+	// Zero value is an empty module without Start and DataCount sections.
 	Module struct {
 		Version int
 
-		DataCount int
-		Start     Index
+		DataCount    int
+		HasDataCount bool // DataCount section is present
+
+		Start    Index
+		HasStart bool // Start section is present
 
 		Type     []FuncType
 		Import   []Import
@@ -39,10 +48,10 @@ type (
 	Import struct {
 		Module, Name []byte
 
-		// raw storage for import description
+		// raw storage for import description, use accessors
 		// tp 0 => typeidx at rawi[0]
 		// tp 1 => refype  at rawb[0], lo, hi limits at rawi
-		// tp 2 => memtype at rawi
+		// tp 2 => memtype at rawi, shared at rawb[0]
 		// tp 3 => valtype at rawb[0], mut at rawb[1]
 		tp   byte
 		rawb [2]byte
@@ -63,7 +72,8 @@ type (
 	}
 
 	Limits struct {
-		Lo, Hi int
+		Lo, Hi int // Hi is -1 if there is no maximum
+		Shared bool
 	}
 
 	Global struct {
@@ -73,15 +83,27 @@ type (
 	}
 
 	Element struct {
-		Type Type
-		Expr Code
-
-		Funcs []Index
+		Mode  byte  // ElemActive, ElemPassive or ElemDeclarative
+		Table Index // active segment table
+		Type  Type  // FuncRef or ExternRef, zero means FuncRef
+		Expr  Code  // active segment offset
+
+		Funcs []Index // function indexes
+		Init  []Code  // or init expressions
+
+		// Encoding details set by the Decoder to encode the segment back the same way.
+		// The Encoder picks the shortest form if they are false.
+		ExplicitTable bool // active segment table index is encoded even if it's 0
+		InitExprs     bool // elements are encoded as init expressions even if there are none
 	}
 
 	Data struct {
-		Expr Code
-		Init []byte
+		Mode   byte  // DataActive or DataPassive
+		Memory Index // active segment memory
+		Expr   Code  // active segment offset
+		Init   []byte
+
+		ExplicitMemory bool // set by the Decoder if memory index is encoded even if it's 0
 	}
 
 	Custom struct {
@@ -109,8 +131,30 @@ const (
 
 	FuncTypeHeader = 0x60
 
-	LimitLo   = 0x00
-	LimitLoHi = 0x01
+	LimitLo     = 0x00
+	LimitLoHi   = 0x01
+	LimitShared = 0x02 // flag
+)
+
+// Import and export description kinds.
+const (
+	ExternFunc = iota
+	ExternTable
+	ExternMemory
+	ExternGlobal
+)
+
+// Element segment modes.
+const (
+	ElemActive = iota
+	ElemPassive
+	ElemDeclarative
+)
+
+// Data segment modes.
+const (
+	DataActive = iota
+	DataPassive
 )
 
 // Section ids.
@@ -138,20 +182,192 @@ func init() {
 	}
 }
 
-func (c Code) TlogAppend(b []byte) []byte {
-	var e tlwire.Encoder
+// NumImported returns the number of imports of the kind.
+// Imported entities go first in the index space.
+func (m *Module) NumImported(kind byte) (n int) {
+	for _, im := range m.Import {
+		if im.Kind() == kind {
+			n++
+		}
+	}
 
-	b = e.AppendSemantic(b, tlwire.Hex)
+	return n
+}
 
-	return e.AppendBytes(b, c)
+// Kind returns import description kind: ExternFunc, ExternTable, ExternMemory or ExternGlobal.
+func (im *Import) Kind() byte { return im.tp }
+
+// FuncType returns imported function type index.
+func (im *Import) FuncType() Index {
+	if im.tp != ExternFunc {
+		return -1
+	}
 
-	b = e.AppendArray(b, len(c))
+	return Index(im.rawi[0])
+}
 
-	for _, v := range c {
-		b = e.AppendInt(b, int(v))
+// Table returns imported table type.
+func (im *Import) Table() Table {
+	if im.tp != ExternTable {
+		return Table{}
 	}
 
-	return b
+	return Table{
+		Type:   Type(im.rawb[0]),
+		Limits: Limits{Lo: im.rawi[0], Hi: im.rawi[1]},
+	}
+}
+
+// Memory returns imported memory limits.
+func (im *Import) Memory() Limits {
+	if im.tp != ExternMemory {
+		return Limits{}
+	}
+
+	return Limits{Lo: im.rawi[0], Hi: im.rawi[1], Shared: im.rawb[0] != 0}
+}
+
+// Global returns imported global type. Expr is always empty.
+func (im *Import) Global() Global {
+	if im.tp != ExternGlobal {
+		return Global{}
+	}
+
+	return Global{Type: Type(im.rawb[0]), Mut: im.rawb[1]}
+}
+
+// SetFunc makes it a function import of type tp.
+func (im *Import) SetFunc(tp Index) {
+	*im = Import{Module: im.Module, Name: im.Name, tp: ExternFunc}
+	im.rawi[0] = int(tp)
+}
+
+// SetTable makes it a table import.
+func (im *Import) SetTable(t Table) {
+	*im = Import{Module: im.Module, Name: im.Name, tp: ExternTable}
+	im.rawb[0] = byte(t.Type)
+	im.rawi[0], im.rawi[1] = t.Limits.Lo, t.Limits.Hi
+}
+
+// SetMemory makes it a memory import.
+func (im *Import) SetMemory(l Limits) {
+	*im = Import{Module: im.Module, Name: im.Name, tp: ExternMemory}
+	im.rawi[0], im.rawi[1] = l.Lo, l.Hi
+
+	if l.Shared {
+		im.rawb[0] = 1
+	}
+}
+
+// SetGlobal makes it a global import. g.Expr is ignored.
+func (im *Import) SetGlobal(g Global) {
+	*im = Import{Module: im.Module, Name: im.Name, tp: ExternGlobal}
+	im.rawb[0], im.rawb[1] = byte(g.Type), g.Mut
+}
+
+// SectionName returns section name by its id.
+func SectionName(id byte) string {
+	switch id {
+	case CustomSection:
+		return "custom"
+	case TypeSection:
+		return "type"
+	case ImportSection:
+		return "import"
+	case FunctionSection:
+		return "function"
+	case TableSection:
+		return "table"
+	case MemorySection:
+		return "memory"
+	case GlobalSection:
+		return "global"
+	case ExportSection:
+		return "export"
+	case StartSection:
+		return "start"
+	case ElementSection:
+		return "element"
+	case CodeSection:
+		return "code"
+	case DataSection:
+		return "data"
+	case DataCountSection:
+		return "datacount"
+	default:
+		return fmt.Sprintf("section_%02x", id)
+	}
+}
+
+func (tp Type) String() string {
+	switch tp {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	case V128:
+		return "v128"
+	case FuncRef:
+		return "funcref"
+	case ExternRef:
+		return "externref"
+	default:
+		return fmt.Sprintf("type_%02x", byte(tp))
+	}
+}
+
+func valueType(tp byte) bool {
+	switch tp {
+	case I32, I64, F32, F64, V128, FuncRef, ExternRef:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Module) sectionEmpty(id byte) bool {
+	switch id {
+	case CustomSection:
+		return len(m.Custom) == 0
+	case TypeSection:
+		return len(m.Type) == 0
+	case ImportSection:
+		return len(m.Import) == 0
+	case FunctionSection:
+		return len(m.Function) == 0
+	case TableSection:
+		return len(m.Table) == 0
+	case MemorySection:
+		return len(m.Memory) == 0
+	case GlobalSection:
+		return len(m.Global) == 0
+	case ExportSection:
+		return len(m.Export) == 0
+	case StartSection:
+		return !m.HasStart
+	case ElementSection:
+		return len(m.Element) == 0
+	case CodeSection:
+		return len(m.Code) == 0
+	case DataSection:
+		return len(m.Data) == 0
+	case DataCountSection:
+		return !m.HasDataCount
+	default:
+		return true
+	}
+}
+
+func (c Code) TlogAppend(b []byte) []byte {
+	var e tlwire.Encoder
+
+	b = e.AppendSemantic(b, tlwire.Hex)
+
+	return e.AppendBytes(b, c)
 }
 
 func (tp ResultType) TlogAppend(b []byte) []byte {
@@ -0,0 +1,308 @@
+package wasm
+
+import (
+	"sort"
+
+	"tlog.app/go/errors"
+)
+
+type (
+	// Names is a parsed name custom section.
+	Names struct {
+		Module []byte
+
+		Funcs  NameMap
+		Locals IndirectNameMap
+
+		// extended names
+		Labels   IndirectNameMap
+		Types    NameMap
+		Tables   NameMap
+		Memories NameMap
+		Globals  NameMap
+		Elems    NameMap
+		Data     NameMap
+	}
+
+	// NameMap is a list of names sorted by Index.
+	NameMap []Naming
+
+	Naming struct {
+		Index Index
+		Name  []byte
+	}
+
+	// IndirectNameMap is a list of NameMaps sorted by Index.
+	IndirectNameMap []IndirectNaming
+
+	IndirectNaming struct {
+		Index Index
+		Names NameMap
+	}
+)
+
+// NameSectionName is the name of the custom section with debug names.
+const NameSectionName = "name"
+
+// Name subsection ids.
+const (
+	NameModule = iota
+	NameFunction
+	NameLocal
+	NameLabel
+	NameType
+	NameTable
+	NameMemory
+	NameGlobal
+	NameElem
+	NameData
+)
+
+// ModuleNames finds and parses the name section.
+// It returns false if there is no one.
+func (d *Decoder) ModuleNames(m *Module, n *Names) (ok bool, err error) {
+	for _, c := range m.Custom {
+		if string(c.Name) != NameSectionName {
+			continue
+		}
+
+		_, err = d.Names(c.Data, 0, n)
+
+		return true, err
+	}
+
+	return false, nil
+}
+
+// Names decodes name section content. Unknown subsections are skipped.
+func (d *Decoder) Names(b []byte, st int, n *Names) (i int, err error) {
+	*n = Names{
+		Funcs:    n.Funcs[:0],
+		Locals:   n.Locals[:0],
+		Labels:   n.Labels[:0],
+		Types:    n.Types[:0],
+		Tables:   n.Tables[:0],
+		Memories: n.Memories[:0],
+		Globals:  n.Globals[:0],
+		Elems:    n.Elems[:0],
+		Data:     n.Data[:0],
+	}
+
+	i = st
+
+	for i < len(b) {
+		var id byte
+		var sub []byte
+		subst := i
+
+		id, sub, i, err = d.Section(b, i)
+		if err != nil {
+			return subst, errors.Wrap(err, "subsection")
+		}
+
+		switch id {
+		case NameModule:
+			n.Module, _, err = d.Name(sub, 0)
+		case NameFunction:
+			n.Funcs, err = d.nameMap(sub, n.Funcs)
+		case NameLocal:
+			n.Locals, err = d.indirectNameMap(sub, n.Locals)
+		case NameLabel:
+			n.Labels, err = d.indirectNameMap(sub, n.Labels)
+		case NameType:
+			n.Types, err = d.nameMap(sub, n.Types)
+		case NameTable:
+			n.Tables, err = d.nameMap(sub, n.Tables)
+		case NameMemory:
+			n.Memories, err = d.nameMap(sub, n.Memories)
+		case NameGlobal:
+			n.Globals, err = d.nameMap(sub, n.Globals)
+		case NameElem:
+			n.Elems, err = d.nameMap(sub, n.Elems)
+		case NameData:
+			n.Data, err = d.nameMap(sub, n.Data)
+		}
+
+		if err != nil {
+			return subst, errors.Wrap(err, "subsection %d", id)
+		}
+	}
+
+	return i, nil
+}
+
+// NameMap decodes a name map. Indexes must be in ascending order.
+func (d *Decoder) NameMap(b []byte, st int, buf NameMap) (m NameMap, i int, err error) {
+	m = buf[:0]
+
+	l, i, err := d.Int(b, st)
+	if err != nil {
+		return m, st, err
+	}
+
+	for j := 0; j < l; j++ {
+		var x Naming
+		xst := i
+
+		x.Index, i, err = d.index(b, i)
+		if err != nil {
+			return m, i, errors.Wrap(err, "index")
+		}
+
+		if j != 0 && x.Index <= m[len(m)-1].Index {
+			return m, xst, errors.New("index %d is not in ascending order after %d", x.Index, m[len(m)-1].Index)
+		}
+
+		x.Name, i, err = d.Name(b, i)
+		if err != nil {
+			return m, i, errors.Wrap(err, "name")
+		}
+
+		m = append(m, x)
+	}
+
+	return m, i, nil
+}
+
+// IndirectNameMap decodes an indirect name map. Indexes must be in ascending order.
+func (d *Decoder) IndirectNameMap(b []byte, st int, buf IndirectNameMap) (m IndirectNameMap, i int, err error) {
+	m = buf[:0]
+
+	l, i, err := d.Int(b, st)
+	if err != nil {
+		return m, st, err
+	}
+
+	for j := 0; j < l; j++ {
+		var x IndirectNaming
+		xst := i
+
+		x.Index, i, err = d.index(b, i)
+		if err != nil {
+			return m, i, errors.Wrap(err, "index")
+		}
+
+		if j != 0 && x.Index <= m[len(m)-1].Index {
+			return m, xst, errors.New("index %d is not in ascending order after %d", x.Index, m[len(m)-1].Index)
+		}
+
+		x.Names, i, err = d.NameMap(b, i, nil)
+		if err != nil {
+			return m, i, errors.Wrap(err, "names %d", x.Index)
+		}
+
+		m = append(m, x)
+	}
+
+	return m, i, nil
+}
+
+func (d *Decoder) nameMap(b []byte, buf NameMap) (m NameMap, err error) {
+	m, i, err := d.NameMap(b, 0, buf)
+	if err == nil && i != len(b) {
+		err = ErrSizeMismatch
+	}
+
+	return
+}
+
+func (d *Decoder) indirectNameMap(b []byte, buf IndirectNameMap) (m IndirectNameMap, err error) {
+	m, i, err := d.IndirectNameMap(b, 0, buf)
+	if err == nil && i != len(b) {
+		err = ErrSizeMismatch
+	}
+
+	return
+}
+
+// Names encodes name section content.
+func (e *Encoder) Names(b []byte, n *Names) []byte {
+	if n.Module != nil {
+		var st int
+
+		b, st = e.SectionStart(b, NameModule)
+		b = e.NameBytes(b, n.Module)
+		b = e.SectionEnd(b, st)
+	}
+
+	b = e.nameMap(b, NameFunction, n.Funcs)
+	b = e.indirectNameMap(b, NameLocal, n.Locals)
+	b = e.indirectNameMap(b, NameLabel, n.Labels)
+	b = e.nameMap(b, NameType, n.Types)
+	b = e.nameMap(b, NameTable, n.Tables)
+	b = e.nameMap(b, NameMemory, n.Memories)
+	b = e.nameMap(b, NameGlobal, n.Globals)
+	b = e.nameMap(b, NameElem, n.Elems)
+	b = e.nameMap(b, NameData, n.Data)
+
+	return b
+}
+
+func (e *Encoder) NameMap(b []byte, m NameMap) []byte {
+	b = e.Int(b, len(m))
+
+	for _, x := range m {
+		b = e.Int(b, int(x.Index))
+		b = e.NameBytes(b, x.Name)
+	}
+
+	return b
+}
+
+func (e *Encoder) IndirectNameMap(b []byte, m IndirectNameMap) []byte {
+	b = e.Int(b, len(m))
+
+	for _, x := range m {
+		b = e.Int(b, int(x.Index))
+		b = e.NameMap(b, x.Names)
+	}
+
+	return b
+}
+
+func (e *Encoder) nameMap(b []byte, id byte, m NameMap) []byte {
+	if len(m) == 0 {
+		return b
+	}
+
+	b, st := e.SectionStart(b, id)
+	b = e.NameMap(b, m)
+
+	return e.SectionEnd(b, st)
+}
+
+func (e *Encoder) indirectNameMap(b []byte, id byte, m IndirectNameMap) []byte {
+	if len(m) == 0 {
+		return b
+	}
+
+	b, st := e.SectionStart(b, id)
+	b = e.IndirectNameMap(b, m)
+
+	return e.SectionEnd(b, st)
+}
+
+// Name returns the name of x or nil.
+func (m NameMap) Name(x Index) []byte {
+	j := sort.Search(len(m), func(j int) bool { return m[j].Index >= x })
+	if j == len(m) || m[j].Index != x {
+		return nil
+	}
+
+	return m[j].Name
+}
+
+// Names returns the NameMap of x or nil.
+func (m IndirectNameMap) Names(x Index) NameMap {
+	j := sort.Search(len(m), func(j int) bool { return m[j].Index >= x })
+	if j == len(m) || m[j].Index != x {
+		return nil
+	}
+
+	return m[j].Names
+}
+
+// Name returns the name of y in the x NameMap or nil.
+func (m IndirectNameMap) Name(x, y Index) []byte {
+	return m.Names(x).Name(y)
+}
@@ -0,0 +1,1294 @@
+package wasm
+
+import (
+	"fmt"
+
+	"tlog.app/go/errors"
+)
+
+type (
+	// Validator checks a Module against the spec validation rules.
+	// It can be reused to avoid allocations.
+	Validator struct {
+		InstructionsDecoder
+
+		m   *Module
+		all bool
+
+		errs []ValidationError
+
+		// index spaces
+		funcs   []Index // function type indexes
+		tables  []Table
+		mems    []Limits
+		globals []Global
+		refs    []bool // functions which may be referenced by ref.func
+
+		// current function
+		locals ResultType
+		vals   []Type
+		ctrls  []ctrlFrame
+		tmp    []Type
+		in     Instruction
+	}
+
+	// ValidationError describes where validation failed.
+	ValidationError struct {
+		Section byte  // section id
+		Index   Index // function, table, memory or global index; or entry index in other sections; -1 if not applicable
+		Offset  int   // instruction offset in the function body or constant expression; -1 if not applicable
+		Err     error
+	}
+
+	ctrlFrame struct {
+		op      Opcode
+		in, out ResultType
+		height  int
+
+		unreachable bool
+	}
+)
+
+// unknownType is a type of a value popped from a polymorphic stack.
+const unknownType Type = 0
+
+// Limits checked by Validator.
+const (
+	MaxLocals = 50000   // function locals including params, implementation limit
+	MaxPages  = 1 << 16 // memory size in 64KiB pages
+)
+
+// Validate checks the module is valid according to the spec.
+// It returns the first error found as ValidationError.
+func Validate(m *Module) error {
+	var v Validator
+
+	return v.Module(m)
+}
+
+// Module returns the first validation error as ValidationError or nil.
+func (v *Validator) Module(m *Module) error {
+	v.validate(m, false)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs[0]
+}
+
+// Errors validates the module and returns all the errors found.
+// Each function body reports at most one error.
+// Returned slice is reused by the next call.
+func (v *Validator) Errors(m *Module) []ValidationError {
+	v.validate(m, true)
+
+	return v.errs
+}
+
+func (v *Validator) validate(m *Module, all bool) {
+	v.m = m
+	v.all = all
+	v.errs = v.errs[:0]
+
+	v.funcs = v.funcs[:0]
+	v.tables = v.tables[:0]
+	v.mems = v.mems[:0]
+	v.globals = v.globals[:0]
+
+	_ = v.types() ||
+		v.imports() ||
+		v.functions() ||
+		v.tableSection() ||
+		v.memorySection() ||
+		v.globalSection() ||
+		v.exports() ||
+		v.start() ||
+		v.elements() ||
+		v.dataSection() ||
+		v.codeSection()
+}
+
+// errorf records an error and reports whether validation should stop.
+func (v *Validator) errorf(section byte, x Index, off int, format string, args ...interface{}) bool {
+	return v.report(section, x, off, errors.New(format, args...))
+}
+
+func (v *Validator) report(section byte, x Index, off int, err error) bool {
+	v.errs = append(v.errs, ValidationError{Section: section, Index: x, Offset: off, Err: err})
+
+	return !v.all
+}
+
+func (v *Validator) types() bool {
+	for i, ft := range v.m.Type {
+		for _, tp := range ft.Params {
+			if !valueType(byte(tp)) && v.errorf(TypeSection, Index(i), -1, "invalid param type: %v", tp) {
+				return true
+			}
+		}
+
+		for _, tp := range ft.Result {
+			if !valueType(byte(tp)) && v.errorf(TypeSection, Index(i), -1, "invalid result type: %v", tp) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) imports() bool {
+	for i, im := range v.m.Import {
+		var err error
+
+		switch im.Kind() {
+		case ExternFunc:
+			x := im.FuncType()
+			err = v.typeIndex(x)
+
+			v.funcs = append(v.funcs, x)
+		case ExternTable:
+			t := im.Table()
+			err = v.table(t)
+
+			v.tables = append(v.tables, t)
+		case ExternMemory:
+			l := im.Memory()
+			err = v.memory(l)
+
+			v.mems = append(v.mems, l)
+		case ExternGlobal:
+			g := im.Global()
+			err = v.globalType(g)
+
+			v.globals = append(v.globals, g)
+		default:
+			err = errors.New("invalid import kind: %d", im.Kind())
+		}
+
+		if err != nil && v.report(ImportSection, Index(i), -1, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) functions() bool {
+	base := len(v.funcs)
+
+	for i, x := range v.m.Function {
+		if err := v.typeIndex(x); err != nil && v.report(FunctionSection, Index(base+i), -1, err) {
+			return true
+		}
+
+		v.funcs = append(v.funcs, x)
+	}
+
+	if len(v.m.Function) != len(v.m.Code) {
+		return v.errorf(FunctionSection, -1, -1, "function and code section have inconsistent lengths: %d != %d", len(v.m.Function), len(v.m.Code))
+	}
+
+	return false
+}
+
+func (v *Validator) tableSection() bool {
+	for _, t := range v.m.Table {
+		if err := v.table(t); err != nil && v.report(TableSection, Index(len(v.tables)), -1, err) {
+			return true
+		}
+
+		v.tables = append(v.tables, t)
+	}
+
+	return false
+}
+
+func (v *Validator) memorySection() bool {
+	for _, l := range v.m.Memory {
+		if err := v.memory(l); err != nil && v.report(MemorySection, Index(len(v.mems)), -1, err) {
+			return true
+		}
+
+		v.mems = append(v.mems, l)
+	}
+
+	if len(v.mems) > 1 {
+		return v.errorf(MemorySection, -1, -1, "multiple memories")
+	}
+
+	return false
+}
+
+func (v *Validator) globalSection() bool {
+	imported := len(v.globals)
+
+	v.refs = v.refs[:0]
+
+	for range v.funcs {
+		v.refs = append(v.refs, false)
+	}
+
+	for _, g := range v.m.Global {
+		x := Index(len(v.globals))
+
+		err := v.globalType(g)
+		if err != nil && v.report(GlobalSection, x, -1, err) {
+			return true
+		}
+
+		off, err := v.constExpr(g.Expr, g.Type, imported)
+		if err != nil && v.report(GlobalSection, x, off, err) {
+			return true
+		}
+
+		v.globals = append(v.globals, g)
+	}
+
+	return false
+}
+
+func (v *Validator) exports() bool {
+	names := make(map[string]struct{}, len(v.m.Export))
+
+	for i, ex := range v.m.Export {
+		if _, ok := names[string(ex.Name)]; ok && v.errorf(ExportSection, Index(i), -1, "duplicate export name: %q", ex.Name) {
+			return true
+		}
+
+		names[string(ex.Name)] = struct{}{}
+
+		var err error
+
+		switch ex.ExportType {
+		case ExternFunc:
+			_, err = v.funcType(ex.Index)
+			if err == nil {
+				v.refs[ex.Index] = true
+			}
+		case ExternTable:
+			_, err = v.tableIndex(ex.Index)
+		case ExternMemory:
+			err = v.memoryIndex(ex.Index)
+		case ExternGlobal:
+			_, err = v.globalIndex(ex.Index)
+		default:
+			err = errors.New("invalid export kind: %d", ex.ExportType)
+		}
+
+		if err != nil && v.report(ExportSection, Index(i), -1, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) start() bool {
+	if !v.m.HasStart {
+		return false
+	}
+
+	ft, err := v.funcType(v.m.Start)
+	if err != nil {
+		return v.report(StartSection, -1, -1, err)
+	}
+
+	if len(ft.Params) != 0 || len(ft.Result) != 0 {
+		return v.errorf(StartSection, -1, -1, "start function must have no params and results")
+	}
+
+	return false
+}
+
+func (v *Validator) elements() bool {
+	for i, el := range v.m.Element {
+		off, err := v.element(el)
+		if err != nil && v.report(ElementSection, Index(i), off, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) element(el Element) (off int, err error) {
+	if el.Type == 0 {
+		el.Type = FuncRef
+	}
+
+	if !isRefType(el.Type) {
+		return -1, errors.New("invalid element type: %v", el.Type)
+	}
+
+	switch el.Mode {
+	case ElemActive:
+		t, err := v.tableIndex(el.Table)
+		if err != nil {
+			return -1, err
+		}
+
+		if t.Type != el.Type {
+			return -1, errors.New("type mismatch: %v element segment for %v table", el.Type, t.Type)
+		}
+
+		off, err := v.constExpr(el.Expr, I32, len(v.globals))
+		if err != nil {
+			return off, errors.Wrap(err, "offset")
+		}
+	case ElemPassive, ElemDeclarative:
+	default:
+		return -1, errors.New("invalid element mode: %d", el.Mode)
+	}
+
+	if len(el.Funcs) != 0 && el.Type != FuncRef {
+		return -1, errors.New("type mismatch: function indexes in %v element segment", el.Type)
+	}
+
+	for _, x := range el.Funcs {
+		if _, err := v.funcType(x); err != nil {
+			return -1, err
+		}
+
+		v.refs[x] = true
+	}
+
+	for _, e := range el.Init {
+		off, err := v.constExpr(e, el.Type, len(v.globals))
+		if err != nil {
+			return off, errors.Wrap(err, "init")
+		}
+	}
+
+	return -1, nil
+}
+
+func (v *Validator) dataSection() bool {
+	if v.m.HasDataCount && v.m.DataCount != len(v.m.Data) {
+		if v.errorf(DataCountSection, -1, -1, "data count and data section have inconsistent lengths: %d != %d", v.m.DataCount, len(v.m.Data)) {
+			return true
+		}
+	}
+
+	for i, d := range v.m.Data {
+		off := -1
+		var err error
+
+		switch d.Mode {
+		case DataActive:
+			err = v.memoryIndex(d.Memory)
+			if err != nil {
+				break
+			}
+
+			off, err = v.constExpr(d.Expr, I32, len(v.globals))
+			err = errors.Wrap(err, "offset")
+		case DataPassive:
+		default:
+			err = errors.New("invalid data mode: %d", d.Mode)
+		}
+
+		if err != nil && v.report(DataSection, Index(i), off, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Validator) codeSection() bool {
+	base := len(v.funcs) - len(v.m.Function)
+
+	for i, c := range v.m.Code {
+		if i == len(v.m.Function) {
+			break
+		}
+
+		x := Index(base + i)
+
+		off, err := v.function(x, c)
+		if err != nil && v.report(CodeSection, x, off, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// constExpr validates constant expression of type tp.
+// Only the first globals may be referred by global.get.
+func (v *Validator) constExpr(e Code, tp Type, globals int) (off int, err error) {
+	v.vals = v.vals[:0]
+
+	for i := 0; i < len(e); {
+		off = i
+
+		v.in, i, err = v.Instruction(e, i, v.in)
+		if err != nil {
+			return off, err
+		}
+
+		switch in := &v.in; in.Opcode {
+		case I32Const:
+			v.push(I32)
+		case I64Const:
+			v.push(I64)
+		case F32Const:
+			v.push(F32)
+		case F64Const:
+			v.push(F64)
+		case RefNull:
+			if !isRefType(in.RefType) {
+				return off, errors.New("invalid reference type: %v", in.RefType)
+			}
+
+			v.push(in.RefType)
+		case RefFunc:
+			if _, err = v.funcType(in.Index); err != nil {
+				return off, err
+			}
+
+			v.refs[in.Index] = true
+			v.push(FuncRef)
+		case GlobalGet:
+			if in.Index < 0 || int(in.Index) >= globals {
+				return off, errors.New("unknown global %d", in.Index)
+			}
+
+			g := v.globals[in.Index]
+			if g.Mut != 0 {
+				return off, errors.New("constant expression required: global %d is mutable", in.Index)
+			}
+
+			v.push(g.Type)
+		case FDExt:
+			if in.Ext != FDV128Const {
+				return off, errors.New("constant expression required: %v", in.Name())
+			}
+
+			v.push(V128)
+		case End:
+			if i != len(e) {
+				return i, errors.New("instructions after expression end")
+			}
+
+			if len(v.vals) != 1 || v.vals[0] != tp {
+				return off, errors.New("type mismatch: expected %v, got %v", tp, ResultType(v.vals))
+			}
+
+			return -1, nil
+		default:
+			return off, errors.New("constant expression required: %v", in.Name())
+		}
+	}
+
+	return len(e), errors.New("unexpected end of expression")
+}
+
+// function validates function x body.
+// off is the offset of failed instruction.
+func (v *Validator) function(x Index, c Code) (off int, err error) {
+	ft, err := v.funcType(x)
+	if err != nil {
+		return -1, err
+	}
+
+	i, err := v.localDecls(c, ft.Params)
+	if err != nil {
+		return 0, err
+	}
+
+	v.vals = v.vals[:0]
+	v.ctrls = v.ctrls[:0]
+
+	v.pushCtrl(Block, nil, ft.Result)
+
+	for i < len(c) {
+		off = i
+
+		if len(v.ctrls) == 0 {
+			return off, errors.New("instructions after function end")
+		}
+
+		v.in, i, err = v.Instruction(c, i, v.in)
+		if err != nil {
+			return off, err
+		}
+
+		err = v.instr(&v.in)
+		if err != nil {
+			return off, err
+		}
+	}
+
+	if len(v.ctrls) != 0 {
+		return len(c), errors.New("unexpected end of function")
+	}
+
+	return -1, nil
+}
+
+func (v *Validator) localDecls(c Code, params ResultType) (i int, err error) {
+	v.locals = append(v.locals[:0], params...)
+
+	n, i, err := v.Int(c, 0)
+	if err != nil {
+		return 0, errors.Wrap(err, "locals")
+	}
+
+	var cnt int
+	var tp byte
+
+	for j := 0; j < n; j++ {
+		cnt, i, err = v.Int(c, i)
+		if err != nil {
+			return 0, errors.Wrap(err, "locals")
+		}
+
+		tp, i, err = v.Byte(c, i)
+		if err != nil {
+			return 0, errors.Wrap(err, "locals")
+		}
+
+		if !valueType(tp) {
+			return 0, errors.New("invalid local type: %v", Type(tp))
+		}
+
+		if cnt < 0 || cnt > MaxLocals-len(v.locals) {
+			return 0, errors.New("too many locals")
+		}
+
+		for k := 0; k < cnt; k++ {
+			v.locals = append(v.locals, Type(tp))
+		}
+	}
+
+	return i, nil
+}
+
+func (v *Validator) instr(in *Instruction) (err error) {
+	switch op := in.Opcode; op {
+	case Unreachable:
+		v.setUnreachable()
+	case Nop:
+	case Block, Loop, If:
+		params, results, err := v.blockType(in.BlockType)
+		if err != nil {
+			return err
+		}
+
+		if op == If {
+			if err = v.popExpect(I32); err != nil {
+				return err
+			}
+		}
+
+		if err = v.pops(params); err != nil {
+			return err
+		}
+
+		v.pushCtrl(op, params, results)
+	case Else:
+		f, err := v.popCtrl()
+		if err != nil {
+			return err
+		}
+
+		if f.op != If {
+			return errors.New("else without if")
+		}
+
+		v.pushCtrl(Else, f.in, f.out)
+	case End:
+		f, err := v.popCtrl()
+		if err != nil {
+			return err
+		}
+
+		if f.op == If && !equalResultTypes(f.in, f.out) {
+			return errors.New("type mismatch: if without else must have the same params and results")
+		}
+
+		v.pushes(f.out)
+	case Br:
+		l, err := v.label(in.Label)
+		if err != nil {
+			return err
+		}
+
+		if err = v.pops(l); err != nil {
+			return err
+		}
+
+		v.setUnreachable()
+	case BrIf:
+		l, err := v.label(in.Label)
+		if err != nil {
+			return err
+		}
+
+		if err = v.popExpect(I32); err != nil {
+			return err
+		}
+
+		if err = v.pops(l); err != nil {
+			return err
+		}
+
+		v.pushes(l)
+	case BrTable:
+		return v.brTable(in)
+	case Ret:
+		if err = v.pops(v.ctrls[0].out); err != nil {
+			return err
+		}
+
+		v.setUnreachable()
+	case Call:
+		ft, err := v.funcType(in.Index)
+		if err != nil {
+			return err
+		}
+
+		return v.apply(ft.Params, ft.Result)
+	case CallIndir:
+		t, err := v.tableIndex(in.Table)
+		if err != nil {
+			return err
+		}
+
+		if t.Type != FuncRef {
+			return errors.New("type mismatch: call_indirect on %v table", t.Type)
+		}
+
+		if err = v.typeIndex(in.Index); err != nil {
+			return err
+		}
+
+		if err = v.popExpect(I32); err != nil {
+			return err
+		}
+
+		ft := v.m.Type[in.Index]
+
+		return v.apply(ft.Params, ft.Result)
+	case Drop:
+		_, err = v.pop()
+
+		return err
+	case Select:
+		return v.selectInstr()
+	case SelectT:
+		if len(in.Types) != 1 || !valueType(byte(in.Types[0])) {
+			return errors.New("invalid select result type: %v", in.Types)
+		}
+
+		tp := in.Types[0]
+
+		if err = v.pops(ResultType{tp, tp, I32}); err != nil {
+			return err
+		}
+
+		v.push(tp)
+	case LocalGet, LocalSet, LocalTee:
+		if in.Index < 0 || int(in.Index) >= len(v.locals) {
+			return errors.New("unknown local %d", in.Index)
+		}
+
+		tp := v.locals[in.Index]
+
+		if op != LocalGet {
+			if err = v.popExpect(tp); err != nil {
+				return err
+			}
+		}
+
+		if op != LocalSet {
+			v.push(tp)
+		}
+	case GlobalGet:
+		g, err := v.globalIndex(in.Index)
+		if err != nil {
+			return err
+		}
+
+		v.push(g.Type)
+	case GlobalSet:
+		g, err := v.globalIndex(in.Index)
+		if err != nil {
+			return err
+		}
+
+		if g.Mut == 0 {
+			return errors.New("global %d is immutable", in.Index)
+		}
+
+		return v.popExpect(g.Type)
+	case TableGet, TableSet:
+		t, err := v.tableIndex(in.Table)
+		if err != nil {
+			return err
+		}
+
+		if op == TableGet {
+			return v.apply(singleTypes[I32], singleTypes[t.Type])
+		}
+
+		return v.apply(ResultType{I32, t.Type}, nil)
+	case MemorySize, MemoryGrow:
+		if err = v.memoryIndex(in.Memory); err != nil {
+			return err
+		}
+
+		if op == MemoryGrow {
+			if err = v.popExpect(I32); err != nil {
+				return err
+			}
+		}
+
+		v.push(I32)
+	case RefNull:
+		if !isRefType(in.RefType) {
+			return errors.New("invalid reference type: %v", in.RefType)
+		}
+
+		v.push(in.RefType)
+	case RefIsNull:
+		tp, err := v.pop()
+		if err != nil {
+			return err
+		}
+
+		if tp != unknownType && !isRefType(tp) {
+			return errors.New("type mismatch: expected reference, got %v", tp)
+		}
+
+		v.push(I32)
+	case RefFunc:
+		if _, err = v.funcType(in.Index); err != nil {
+			return err
+		}
+
+		if !v.refs[in.Index] {
+			return errors.New("undeclared function reference %d", in.Index)
+		}
+
+		v.push(FuncRef)
+	case FCExt:
+		return v.fcInstr(in)
+	case FDExt:
+		return v.fdInstr(in)
+	case FEExt:
+		return v.feInstr(in)
+	default:
+		s := &opSigs[op]
+
+		if s.mem {
+			if err = v.memArg(in, s.align, false); err != nil {
+				return err
+			}
+		}
+
+		return v.apply(s.in, s.out)
+	}
+
+	return nil
+}
+
+func (v *Validator) brTable(in *Instruction) (err error) {
+	if err = v.popExpect(I32); err != nil {
+		return err
+	}
+
+	def, err := v.label(in.Label)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range in.Labels {
+		l, err := v.label(x)
+		if err != nil {
+			return err
+		}
+
+		if len(l) != len(def) {
+			return errors.New("type mismatch: br_table label %d arity %d, default label arity %d", x, len(l), len(def))
+		}
+
+		v.tmp = v.tmp[:0]
+
+		for j := len(l) - 1; j >= 0; j-- {
+			tp, err := v.popType(l[j])
+			if err != nil {
+				return err
+			}
+
+			v.tmp = append(v.tmp, tp)
+		}
+
+		for j := len(v.tmp) - 1; j >= 0; j-- {
+			v.push(v.tmp[j])
+		}
+	}
+
+	if err = v.pops(def); err != nil {
+		return err
+	}
+
+	v.setUnreachable()
+
+	return nil
+}
+
+func (v *Validator) selectInstr() (err error) {
+	if err = v.popExpect(I32); err != nil {
+		return err
+	}
+
+	t1, err := v.pop()
+	if err != nil {
+		return err
+	}
+
+	t2, err := v.pop()
+	if err != nil {
+		return err
+	}
+
+	if isRefType(t1) || isRefType(t2) {
+		return errors.New("type mismatch: select without type on reference operands")
+	}
+
+	if t1 != t2 && t1 != unknownType && t2 != unknownType {
+		return errors.New("type mismatch: select operands %v and %v", t2, t1)
+	}
+
+	if t1 == unknownType {
+		t1 = t2
+	}
+
+	v.push(t1)
+
+	return nil
+}
+
+func (v *Validator) fcInstr(in *Instruction) (err error) {
+	i32x3 := ResultType{I32, I32, I32}
+
+	switch in.Ext {
+	case FCMemoryInit, FCDataDrop:
+		if !v.m.HasDataCount {
+			return errors.New("data count section required")
+		}
+
+		if in.Index < 0 || int(in.Index) >= v.m.DataCount {
+			return errors.New("unknown data segment %d", in.Index)
+		}
+
+		if in.Ext == FCDataDrop {
+			return nil
+		}
+
+		if err = v.memoryIndex(in.Memory); err != nil {
+			return err
+		}
+
+		return v.pops(i32x3)
+	case FCMemoryCopy, FCMemoryFill:
+		if err = v.memoryIndex(in.Memory); err != nil {
+			return err
+		}
+
+		if in.Ext == FCMemoryCopy {
+			if err = v.memoryIndex(in.Src); err != nil {
+				return err
+			}
+		}
+
+		return v.pops(i32x3)
+	case FCTableInit, FCElemDrop:
+		if in.Index < 0 || int(in.Index) >= len(v.m.Element) {
+			return errors.New("unknown elem segment %d", in.Index)
+		}
+
+		if in.Ext == FCElemDrop {
+			return nil
+		}
+
+		t, err := v.tableIndex(in.Table)
+		if err != nil {
+			return err
+		}
+
+		if tp := v.m.Element[in.Index].Type; tp != t.Type {
+			return errors.New("type mismatch: %v element segment for %v table", tp, t.Type)
+		}
+
+		return v.pops(i32x3)
+	case FCTableCopy:
+		dst, err := v.tableIndex(in.Table)
+		if err != nil {
+			return err
+		}
+
+		src, err := v.tableIndex(in.Src)
+		if err != nil {
+			return err
+		}
+
+		if src.Type != dst.Type {
+			return errors.New("type mismatch: copy from %v table to %v table", src.Type, dst.Type)
+		}
+
+		return v.pops(i32x3)
+	case FCTableGrow, FCTableSize, FCTableFill:
+		t, err := v.tableIndex(in.Table)
+		if err != nil {
+			return err
+		}
+
+		switch in.Ext {
+		case FCTableGrow:
+			return v.apply(ResultType{t.Type, I32}, singleTypes[I32])
+		case FCTableSize:
+			return v.apply(nil, singleTypes[I32])
+		default:
+			return v.apply(ResultType{I32, t.Type, I32}, nil)
+		}
+	}
+
+	if int(in.Ext) >= len(fcSigs) {
+		return errors.New("unsupported instruction: %v", in.Name())
+	}
+
+	s := &fcSigs[in.Ext]
+
+	return v.apply(s.in, s.out)
+}
+
+func (v *Validator) fdInstr(in *Instruction) (err error) {
+	s := &fdSigs[in.Ext]
+
+	if s.mem {
+		if err = v.memArg(in, s.align, false); err != nil {
+			return err
+		}
+	}
+
+	if s.lanes != 0 && int(in.Lane) >= s.lanes {
+		return errors.New("invalid lane index: %d", in.Lane)
+	}
+
+	if in.Ext == FDI8x16Shuffle {
+		for _, l := range in.V128 {
+			if l >= 32 {
+				return errors.New("invalid lane index: %d", l)
+			}
+		}
+	}
+
+	return v.apply(s.in, s.out)
+}
+
+func (v *Validator) feInstr(in *Instruction) (err error) {
+	if in.Ext == FEAtomicFence {
+		return nil
+	}
+
+	s := &feSigs[in.Ext]
+
+	if err = v.memArg(in, s.align, true); err != nil {
+		return err
+	}
+
+	return v.apply(s.in, s.out)
+}
+
+// memArg checks memory instruction alignment, it must be natural for atomic instructions.
+func (v *Validator) memArg(in *Instruction, natural int, exact bool) error {
+	if err := v.memoryIndex(0); err != nil {
+		return err
+	}
+
+	if in.MemArg.Align > natural || exact && in.MemArg.Align != natural {
+		return errors.New("invalid alignment: %d, natural is %d", in.MemArg.Align, natural)
+	}
+
+	return nil
+}
+
+func (v *Validator) blockType(bt BlockType) (params, results ResultType, err error) {
+	if bt == BlockEmpty {
+		return nil, nil, nil
+	}
+
+	if tp, ok := bt.ValueType(); ok {
+		if !valueType(byte(tp)) {
+			return nil, nil, errors.New("invalid block type: %v", tp)
+		}
+
+		return nil, singleTypes[tp], nil
+	}
+
+	if err = v.typeIndex(Index(bt)); err != nil {
+		return nil, nil, err
+	}
+
+	ft := v.m.Type[bt]
+
+	return ft.Params, ft.Result, nil
+}
+
+func (v *Validator) label(x Index) (ResultType, error) {
+	if x < 0 || int(x) >= len(v.ctrls) {
+		return nil, errors.New("unknown label %d", x)
+	}
+
+	f := &v.ctrls[len(v.ctrls)-1-int(x)]
+
+	if f.op == Loop {
+		return f.in, nil
+	}
+
+	return f.out, nil
+}
+
+// apply pops params and pushes results.
+func (v *Validator) apply(params, results ResultType) error {
+	if err := v.pops(params); err != nil {
+		return err
+	}
+
+	v.pushes(results)
+
+	return nil
+}
+
+func (v *Validator) push(tp Type) {
+	v.vals = append(v.vals, tp)
+}
+
+func (v *Validator) pushes(tps ResultType) {
+	v.vals = append(v.vals, tps...)
+}
+
+func (v *Validator) pop() (Type, error) {
+	f := &v.ctrls[len(v.ctrls)-1]
+
+	if len(v.vals) == f.height {
+		if f.unreachable {
+			return unknownType, nil
+		}
+
+		return 0, errors.New("type mismatch: value expected, stack is empty")
+	}
+
+	tp := v.vals[len(v.vals)-1]
+	v.vals = v.vals[:len(v.vals)-1]
+
+	return tp, nil
+}
+
+// popType pops a value of type exp and returns its actual type, which may be unknownType.
+func (v *Validator) popType(exp Type) (Type, error) {
+	tp, err := v.pop()
+	if err != nil {
+		return 0, errors.New("type mismatch: expected %v, stack is empty", exp)
+	}
+
+	if tp != exp && tp != unknownType {
+		return 0, errors.New("type mismatch: expected %v, got %v", exp, tp)
+	}
+
+	return tp, nil
+}
+
+func (v *Validator) popExpect(exp Type) error {
+	_, err := v.popType(exp)
+
+	return err
+}
+
+func (v *Validator) pops(tps ResultType) error {
+	for j := len(tps) - 1; j >= 0; j-- {
+		if err := v.popExpect(tps[j]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *Validator) pushCtrl(op Opcode, params, results ResultType) {
+	v.ctrls = append(v.ctrls, ctrlFrame{
+		op:     op,
+		in:     params,
+		out:    results,
+		height: len(v.vals),
+	})
+
+	v.pushes(params)
+}
+
+func (v *Validator) popCtrl() (f ctrlFrame, err error) {
+	f = v.ctrls[len(v.ctrls)-1]
+
+	if err = v.pops(f.out); err != nil {
+		return f, err
+	}
+
+	if len(v.vals) != f.height {
+		return f, errors.New("type mismatch: %d extra values at the end of block", len(v.vals)-f.height)
+	}
+
+	v.ctrls = v.ctrls[:len(v.ctrls)-1]
+
+	return f, nil
+}
+
+func (v *Validator) setUnreachable() {
+	f := &v.ctrls[len(v.ctrls)-1]
+
+	v.vals = v.vals[:f.height]
+	f.unreachable = true
+}
+
+func (v *Validator) typeIndex(x Index) error {
+	if x < 0 || int(x) >= len(v.m.Type) {
+		return errors.New("unknown type %d", x)
+	}
+
+	return nil
+}
+
+func (v *Validator) funcType(x Index) (FuncType, error) {
+	if x < 0 || int(x) >= len(v.funcs) {
+		return FuncType{}, errors.New("unknown function %d", x)
+	}
+
+	tx := v.funcs[x]
+	if tx < 0 || int(tx) >= len(v.m.Type) {
+		return FuncType{}, errors.New("unknown type %d of function %d", tx, x)
+	}
+
+	return v.m.Type[tx], nil
+}
+
+func (v *Validator) tableIndex(x Index) (Table, error) {
+	if x < 0 || int(x) >= len(v.tables) {
+		return Table{}, errors.New("unknown table %d", x)
+	}
+
+	return v.tables[x], nil
+}
+
+func (v *Validator) memoryIndex(x Index) error {
+	if x < 0 || int(x) >= len(v.mems) {
+		return errors.New("unknown memory %d", x)
+	}
+
+	return nil
+}
+
+func (v *Validator) globalIndex(x Index) (Global, error) {
+	if x < 0 || int(x) >= len(v.globals) {
+		return Global{}, errors.New("unknown global %d", x)
+	}
+
+	return v.globals[x], nil
+}
+
+func (v *Validator) table(t Table) error {
+	if !isRefType(t.Type) {
+		return errors.New("invalid table element type: %v", t.Type)
+	}
+
+	return limits(t.Limits, 1<<32-1)
+}
+
+func (v *Validator) memory(l Limits) error {
+	if err := limits(l, MaxPages); err != nil {
+		return err
+	}
+
+	if l.Shared && l.Hi < 0 {
+		return errors.New("shared memory must have maximum")
+	}
+
+	return nil
+}
+
+func (v *Validator) globalType(g Global) error {
+	if !valueType(byte(g.Type)) {
+		return errors.New("invalid global type: %v", g.Type)
+	}
+
+	if g.Mut > 1 {
+		return errors.New("invalid global mutability: %d", g.Mut)
+	}
+
+	return nil
+}
+
+func limits(l Limits, max int) error {
+	if l.Lo < 0 || l.Lo > max {
+		return errors.New("limits minimum must not be larger than %d", max)
+	}
+
+	if l.Hi >= 0 && l.Hi > max {
+		return errors.New("limits maximum must not be larger than %d", max)
+	}
+
+	if l.Hi >= 0 && l.Hi < l.Lo {
+		return errors.New("limits minimum must not be larger than maximum")
+	}
+
+	return nil
+}
+
+func isRefType(tp Type) bool {
+	return tp == FuncRef || tp == ExternRef
+}
+
+func equalResultTypes(a, b ResultType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e ValidationError) Error() string {
+	b := fmt.Appendf(nil, "%s section", SectionName(e.Section))
+
+	if e.Index >= 0 {
+		b = fmt.Appendf(b, ": %s %d", SectionItem(e.Section), e.Index)
+	}
+
+	if e.Offset >= 0 {
+		b = fmt.Appendf(b, ": offset 0x%x", e.Offset)
+	}
+
+	return fmt.Sprintf("%s: %v", b, e.Err)
+}
+
+func (e ValidationError) Unwrap() error { return e.Err }
+
+// SectionItem returns the name of the section items used in error messages.
+func SectionItem(id byte) string {
+	switch id {
+	case FunctionSection, CodeSection:
+		return "func"
+	case ElementSection:
+		return "elem"
+	default:
+		return SectionName(id)
+	}
+}
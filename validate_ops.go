@@ -0,0 +1,229 @@
+package wasm
+
+type (
+	// opSig is an instruction operand and result types.
+	opSig struct {
+		in, out ResultType
+
+		mem   bool // memory access instruction
+		align int  // natural alignment of the memory access
+		lanes int  // lane index upper bound
+	}
+)
+
+var (
+	opSigs [256]opSig
+	fcSigs [FCTableFill + 1]opSig
+	fdSigs [256]opSig
+	feSigs [len(feNames)]opSig
+
+	// singleTypes contains one element ResultType for each value type.
+	singleTypes [256]ResultType
+)
+
+func init() {
+	for _, tp := range []Type{I32, I64, F32, F64, V128, FuncRef, ExternRef} {
+		singleTypes[tp] = ResultType{tp}
+	}
+
+	i32, i64, f32, f64, v128 := singleTypes[I32], singleTypes[I64], singleTypes[F32], singleTypes[F64], singleTypes[V128]
+
+	i32i32 := ResultType{I32, I32}
+	i64i64 := ResultType{I64, I64}
+	f32f32 := ResultType{F32, F32}
+	f64f64 := ResultType{F64, F64}
+	i32v128 := ResultType{I32, V128}
+	v128v128 := ResultType{V128, V128}
+
+	set := func(tab []opSig, lo, hi int, in, out ResultType) {
+		for op := lo; op <= hi; op++ {
+			tab[op] = opSig{in: in, out: out}
+		}
+	}
+
+	mem := func(tab []opSig, op int, in, out ResultType, align int) {
+		tab[op] = opSig{in: in, out: out, mem: true, align: align}
+	}
+
+	// memory
+
+	for _, x := range []struct {
+		op    int
+		tp    ResultType
+		align int
+	}{
+		{I32Load, i32, 2}, {I64Load, i64, 3}, {F32Load, f32, 2}, {F64Load, f64, 3},
+		{I32Load8S, i32, 0}, {I32Load8U, i32, 0}, {I32Load16S, i32, 1}, {I32Load16U, i32, 1},
+		{I64Load8S, i64, 0}, {I64Load8U, i64, 0}, {I64Load16S, i64, 1}, {I64Load16U, i64, 1},
+		{I64Load32S, i64, 2}, {I64Load32U, i64, 2},
+	} {
+		mem(opSigs[:], x.op, i32, x.tp, x.align)
+	}
+
+	for _, x := range []struct {
+		op    int
+		tp    Type
+		align int
+	}{
+		{I32Store, I32, 2}, {I64Store, I64, 3}, {F32Store, F32, 2}, {F64Store, F64, 3},
+		{I32Store8, I32, 0}, {I32Store16, I32, 1},
+		{I64Store8, I64, 0}, {I64Store16, I64, 1}, {I64Store32, I64, 2},
+	} {
+		mem(opSigs[:], x.op, ResultType{I32, x.tp}, nil, x.align)
+	}
+
+	// numeric
+
+	opSigs[I32Const] = opSig{out: i32}
+	opSigs[I64Const] = opSig{out: i64}
+	opSigs[F32Const] = opSig{out: f32}
+	opSigs[F64Const] = opSig{out: f64}
+
+	set(opSigs[:], I32EqZ, I32EqZ, i32, i32)
+	set(opSigs[:], I32Eq, I32GeU, i32i32, i32)
+	set(opSigs[:], I64EqZ, I64EqZ, i64, i32)
+	set(opSigs[:], I64Eq, I64GeU, i64i64, i32)
+	set(opSigs[:], F32Eq, F32Ge, f32f32, i32)
+	set(opSigs[:], F64Eq, F64Ge, f64f64, i32)
+
+	set(opSigs[:], I32Clz, I32Popcnt, i32, i32)
+	set(opSigs[:], I32Add, I32RotR, i32i32, i32)
+	set(opSigs[:], I64Clz, I64Popcnt, i64, i64)
+	set(opSigs[:], I64Add, I64RotR, i64i64, i64)
+	set(opSigs[:], F32Abs, F32Sqrt, f32, f32)
+	set(opSigs[:], F32Add, F32CopySign, f32f32, f32)
+	set(opSigs[:], F64Abs, F64Sqrt, f64, f64)
+	set(opSigs[:], F64Add, F64CopySign, f64f64, f64)
+
+	set(opSigs[:], I32WrapI64, I32WrapI64, i64, i32)
+	set(opSigs[:], I32TruncF32S, I32TruncF32U, f32, i32)
+	set(opSigs[:], I32TruncF64S, I32TruncF64U, f64, i32)
+	set(opSigs[:], I64ExtendI32S, I64ExtendI32U, i32, i64)
+	set(opSigs[:], I64TruncF32S, I64TruncF32U, f32, i64)
+	set(opSigs[:], I64TruncF64S, I64TruncF64U, f64, i64)
+	set(opSigs[:], F32ConvertI32S, F32ConvertI32U, i32, f32)
+	set(opSigs[:], F32ConvertI64S, F32ConvertI64U, i64, f32)
+	set(opSigs[:], F32DemoteF64, F32DemoteF64, f64, f32)
+	set(opSigs[:], F64ConvertI32S, F64ConvertI32U, i32, f64)
+	set(opSigs[:], F64ConvertI64S, F64ConvertI64U, i64, f64)
+	set(opSigs[:], F64PromoteF32, F64PromoteF32, f32, f64)
+
+	set(opSigs[:], I32ReinterpretF32, I32ReinterpretF32, f32, i32)
+	set(opSigs[:], I64ReinterpretF64, I64ReinterpretF64, f64, i64)
+	set(opSigs[:], F32ReinterpretI32, F32ReinterpretI32, i32, f32)
+	set(opSigs[:], F64ReinterpretI64, F64ReinterpretI64, i64, f64)
+
+	set(opSigs[:], I32Extend8S, I32Extend16S, i32, i32)
+	set(opSigs[:], I64Extend8S, I64Extend32S, i64, i64)
+
+	set(fcSigs[:], FCI32TruncSatF32S, FCI32TruncSatF32U, f32, i32)
+	set(fcSigs[:], FCI32TruncSatF64S, FCI32TruncSatF64U, f64, i32)
+	set(fcSigs[:], FCI64TruncSatF32S, FCI64TruncSatF32U, f32, i64)
+	set(fcSigs[:], FCI64TruncSatF64S, FCI64TruncSatF64U, f64, i64)
+
+	// simd, most are binary operations, the rest are listed below
+
+	set(fdSigs[:], FDI8x16Eq, 0xff, v128v128, v128)
+
+	for op, align := range []int{4, 3, 3, 3, 3, 3, 3, 0, 1, 2, 3} {
+		mem(fdSigs[:], FDV128Load+op, i32, v128, align)
+	}
+
+	mem(fdSigs[:], FDV128Store, i32v128, nil, 4)
+	mem(fdSigs[:], FDV128Load32Zero, i32, v128, 2)
+	mem(fdSigs[:], FDV128Load64Zero, i32, v128, 3)
+
+	for j, lanes := range []int{16, 8, 4, 2} {
+		fdSigs[FDV128Load8Lane+j] = opSig{in: i32v128, out: v128, mem: true, align: j, lanes: lanes}
+		fdSigs[FDV128Store8Lane+j] = opSig{in: i32v128, mem: true, align: j, lanes: lanes}
+	}
+
+	fdSigs[FDV128Const] = opSig{out: v128}
+	fdSigs[FDI8x16Shuffle] = opSig{in: v128v128, out: v128}
+	fdSigs[FDI8x16Swizzle] = opSig{in: v128v128, out: v128}
+
+	for _, x := range []struct {
+		splat, extract, replace int
+		extractU                int
+		tp                      ResultType
+		lanes                   int
+	}{
+		{FDI8x16Splat, FDI8x16ExtractLaneS, FDI8x16ReplaceLane, FDI8x16ExtractLaneU, i32, 16},
+		{FDI16x8Splat, FDI16x8ExtractLaneS, FDI16x8ReplaceLane, FDI16x8ExtractLaneU, i32, 8},
+		{FDI32x4Splat, FDI32x4ExtractLane, FDI32x4ReplaceLane, -1, i32, 4},
+		{FDI64x2Splat, FDI64x2ExtractLane, FDI64x2ReplaceLane, -1, i64, 2},
+		{FDF32x4Splat, FDF32x4ExtractLane, FDF32x4ReplaceLane, -1, f32, 4},
+		{FDF64x2Splat, FDF64x2ExtractLane, FDF64x2ReplaceLane, -1, f64, 2},
+	} {
+		fdSigs[x.splat] = opSig{in: x.tp, out: v128}
+		fdSigs[x.extract] = opSig{in: v128, out: x.tp, lanes: x.lanes}
+		fdSigs[x.replace] = opSig{in: ResultType{V128, x.tp[0]}, out: v128, lanes: x.lanes}
+
+		if x.extractU >= 0 {
+			fdSigs[x.extractU] = fdSigs[x.extract]
+		}
+	}
+
+	for _, op := range []int{
+		FDV128Not, FDF32x4DemoteF64x2Zero, FDF64x2PromoteLowF32x4,
+		FDI8x16Abs, FDI8x16Neg, FDI8x16Popcnt,
+		FDF32x4Ceil, FDF32x4Floor, FDF32x4Trunc, FDF32x4Nearest,
+		FDF64x2Ceil, FDF64x2Floor, FDF64x2Trunc, FDF64x2Nearest,
+		FDI16x8ExtaddPairwiseI8x16S, FDI16x8ExtaddPairwiseI8x16U, FDI32x4ExtaddPairwiseI16x8S, FDI32x4ExtaddPairwiseI16x8U,
+		FDI16x8Abs, FDI16x8Neg,
+		FDI16x8ExtendLowI8x16S, FDI16x8ExtendHighI8x16S, FDI16x8ExtendLowI8x16U, FDI16x8ExtendHighI8x16U,
+		FDI32x4Abs, FDI32x4Neg,
+		FDI32x4ExtendLowI16x8S, FDI32x4ExtendHighI16x8S, FDI32x4ExtendLowI16x8U, FDI32x4ExtendHighI16x8U,
+		FDI64x2Abs, FDI64x2Neg,
+		FDI64x2ExtendLowI32x4S, FDI64x2ExtendHighI32x4S, FDI64x2ExtendLowI32x4U, FDI64x2ExtendHighI32x4U,
+		FDF32x4Abs, FDF32x4Neg, FDF32x4Sqrt,
+		FDF64x2Abs, FDF64x2Neg, FDF64x2Sqrt,
+		FDI32x4TruncSatF32x4S, FDI32x4TruncSatF32x4U, FDF32x4ConvertI32x4S, FDF32x4ConvertI32x4U,
+		FDI32x4TruncSatF64x2SZero, FDI32x4TruncSatF64x2UZero, FDF64x2ConvertLowI32x4S, FDF64x2ConvertLowI32x4U,
+	} {
+		fdSigs[op] = opSig{in: v128, out: v128}
+	}
+
+	for _, op := range []int{
+		FDV128AnyTrue,
+		FDI8x16AllTrue, FDI8x16Bitmask,
+		FDI16x8AllTrue, FDI16x8Bitmask,
+		FDI32x4AllTrue, FDI32x4Bitmask,
+		FDI64x2AllTrue, FDI64x2Bitmask,
+	} {
+		fdSigs[op] = opSig{in: v128, out: i32}
+	}
+
+	for _, op := range []int{
+		FDI8x16Shl, FDI8x16ShrS, FDI8x16ShrU,
+		FDI16x8Shl, FDI16x8ShrS, FDI16x8ShrU,
+		FDI32x4Shl, FDI32x4ShrS, FDI32x4ShrU,
+		FDI64x2Shl, FDI64x2ShrS, FDI64x2ShrU,
+	} {
+		fdSigs[op] = opSig{in: ResultType{V128, I32}, out: v128}
+	}
+
+	fdSigs[FDV128Bitselect] = opSig{in: ResultType{V128, V128, V128}, out: v128}
+
+	// atomics
+
+	atomic := []struct {
+		tp    Type
+		align int
+	}{{I32, 2}, {I64, 3}, {I32, 0}, {I32, 1}, {I64, 0}, {I64, 1}, {I64, 2}}
+
+	mem(feSigs[:], FEMemoryAtomicNotify, i32i32, i32, 2)
+	mem(feSigs[:], FEMemoryAtomicWait32, ResultType{I32, I32, I64}, i32, 2)
+	mem(feSigs[:], FEMemoryAtomicWait64, ResultType{I32, I64, I64}, i32, 3)
+
+	for j, x := range atomic {
+		mem(feSigs[:], FEI32AtomicLoad+j, i32, singleTypes[x.tp], x.align)
+		mem(feSigs[:], FEI32AtomicStore+j, ResultType{I32, x.tp}, nil, x.align)
+
+		for op := FEI32AtomicRmwAdd; op < FEI32AtomicRmwCmpxchg; op += len(atomic) {
+			mem(feSigs[:], op+j, ResultType{I32, x.tp}, singleTypes[x.tp], x.align)
+		}
+
+		mem(feSigs[:], FEI32AtomicRmwCmpxchg+j, ResultType{I32, x.tp, x.tp}, singleTypes[x.tp], x.align)
+	}
+}
@@ -0,0 +1,180 @@
+package wasm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validModule() *Module {
+	m := &Module{
+		Version: 1,
+		Type: []FuncType{
+			{Params: ResultType{I32}, Result: ResultType{I32}},
+			{},
+		},
+		Function: []Index{0, 1},
+		Table:    []Table{{Type: FuncRef, Limits: Limits{Lo: 1, Hi: -1}}},
+		Memory:   []Limits{{Lo: 1, Hi: 2}},
+		Global:   []Global{{Type: I32, Mut: 1, Expr: Code{I32Const, 0, End}}},
+		Export:   []Export{{Name: []byte("f"), ExportType: ExternFunc, Index: 0}},
+		Element:  []Element{{Mode: ElemActive, Type: FuncRef, Expr: Code{I32Const, 0, End}, Funcs: []Index{0}}},
+		Data:     []Data{{Mode: DataActive, Expr: Code{I32Const, 0, End}, Init: []byte("a")}},
+		Code: []Code{
+			{
+				0,
+				Block, I32,
+				LocalGet, 0,
+				LocalGet, 0,
+				BrIf, 0,
+				I32Load, 2, 0,
+				End,
+				End,
+			},
+			{
+				0,
+				I32Const, 0,
+				I32Const, 0,
+				CallIndir, 0, 0,
+				Drop,
+				RefFunc, 0,
+				Drop,
+				Unreachable,
+				I64Add,
+				Drop,
+				End,
+			},
+		},
+	}
+
+	return m
+}
+
+func TestValidate(tb *testing.T) {
+	m := validModule()
+
+	err := Validate(m)
+	require.NoError(tb, err)
+
+	m.Global[0].Mut = 0
+	m.Data[0].Expr = Code{GlobalGet, 0, End}
+	require.NoError(tb, Validate(m))
+
+	// zero element type is funcref as in the Encoder
+	m.Element = append(m.Element, Element{Mode: ElemPassive, Init: []Code{{RefFunc, 0, End}}})
+	require.NoError(tb, Validate(m))
+
+	var e Encoder
+	var d Decoder
+
+	el, _, err := d.Element(e.Element(nil, m.Element[1]), 0, Element{})
+	require.NoError(tb, err)
+	assert.Equal(tb, Type(FuncRef), el.Type)
+}
+
+func TestValidateErrors(tb *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		modify  func(m *Module)
+		section byte
+		index   Index
+		offset  int
+		err     string
+	}{
+		{"result", func(m *Module) {
+			m.Code[0] = Code{0, I64Const, 0, End}
+		}, CodeSection, 0, 3, "expected i32, got i64"},
+		{"empty", func(m *Module) {
+			m.Code[1] = Code{0, I32Const, 0, I32Add, Drop, End}
+		}, CodeSection, 1, 3, "expected i32, stack is empty"},
+		{"label", func(m *Module) {
+			m.Code[1] = Code{0, Block, 0x40, Br, 2, End, End}
+		}, CodeSection, 1, 3, "unknown label 2"},
+		{"no_end", func(m *Module) {
+			m.Code[1] = Code{0, Nop}
+		}, CodeSection, 1, 2, "unexpected end of function"},
+		{"after_end", func(m *Module) {
+			m.Code[1] = Code{0, End, Nop}
+		}, CodeSection, 1, 2, "instructions after function end"},
+		{"align", func(m *Module) {
+			m.Code[0] = Code{0, LocalGet, 0, I32Load, 3, 0, End}
+		}, CodeSection, 0, 3, "invalid alignment"},
+		{"immutable", func(m *Module) {
+			m.Global[0].Mut = 0
+			m.Code[1] = Code{0, I32Const, 0, GlobalSet, 0, End}
+		}, CodeSection, 1, 3, "global 0 is immutable"},
+		{"ref_func", func(m *Module) {
+			m.Code[1] = Code{0, RefFunc, 1, Drop, End}
+		}, CodeSection, 1, 1, "undeclared function reference 1"},
+		{"data_count", func(m *Module) {
+			m.Code[1] = Code{0, FCExt, FCDataDrop, 0, End}
+		}, CodeSection, 1, 1, "data count section required"},
+		{"func_count", func(m *Module) {
+			m.Function = m.Function[:1]
+		}, FunctionSection, -1, -1, "inconsistent lengths"},
+		{"type_index", func(m *Module) {
+			m.Function[1] = 5
+		}, FunctionSection, 1, -1, "unknown type 5"},
+		{"export_name", func(m *Module) {
+			m.Export = append(m.Export, Export{Name: []byte("f"), ExportType: ExternMemory})
+		}, ExportSection, 1, -1, "duplicate export name"},
+		{"export_kind", func(m *Module) {
+			m.Export[0].ExportType = 7
+		}, ExportSection, 0, -1, "invalid export kind"},
+		{"const_expr", func(m *Module) {
+			m.Global[0].Expr = Code{I32Const, 0, I32Const, 1, I32Add, End}
+		}, GlobalSection, 0, 4, "constant expression required"},
+		{"global_get", func(m *Module) {
+			m.Global[0].Expr = Code{GlobalGet, 0, End}
+		}, GlobalSection, 0, 0, "unknown global 0"},
+		{"mutable_offset", func(m *Module) {
+			m.Data[0].Expr = Code{GlobalGet, 0, End}
+		}, DataSection, 0, 0, "global 0 is mutable"},
+		{"limits", func(m *Module) {
+			m.Memory[0] = Limits{Lo: 3, Hi: 2}
+		}, MemorySection, 0, -1, "minimum must not be larger than maximum"},
+		{"elem_type", func(m *Module) {
+			m.Table[0].Type = ExternRef
+		}, ElementSection, 0, -1, "funcref element segment for externref table"},
+		{"start", func(m *Module) {
+			m.Start = 0
+			m.HasStart = true
+		}, StartSection, -1, -1, "start function must have no params and results"},
+	} {
+		tb.Run(tc.name, func(tb *testing.T) {
+			m := validModule()
+			tc.modify(m)
+
+			err := Validate(m)
+
+			var verr ValidationError
+			require.True(tb, errors.As(err, &verr), "error: %v", err)
+
+			assert.Equal(tb, SectionName(tc.section), SectionName(verr.Section))
+			assert.Equal(tb, tc.index, verr.Index)
+			assert.Equal(tb, tc.offset, verr.Offset)
+			assert.Contains(tb, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestValidatorErrors(tb *testing.T) {
+	m := validModule()
+	m.Code[0] = Code{0, End}
+	m.Code[1] = Code{0, I32Const, 0, End}
+	m.Export[0].Index = 3
+
+	var v Validator
+
+	errs := v.Errors(m)
+	require.Len(tb, errs, 3)
+
+	assert.Equal(tb, "export section: export 0: unknown function 3", errs[0].Error())
+	assert.Equal(tb, "code section: func 0: offset 0x1: type mismatch: expected i32, stack is empty", errs[1].Error())
+	assert.Equal(tb, "code section: func 1: offset 0x3: type mismatch: 1 extra values at the end of block", errs[2].Error())
+
+	m = validModule()
+	assert.Empty(tb, v.Errors(m))
+}
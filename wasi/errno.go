@@ -0,0 +1,169 @@
+package wasi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Errno is a WASI error code.
+type Errno uint16
+
+// Error codes.
+const (
+	ESUCCESS Errno = iota
+	E2BIG
+	EACCES
+	EADDRINUSE
+	EADDRNOTAVAIL
+	EAFNOSUPPORT
+	EAGAIN
+	EALREADY
+	EBADF
+	EBADMSG
+	EBUSY
+	ECANCELED
+	ECHILD
+	ECONNABORTED
+	ECONNREFUSED
+	ECONNRESET
+	EDEADLK
+	EDESTADDRREQ
+	EDOM
+	EDQUOT
+	EEXIST
+	EFAULT
+	EFBIG
+	EHOSTUNREACH
+	EIDRM
+	EILSEQ
+	EINPROGRESS
+	EINTR
+	EINVAL
+	EIO
+	EISCONN
+	EISDIR
+	ELOOP
+	EMFILE
+	EMLINK
+	EMSGSIZE
+	EMULTIHOP
+	ENAMETOOLONG
+	ENETDOWN
+	ENETRESET
+	ENETUNREACH
+	ENFILE
+	ENOBUFS
+	ENODEV
+	ENOENT
+	ENOEXEC
+	ENOLCK
+	ENOLINK
+	ENOMEM
+	ENOMSG
+	ENOPROTOOPT
+	ENOSPC
+	ENOSYS
+	ENOTCONN
+	ENOTDIR
+	ENOTEMPTY
+	ENOTRECOVERABLE
+	ENOTSOCK
+	ENOTSUP
+	ENOTTY
+	ENXIO
+	EOVERFLOW
+	EOWNERDEAD
+	EPERM
+	EPIPE
+	EPROTO
+	EPROTONOSUPPORT
+	EPROTOTYPE
+	ERANGE
+	EROFS
+	ESPIPE
+	ESRCH
+	ESTALE
+	ETIMEDOUT
+	ETXTBSY
+	EXDEV
+	ENOTCAPABLE
+)
+
+var errnoNames = map[Errno]string{
+	ESUCCESS:     "success",
+	E2BIG:        "argument list too long",
+	EACCES:       "permission denied",
+	EAGAIN:       "resource unavailable, try again",
+	EBADF:        "bad file descriptor",
+	ECANCELED:    "operation canceled",
+	EEXIST:       "file exists",
+	EFAULT:       "bad address",
+	EFBIG:        "file too large",
+	EINVAL:       "invalid argument",
+	EIO:          "i/o error",
+	EISDIR:       "is a directory",
+	ELOOP:        "too many levels of symbolic links",
+	ENAMETOOLONG: "filename too long",
+	ENOENT:       "no such file or directory",
+	ENOSPC:       "no space left on device",
+	ENOSYS:       "function not supported",
+	ENOTDIR:      "not a directory",
+	ENOTEMPTY:    "directory not empty",
+	ENOTSUP:      "not supported",
+	EPERM:        "operation not permitted",
+	EROFS:        "read-only file system",
+	ESPIPE:       "invalid seek",
+	EXDEV:        "cross-device link",
+	ENOTCAPABLE:  "capabilities insufficient",
+}
+
+func (e Errno) Error() string {
+	if n, ok := errnoNames[e]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("errno %d", uint16(e))
+}
+
+// Is makes Errno match the corresponding fs errors.
+func (e Errno) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e == ENOENT
+	case fs.ErrExist:
+		return e == EEXIST
+	case fs.ErrPermission:
+		return e == EPERM || e == EACCES || e == ENOTCAPABLE
+	case fs.ErrInvalid:
+		return e == EINVAL
+	case fs.ErrClosed:
+		return e == EBADF
+	default:
+		return false
+	}
+}
+
+// errno converts Go error to Errno.
+func errno(err error) Errno {
+	var e Errno
+
+	switch {
+	case err == nil:
+		return ESUCCESS
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, fs.ErrNotExist):
+		return ENOENT
+	case errors.Is(err, fs.ErrExist):
+		return EEXIST
+	case errors.Is(err, fs.ErrPermission):
+		return EPERM
+	case errors.Is(err, fs.ErrInvalid):
+		return EINVAL
+	case errors.Is(err, fs.ErrClosed):
+		return EBADF
+	default:
+		return EIO
+	}
+}
@@ -0,0 +1,822 @@
+package wasi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+type (
+	// WriteFS is a file system that can be modified by the module.
+	// Flags are os.O_* flags as for os.OpenFile.
+	// Files opened for writing are expected to implement io.Writer,
+	// and optionally io.Seeker, io.ReaderAt, io.WriterAt and Truncate(size int64) error.
+	WriteFS interface {
+		fs.FS
+
+		OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+		Mkdir(name string, perm fs.FileMode) error
+		Remove(name string) error
+		Rename(oldname, newname string) error
+	}
+
+	file struct {
+		path    string // in Host.FS, "." for the root
+		preopen string // preopened directory name
+		dir     bool
+		stdio   bool
+		append  bool
+
+		f fs.File
+		r io.Reader
+		w io.Writer
+
+		entries []dirent // fd_readdir cache
+	}
+
+	dirent struct {
+		name string
+		tp   uint8
+	}
+)
+
+// File types.
+const (
+	fileUnknown = iota
+	fileBlockDevice
+	fileCharDevice
+	fileDirectory
+	fileRegular
+	fileSocketDgram
+	fileSocketStream
+	fileSymlink
+)
+
+// Flags.
+const (
+	fdflagAppend = 1 << 0
+
+	oflagCreat     = 1 << 0
+	oflagDirectory = 1 << 1
+	oflagExcl      = 1 << 2
+	oflagTrunc     = 1 << 3
+
+	rightFdRead  = 1 << 1
+	rightFdWrite = 1 << 6
+	rightsAll    = 1<<29 - 1
+)
+
+const (
+	fdstatSize   = 24
+	filestatSize = 64
+	direntSize   = 24
+)
+
+func (h *Host) file(fd uint32) (*file, Errno) {
+	if fd >= uint32(len(h.fds)) || h.fds[fd] == nil {
+		return nil, EBADF
+	}
+
+	return h.fds[fd], ESUCCESS
+}
+
+func (h *Host) dir(fd uint32) (*file, Errno) {
+	f, e := h.file(fd)
+	if e != ESUCCESS {
+		return nil, e
+	}
+
+	if !f.dir {
+		return nil, ENOTDIR
+	}
+
+	return f, ESUCCESS
+}
+
+func (h *Host) alloc(f *file) uint32 {
+	for fd := 3; fd < len(h.fds); fd++ {
+		if h.fds[fd] == nil {
+			h.fds[fd] = f
+			return uint32(fd)
+		}
+	}
+
+	h.fds = append(h.fds, f)
+
+	return uint32(len(h.fds) - 1)
+}
+
+func (h *Host) writeFS() (WriteFS, Errno) {
+	w, ok := h.FS.(WriteFS)
+	if !ok {
+		return nil, EROFS
+	}
+
+	return w, ESUCCESS
+}
+
+// resolve returns the FS path of the path relative to the directory fd.
+// Paths escaping the directory are not allowed.
+func (h *Host) resolve(m mem, fd, p, n uint32) (string, Errno) {
+	d, e := h.dir(fd)
+	if e != ESUCCESS {
+		return "", e
+	}
+
+	name, e := m.str(p, n)
+	if e != ESUCCESS {
+		return "", e
+	}
+
+	if name == "" {
+		return "", ENOENT
+	}
+
+	if strings.HasPrefix(name, "/") || strings.IndexByte(name, 0) >= 0 {
+		return "", ENOTCAPABLE
+	}
+
+	name = path.Join(d.path, name)
+
+	if !fs.ValidPath(name) {
+		return "", ENOTCAPABLE
+	}
+
+	return name, ESUCCESS
+}
+
+func (h *Host) fdClose(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	h.fds[st[0]] = nil
+
+	if f.f != nil {
+		return errno(f.f.Close())
+	}
+
+	return ESUCCESS
+}
+
+func (h *Host) fdRenumber(m mem, st []uint64) Errno {
+	from, to := uint32(st[0]), uint32(st[1])
+
+	f, e := h.file(from)
+	if e != ESUCCESS {
+		return e
+	}
+
+	old, e := h.file(to)
+	if e != ESUCCESS {
+		return e
+	}
+
+	if from == to {
+		return ESUCCESS
+	}
+
+	if old.f != nil {
+		_ = old.f.Close()
+	}
+
+	h.fds[to], h.fds[from] = f, nil
+
+	return ESUCCESS
+}
+
+func (h *Host) fdSync(m mem, st []uint64) Errno {
+	_, e := h.file(uint32(st[0]))
+	return e
+}
+
+func (h *Host) fdAdvise(m mem, st []uint64) Errno {
+	_, e := h.file(uint32(st[0]))
+	return e
+}
+
+func (h *Host) fdFdstatGet(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	b, e := m.slice(uint32(st[1]), fdstatSize)
+	if e != ESUCCESS {
+		return e
+	}
+
+	clear(b)
+
+	switch {
+	case f.stdio:
+		b[0] = fileCharDevice
+	case f.dir:
+		b[0] = fileDirectory
+	default:
+		b[0] = fileRegular
+	}
+
+	if f.append {
+		le.PutUint16(b[2:], fdflagAppend)
+	}
+
+	le.PutUint64(b[8:], rightsAll)
+	le.PutUint64(b[16:], rightsAll)
+
+	return ESUCCESS
+}
+
+func (h *Host) fdFdstatSetFlags(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	f.append = st[1]&fdflagAppend != 0
+
+	return ESUCCESS
+}
+
+func (h *Host) fdFilestatGet(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	if f.stdio {
+		b, e := m.slice(uint32(st[1]), filestatSize)
+		if e != ESUCCESS {
+			return e
+		}
+
+		clear(b)
+		b[16] = fileCharDevice
+
+		return ESUCCESS
+	}
+
+	var fi fs.FileInfo
+	var err error
+
+	if f.f != nil {
+		fi, err = f.f.Stat()
+	} else {
+		fi, err = fs.Stat(h.FS, f.path)
+	}
+
+	if err != nil {
+		return errno(err)
+	}
+
+	return m.putFilestat(uint32(st[1]), fi)
+}
+
+func (h *Host) fdFilestatSetSize(m mem, st []uint64) Errno {
+	f, e := h.regular(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	t, ok := f.f.(interface{ Truncate(int64) error })
+	if !ok {
+		return EBADF
+	}
+
+	return errno(t.Truncate(int64(st[1])))
+}
+
+// regular returns an opened regular file.
+func (h *Host) regular(fd uint32) (*file, Errno) {
+	f, e := h.file(fd)
+	if e != ESUCCESS {
+		return nil, e
+	}
+
+	switch {
+	case f.stdio:
+		return nil, ESPIPE
+	case f.dir:
+		return nil, EISDIR
+	}
+
+	return f, ESUCCESS
+}
+
+func (h *Host) fdPrestatGet(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	if f.preopen == "" {
+		return EBADF
+	}
+
+	b, e := m.slice(uint32(st[1]), 8)
+	if e != ESUCCESS {
+		return e
+	}
+
+	clear(b)
+	le.PutUint32(b[4:], uint32(len(f.preopen)))
+
+	return ESUCCESS
+}
+
+func (h *Host) fdPrestatDirName(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	if f.preopen == "" {
+		return EBADF
+	}
+
+	if uint32(st[2]) < uint32(len(f.preopen)) {
+		return ENAMETOOLONG
+	}
+
+	b, e := m.slice(uint32(st[1]), uint32(len(f.preopen)))
+	if e != ESUCCESS {
+		return e
+	}
+
+	copy(b, f.preopen)
+
+	return ESUCCESS
+}
+
+func (h *Host) fdRead(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	iovs, e := m.iovecs(uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	var r io.Reader
+
+	switch {
+	case f.dir:
+		return EISDIR
+	case f.stdio:
+		r = f.r
+	default:
+		r = f.f
+	}
+
+	n := 0
+
+	if r != nil {
+		n, e = readv(r, iovs)
+		if e != ESUCCESS {
+			return e
+		}
+	}
+
+	return m.putU32(uint32(st[3]), uint32(n))
+}
+
+func (h *Host) fdPread(m mem, st []uint64) Errno {
+	f, e := h.regular(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	iovs, e := m.iovecs(uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	ra, ok := f.f.(io.ReaderAt)
+	if !ok {
+		return ESPIPE
+	}
+
+	n, e := readv(io.NewSectionReader(ra, int64(st[3]), 1<<63-1-int64(st[3])), iovs)
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU32(uint32(st[4]), uint32(n))
+}
+
+func readv(r io.Reader, iovs [][]byte) (n int, e Errno) {
+	for _, b := range iovs {
+		m, err := r.Read(b)
+		n += m
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			if n != 0 {
+				break
+			}
+
+			return 0, errno(err)
+		}
+
+		if m < len(b) {
+			break
+		}
+	}
+
+	return n, ESUCCESS
+}
+
+func (h *Host) fdWrite(m mem, st []uint64) Errno {
+	f, e := h.file(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	iovs, e := m.iovecs(uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	var w io.Writer
+
+	switch {
+	case f.dir:
+		return EISDIR
+	case f.stdio:
+		w = f.w
+		if w == nil {
+			w = io.Discard
+		}
+	default:
+		var ok bool
+
+		w, ok = f.f.(io.Writer)
+		if !ok {
+			return EBADF
+		}
+
+		if s, ok := f.f.(io.Seeker); ok && f.append {
+			if _, err := s.Seek(0, io.SeekEnd); err != nil {
+				return errno(err)
+			}
+		}
+	}
+
+	n, e := writev(w, iovs)
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU32(uint32(st[3]), uint32(n))
+}
+
+func (h *Host) fdPwrite(m mem, st []uint64) Errno {
+	f, e := h.regular(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	iovs, e := m.iovecs(uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	wa, ok := f.f.(io.WriterAt)
+	if !ok {
+		return ESPIPE
+	}
+
+	n, e := writev(io.NewOffsetWriter(wa, int64(st[3])), iovs)
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU32(uint32(st[4]), uint32(n))
+}
+
+func writev(w io.Writer, iovs [][]byte) (n int, e Errno) {
+	for _, b := range iovs {
+		m, err := w.Write(b)
+		n += m
+
+		if err != nil {
+			if n != 0 {
+				break
+			}
+
+			return 0, errno(err)
+		}
+	}
+
+	return n, ESUCCESS
+}
+
+func (h *Host) fdSeek(m mem, st []uint64) Errno {
+	off, e := h.seek(uint32(st[0]), int64(st[1]), int(uint32(st[2])))
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU64(uint32(st[3]), uint64(off))
+}
+
+func (h *Host) fdTell(m mem, st []uint64) Errno {
+	off, e := h.seek(uint32(st[0]), 0, io.SeekCurrent)
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU64(uint32(st[1]), uint64(off))
+}
+
+func (h *Host) seek(fd uint32, off int64, whence int) (int64, Errno) {
+	f, e := h.regular(fd)
+	if e != ESUCCESS {
+		return 0, e
+	}
+
+	if whence > io.SeekEnd {
+		return 0, EINVAL
+	}
+
+	s, ok := f.f.(io.Seeker)
+	if !ok {
+		return 0, ESPIPE
+	}
+
+	off, err := s.Seek(off, whence)
+
+	return off, errno(err)
+}
+
+// fdReaddir writes directory entries starting from the cookie.
+// The last entry is truncated if it doesn't fit,
+// the module is expected to retry with a bigger buffer.
+func (h *Host) fdReaddir(m mem, st []uint64) Errno {
+	f, e := h.dir(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	buf, e := m.slice(uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	cookie := st[3]
+
+	if cookie == 0 || f.entries == nil {
+		list, err := fs.ReadDir(h.FS, f.path)
+		if err != nil {
+			return errno(err)
+		}
+
+		f.entries = append(f.entries[:0], dirent{".", fileDirectory}, dirent{"..", fileDirectory})
+
+		for _, d := range list {
+			f.entries = append(f.entries, dirent{d.Name(), fileType(d.Type())})
+		}
+	}
+
+	if cookie > uint64(len(f.entries)) {
+		return EINVAL
+	}
+
+	used := 0
+
+	for i := int(cookie); i < len(f.entries) && used < len(buf); i++ {
+		d := f.entries[i]
+
+		var hdr [direntSize]byte
+
+		le.PutUint64(hdr[0:], uint64(i+1))
+		le.PutUint32(hdr[16:], uint32(len(d.name)))
+		hdr[20] = d.tp
+
+		used += copy(buf[used:], hdr[:])
+		used += copy(buf[used:], d.name)
+	}
+
+	return m.putU32(uint32(st[4]), uint32(used))
+}
+
+func (h *Host) pathOpen(m mem, st []uint64) Errno {
+	name, e := h.resolve(m, uint32(st[0]), uint32(st[2]), uint32(st[3]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	oflags, rights, fdflags := st[4], st[5], st[7]
+
+	read := rights&rightFdRead != 0
+	write := rights&rightFdWrite != 0 || oflags&oflagTrunc != 0
+
+	var ff fs.File
+	var err error
+
+	if write || oflags&oflagCreat != 0 {
+		w, e := h.writeFS()
+		if e != ESUCCESS {
+			return e
+		}
+
+		flag := os.O_RDONLY
+
+		switch {
+		case read && write:
+			flag = os.O_RDWR
+		case write:
+			flag = os.O_WRONLY
+		}
+
+		if oflags&oflagCreat != 0 {
+			flag |= os.O_CREATE
+		}
+		if oflags&oflagExcl != 0 {
+			flag |= os.O_EXCL
+		}
+		if oflags&oflagTrunc != 0 {
+			flag |= os.O_TRUNC
+		}
+		if fdflags&fdflagAppend != 0 {
+			flag |= os.O_APPEND
+		}
+
+		ff, err = w.OpenFile(name, flag, 0o644)
+	} else {
+		ff, err = h.FS.Open(name)
+	}
+
+	if err != nil {
+		return errno(err)
+	}
+
+	fi, err := ff.Stat()
+	if err != nil {
+		_ = ff.Close()
+		return errno(err)
+	}
+
+	f := &file{path: name, append: fdflags&fdflagAppend != 0}
+
+	switch {
+	case fi.IsDir() && write:
+		_ = ff.Close()
+		return EISDIR
+	case fi.IsDir():
+		_ = ff.Close()
+		f.dir = true
+	case oflags&oflagDirectory != 0:
+		_ = ff.Close()
+		return ENOTDIR
+	default:
+		f.f = ff
+	}
+
+	fd := h.alloc(f)
+
+	return m.putU32(uint32(st[8]), fd)
+}
+
+func (h *Host) pathFilestatGet(m mem, st []uint64) Errno {
+	name, e := h.resolve(m, uint32(st[0]), uint32(st[2]), uint32(st[3]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	fi, err := fs.Stat(h.FS, name)
+	if err != nil {
+		return errno(err)
+	}
+
+	return m.putFilestat(uint32(st[4]), fi)
+}
+
+// pathReadlink reports every existing file is not a symlink.
+func (h *Host) pathReadlink(m mem, st []uint64) Errno {
+	name, e := h.resolve(m, uint32(st[0]), uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	_, err := fs.Stat(h.FS, name)
+	if err != nil {
+		return errno(err)
+	}
+
+	return EINVAL
+}
+
+func (h *Host) pathCreateDirectory(m mem, st []uint64) Errno {
+	name, e := h.resolve(m, uint32(st[0]), uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	w, e := h.writeFS()
+	if e != ESUCCESS {
+		return e
+	}
+
+	return errno(w.Mkdir(name, 0o755))
+}
+
+func (h *Host) pathRemoveDirectory(m mem, st []uint64) Errno {
+	return h.remove(m, st, true)
+}
+
+func (h *Host) pathUnlinkFile(m mem, st []uint64) Errno {
+	return h.remove(m, st, false)
+}
+
+func (h *Host) remove(m mem, st []uint64, dir bool) Errno {
+	name, e := h.resolve(m, uint32(st[0]), uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	w, e := h.writeFS()
+	if e != ESUCCESS {
+		return e
+	}
+
+	fi, err := fs.Stat(w, name)
+	if err != nil {
+		return errno(err)
+	}
+
+	switch {
+	case dir && !fi.IsDir():
+		return ENOTDIR
+	case !dir && fi.IsDir():
+		return EISDIR
+	}
+
+	return errno(w.Remove(name))
+}
+
+func (h *Host) pathRename(m mem, st []uint64) Errno {
+	oldname, e := h.resolve(m, uint32(st[0]), uint32(st[1]), uint32(st[2]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	newname, e := h.resolve(m, uint32(st[3]), uint32(st[4]), uint32(st[5]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	w, e := h.writeFS()
+	if e != ESUCCESS {
+		return e
+	}
+
+	return errno(w.Rename(oldname, newname))
+}
+
+func (m mem) putFilestat(p uint32, fi fs.FileInfo) Errno {
+	b, e := m.slice(p, filestatSize)
+	if e != ESUCCESS {
+		return e
+	}
+
+	clear(b)
+
+	t := uint64(fi.ModTime().UnixNano())
+
+	b[16] = fileType(fi.Mode())
+	le.PutUint64(b[24:], 1) // nlink
+	le.PutUint64(b[32:], uint64(fi.Size()))
+	le.PutUint64(b[40:], t) // atim
+	le.PutUint64(b[48:], t) // mtim
+	le.PutUint64(b[56:], t) // ctim
+
+	return ESUCCESS
+}
+
+func fileType(mode fs.FileMode) uint8 {
+	switch {
+	case mode.IsDir():
+		return fileDirectory
+	case mode.IsRegular():
+		return fileRegular
+	case mode&fs.ModeSymlink != 0:
+		return fileSymlink
+	case mode&fs.ModeCharDevice != 0:
+		return fileCharDevice
+	case mode&fs.ModeDevice != 0:
+		return fileBlockDevice
+	case mode&fs.ModeSocket != 0:
+		return fileSocketStream
+	default:
+		return fileUnknown
+	}
+}
@@ -0,0 +1,384 @@
+package wasi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// MemFS is an in-memory writable file system.
+	// Zero value is an empty file system ready to use.
+	// It's not safe for concurrent use.
+	MemFS struct {
+		root memNode
+	}
+
+	memNode struct {
+		mode  fs.FileMode
+		mtime time.Time
+		data  []byte
+		files map[string]*memNode
+	}
+
+	memFile struct {
+		name string
+		n    *memNode
+		flag int
+		off  int64
+
+		dir     []fs.DirEntry // ReadDir state
+		dirRead bool
+		closed  bool
+	}
+
+	memInfo struct {
+		name string
+		n    *memNode
+	}
+)
+
+var (
+	_ WriteFS        = &MemFS{}
+	_ fs.ReadDirFile = &memFile{}
+)
+
+// WriteFile creates or truncates the file and writes data to it.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, _ = f.(*memFile).Write(data)
+
+	return f.Close()
+}
+
+// Open implements fs.FS.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the file as os.OpenFile does.
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	dir, base, n, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	switch {
+	case n != nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, pathError("open", name, EEXIST)
+	case n != nil && n.mode.IsDir() && write:
+		return nil, pathError("open", name, EISDIR)
+	case n == nil && flag&os.O_CREATE == 0:
+		return nil, pathError("open", name, ENOENT)
+	case n == nil:
+		n = &memNode{mode: perm & fs.ModePerm, mtime: time.Now()}
+		dir.files[base] = n
+	}
+
+	if flag&os.O_TRUNC != 0 && write {
+		n.data = n.data[:0]
+		n.mtime = time.Now()
+	}
+
+	return &memFile{name: path.Base(name), n: n, flag: flag}, nil
+}
+
+// Mkdir creates a directory.
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	dir, base, n, err := m.lookup("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	if n != nil {
+		return pathError("mkdir", name, EEXIST)
+	}
+
+	dir.files[base] = &memNode{mode: fs.ModeDir | perm&fs.ModePerm, mtime: time.Now(), files: map[string]*memNode{}}
+
+	return nil
+}
+
+// Remove removes a file or an empty directory.
+func (m *MemFS) Remove(name string) error {
+	dir, base, n, err := m.lookup("remove", name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case n == nil:
+		return pathError("remove", name, ENOENT)
+	case dir == nil:
+		return pathError("remove", name, EBUSY)
+	case len(n.files) != 0:
+		return pathError("remove", name, ENOTEMPTY)
+	}
+
+	delete(dir.files, base)
+
+	return nil
+}
+
+// Rename moves the file or directory replacing the destination if it exists.
+func (m *MemFS) Rename(oldname, newname string) error {
+	odir, obase, o, err := m.lookup("rename", oldname)
+	if err != nil {
+		return err
+	}
+
+	ndir, nbase, n, err := m.lookup("rename", newname)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case o == nil:
+		return pathError("rename", oldname, ENOENT)
+	case odir == nil || ndir == nil:
+		return pathError("rename", oldname, EBUSY)
+	case o.mode.IsDir() && strings.HasPrefix(newname, oldname+"/"):
+		return pathError("rename", oldname, EINVAL)
+	case n == nil || n == o:
+	case o.mode.IsDir() && !n.mode.IsDir():
+		return pathError("rename", newname, ENOTDIR)
+	case !o.mode.IsDir() && n.mode.IsDir():
+		return pathError("rename", newname, EISDIR)
+	case len(n.files) != 0:
+		return pathError("rename", newname, ENOTEMPTY)
+	}
+
+	delete(odir.files, obase)
+	ndir.files[nbase] = o
+
+	return nil
+}
+
+// lookup finds the parent directory of the name and the node itself, which may be nil.
+// The root has no parent.
+func (m *MemFS) lookup(op, name string) (dir *memNode, base string, n *memNode, err error) {
+	if m.root.files == nil {
+		m.root = memNode{mode: fs.ModeDir | 0o755, mtime: time.Now(), files: map[string]*memNode{}}
+	}
+
+	if !fs.ValidPath(name) {
+		return nil, "", nil, pathError(op, name, EINVAL)
+	}
+
+	if name == "." {
+		return nil, "", &m.root, nil
+	}
+
+	dir = &m.root
+	elems := strings.Split(name, "/")
+
+	for i, e := range elems[:len(elems)-1] {
+		dir = dir.files[e]
+
+		switch {
+		case dir == nil:
+			return nil, "", nil, pathError(op, name, ENOENT)
+		case !dir.mode.IsDir():
+			return nil, "", nil, pathError(op, path.Join(elems[:i+1]...), ENOTDIR)
+		}
+	}
+
+	base = elems[len(elems)-1]
+
+	return dir, base, dir.files[base], nil
+}
+
+func pathError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return memInfo{name: f.name, n: f.n}, nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+
+	if err == io.EOF && n != 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.check("read", f.flag&(os.O_WRONLY|os.O_RDWR) != os.O_WRONLY); err != nil {
+		return 0, err
+	}
+
+	if off < 0 {
+		return 0, pathError("read", f.name, EINVAL)
+	}
+
+	if off >= int64(len(f.n.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.n.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.flag&os.O_APPEND != 0 {
+		f.off = int64(len(f.n.data))
+	}
+
+	n, err := f.WriteAt(p, f.off)
+	f.off += int64(n)
+
+	return n, err
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.check("write", f.flag&(os.O_WRONLY|os.O_RDWR) != 0); err != nil {
+		return 0, err
+	}
+
+	if off < 0 {
+		return 0, pathError("write", f.name, EINVAL)
+	}
+
+	if end, size := int(off)+len(p), len(f.n.data); end > size {
+		f.n.data = slices.Grow(f.n.data, end-size)[:end]
+		clear(f.n.data[size:])
+	}
+
+	copy(f.n.data[off:], p)
+	f.n.mtime = time.Now()
+
+	return len(p), nil
+}
+
+func (f *memFile) Seek(off int64, whence int) (int64, error) {
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		off += f.off
+	case io.SeekEnd:
+		off += int64(len(f.n.data))
+	}
+
+	if off < 0 {
+		return 0, pathError("seek", f.name, EINVAL)
+	}
+
+	f.off = off
+
+	return off, nil
+}
+
+// Truncate changes the file size.
+func (f *memFile) Truncate(size int64) error {
+	if err := f.check("truncate", f.flag&(os.O_WRONLY|os.O_RDWR) != 0); err != nil {
+		return err
+	}
+
+	if size < 0 {
+		return pathError("truncate", f.name, EINVAL)
+	}
+
+	if int(size) <= len(f.n.data) {
+		f.n.data = f.n.data[:size]
+	} else {
+		f.n.data = append(f.n.data, make([]byte, int(size)-len(f.n.data))...)
+	}
+
+	f.n.mtime = time.Now()
+
+	return nil
+}
+
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if f.closed {
+		return nil, pathError("readdir", f.name, fs.ErrClosed)
+	}
+
+	if !f.n.mode.IsDir() {
+		return nil, pathError("readdir", f.name, ENOTDIR)
+	}
+
+	if !f.dirRead {
+		f.dirRead = true
+
+		for name, n := range f.n.files {
+			f.dir = append(f.dir, memInfo{name: name, n: n})
+		}
+
+		slices.SortFunc(f.dir, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+
+	if n <= 0 {
+		list := f.dir
+		f.dir = nil
+
+		return list, nil
+	}
+
+	if len(f.dir) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(f.dir))
+	list := f.dir[:n:n]
+	f.dir = f.dir[n:]
+
+	return list, nil
+}
+
+func (f *memFile) Close() error {
+	if f.closed {
+		return pathError("close", f.name, fs.ErrClosed)
+	}
+
+	f.closed = true
+
+	return nil
+}
+
+// check returns an error if the file is closed, is a directory or if the operation is not allowed.
+func (f *memFile) check(op string, allowed bool) error {
+	switch {
+	case f.closed:
+		return pathError(op, f.name, fs.ErrClosed)
+	case f.n.mode.IsDir():
+		return pathError(op, f.name, EISDIR)
+	case !allowed:
+		return pathError(op, f.name, EBADF)
+	}
+
+	return nil
+}
+
+func (i memInfo) Name() string               { return i.name }
+func (i memInfo) Size() int64                { return int64(len(i.n.data)) }
+func (i memInfo) Mode() fs.FileMode          { return i.n.mode }
+func (i memInfo) ModTime() time.Time         { return i.n.mtime }
+func (i memInfo) IsDir() bool                { return i.n.mode.IsDir() }
+func (i memInfo) Sys() any                   { return nil }
+func (i memInfo) Type() fs.FileMode          { return i.n.mode.Type() }
+func (i memInfo) Info() (fs.FileInfo, error) { return i, nil }
@@ -0,0 +1,463 @@
+// Package wasi implements WASI preview1 host functions for the interp package.
+//
+// Modules are sandboxed: they see only the arguments, environment,
+// standard streams and the file system given in the Host.
+package wasi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"nikand.dev/go/wasm"
+	"nikand.dev/go/wasm/interp"
+)
+
+type (
+	// Host is a WASI environment.
+	// The file system is preopened as "/" if set.
+	// It's writable if it implements WriteFS, MemFS does.
+	// Zero value is a module without arguments, environment, streams and files.
+	// Host may be used by a single instance at a time.
+	Host struct {
+		Args []string
+		Env  []string // KEY=value
+
+		Stdin  io.Reader
+		Stdout io.Writer
+		Stderr io.Writer
+
+		FS fs.FS
+
+		Now  func() time.Time // time.Now if nil
+		Rand io.Reader        // crypto/rand.Reader if nil
+
+		start time.Time
+		fds   []*file
+	}
+
+	// ExitError is returned from the module call when it called proc_exit.
+	// It's wrapped into interp.Trap, use errors.As to get it.
+	ExitError struct {
+		Code uint32
+	}
+
+	hostFunc struct {
+		name   string
+		params string // i for i32, I for i64
+		fn     func(h *Host, m mem, st []uint64) Errno
+	}
+
+	mem []byte
+)
+
+// Module is the WASI preview1 import module name.
+const Module = "wasi_snapshot_preview1"
+
+var le = binary.LittleEndian
+
+var funcs = []hostFunc{
+	{"args_get", "ii", (*Host).argsGet},
+	{"args_sizes_get", "ii", (*Host).argsSizesGet},
+	{"environ_get", "ii", (*Host).environGet},
+	{"environ_sizes_get", "ii", (*Host).environSizesGet},
+	{"clock_res_get", "ii", (*Host).clockResGet},
+	{"clock_time_get", "iIi", (*Host).clockTimeGet},
+	{"fd_advise", "iIIi", (*Host).fdAdvise},
+	{"fd_allocate", "iII", notSupported},
+	{"fd_close", "i", (*Host).fdClose},
+	{"fd_datasync", "i", (*Host).fdSync},
+	{"fd_fdstat_get", "ii", (*Host).fdFdstatGet},
+	{"fd_fdstat_set_flags", "ii", (*Host).fdFdstatSetFlags},
+	{"fd_fdstat_set_rights", "iII", notSupported},
+	{"fd_filestat_get", "ii", (*Host).fdFilestatGet},
+	{"fd_filestat_set_size", "iI", (*Host).fdFilestatSetSize},
+	{"fd_filestat_set_times", "iIIi", notSupported},
+	{"fd_pread", "iiiIi", (*Host).fdPread},
+	{"fd_prestat_get", "ii", (*Host).fdPrestatGet},
+	{"fd_prestat_dir_name", "iii", (*Host).fdPrestatDirName},
+	{"fd_pwrite", "iiiIi", (*Host).fdPwrite},
+	{"fd_read", "iiii", (*Host).fdRead},
+	{"fd_readdir", "iiiIi", (*Host).fdReaddir},
+	{"fd_renumber", "ii", (*Host).fdRenumber},
+	{"fd_seek", "iIii", (*Host).fdSeek},
+	{"fd_sync", "i", (*Host).fdSync},
+	{"fd_tell", "ii", (*Host).fdTell},
+	{"fd_write", "iiii", (*Host).fdWrite},
+	{"path_create_directory", "iii", (*Host).pathCreateDirectory},
+	{"path_filestat_get", "iiiii", (*Host).pathFilestatGet},
+	{"path_filestat_set_times", "iiiiIIi", notSupported},
+	{"path_link", "iiiiiii", notSupported},
+	{"path_open", "iiiiiIIii", (*Host).pathOpen},
+	{"path_readlink", "iiiiii", (*Host).pathReadlink},
+	{"path_remove_directory", "iii", (*Host).pathRemoveDirectory},
+	{"path_rename", "iiiiii", (*Host).pathRename},
+	{"path_symlink", "iiiii", notSupported},
+	{"path_unlink_file", "iii", (*Host).pathUnlinkFile},
+	{"poll_oneoff", "iiii", nil}, // defined in Define, it needs the call Context
+	{"proc_raise", "i", notSupported},
+	{"random_get", "ii", (*Host).randomGet},
+	{"sched_yield", "", (*Host).schedYield},
+	{"sock_accept", "iii", notSupported},
+	{"sock_recv", "iiiiii", notSupported},
+	{"sock_send", "iiiii", notSupported},
+	{"sock_shutdown", "ii", notSupported},
+}
+
+// Define defines WASI functions in the linker.
+// It resets the Host state: opened files are forgotten and monotonic clock is restarted.
+func (h *Host) Define(l *interp.Linker) {
+	h.reset()
+
+	for _, f := range funcs {
+		if f.fn == nil {
+			continue
+		}
+
+		l.DefineFunc(Module, f.name, wasm.FuncType{Params: params(f.params), Result: wasm.ResultType{wasm.I32}}, func(c *interp.Caller, st []uint64) error {
+			st[0] = uint64(f.fn(h, memory(c), st))
+
+			return nil
+		})
+	}
+
+	l.DefineFunc(Module, "poll_oneoff", wasm.FuncType{Params: params("iiii"), Result: wasm.ResultType{wasm.I32}}, func(c *interp.Caller, st []uint64) error {
+		ctx := c.Context()
+		st[0] = uint64(h.pollOneoff(ctx, memory(c), st))
+
+		return ctx.Err()
+	})
+
+	l.DefineFunc(Module, "proc_exit", wasm.FuncType{Params: params("i")}, func(c *interp.Caller, st []uint64) error {
+		return ExitError{Code: uint32(st[0])}
+	})
+}
+
+func (h *Host) reset() {
+	h.start = time.Now()
+	h.fds = []*file{
+		{stdio: true, r: h.Stdin},
+		{stdio: true, w: h.Stdout},
+		{stdio: true, w: h.Stderr},
+	}
+
+	if h.FS != nil {
+		h.fds = append(h.fds, &file{dir: true, path: ".", preopen: "/"})
+	}
+}
+
+func params(s string) wasm.ResultType {
+	tp := make(wasm.ResultType, len(s))
+
+	for i, c := range s {
+		if c == 'I' {
+			tp[i] = wasm.I64
+		} else {
+			tp[i] = wasm.I32
+		}
+	}
+
+	return tp
+}
+
+func memory(c *interp.Caller) mem {
+	m := c.Memory()
+	if m == nil {
+		return nil
+	}
+
+	return m.Bytes()
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.Code)
+}
+
+func (h *Host) argsGet(m mem, st []uint64) Errno {
+	return m.putStrings(h.Args, uint32(st[0]), uint32(st[1]))
+}
+
+func (h *Host) argsSizesGet(m mem, st []uint64) Errno {
+	return m.putSizes(h.Args, uint32(st[0]), uint32(st[1]))
+}
+
+func (h *Host) environGet(m mem, st []uint64) Errno {
+	return m.putStrings(h.Env, uint32(st[0]), uint32(st[1]))
+}
+
+func (h *Host) environSizesGet(m mem, st []uint64) Errno {
+	return m.putSizes(h.Env, uint32(st[0]), uint32(st[1]))
+}
+
+// Clock ids.
+const (
+	clockRealtime = iota
+	clockMonotonic
+	clockProcessCPUTime
+	clockThreadCPUTime
+)
+
+func (h *Host) clockResGet(m mem, st []uint64) Errno {
+	if st[0] > clockThreadCPUTime {
+		return EINVAL
+	}
+
+	return m.putU64(uint32(st[1]), 1)
+}
+
+func (h *Host) clockTimeGet(m mem, st []uint64) Errno {
+	t, e := h.clock(uint32(st[0]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	return m.putU64(uint32(st[2]), t)
+}
+
+func (h *Host) clock(id uint32) (uint64, Errno) {
+	switch id {
+	case clockRealtime:
+		return uint64(h.now().UnixNano()), ESUCCESS
+	case clockMonotonic, clockProcessCPUTime, clockThreadCPUTime:
+		return uint64(time.Since(h.start)), ESUCCESS
+	default:
+		return 0, EINVAL
+	}
+}
+
+func (h *Host) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+
+	return time.Now()
+}
+
+func (h *Host) randomGet(m mem, st []uint64) Errno {
+	b, e := m.slice(uint32(st[0]), uint32(st[1]))
+	if e != ESUCCESS {
+		return e
+	}
+
+	r := h.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+
+	_, err := io.ReadFull(r, b)
+
+	return errno(err)
+}
+
+func (h *Host) schedYield(m mem, st []uint64) Errno {
+	return ESUCCESS
+}
+
+// Subscription and event types.
+const (
+	eventClock = iota
+	eventFdRead
+	eventFdWrite
+)
+
+const (
+	subscriptionSize = 48
+	eventSize        = 32
+
+	subclockAbstime = 1
+)
+
+// pollOneoff waits for the earliest clock subscription if there are no fd subscriptions.
+// Fd subscriptions are always ready.
+// Waiting is interrupted if ctx is canceled.
+func (h *Host) pollOneoff(ctx context.Context, m mem, st []uint64) Errno {
+	in, out, n := uint32(st[0]), uint32(st[1]), uint32(st[2])
+	if n == 0 {
+		return EINVAL
+	}
+
+	subs, e := m.array(in, n, subscriptionSize)
+	if e != ESUCCESS {
+		return e
+	}
+
+	events, e := m.array(out, n, eventSize)
+	if e != ESUCCESS {
+		return e
+	}
+
+	var wait time.Duration
+	clock := -1
+	ready := 0
+
+	for i := 0; i < int(n); i++ {
+		s := subs[i*subscriptionSize:]
+		if s[8] != eventClock {
+			ready++
+			continue
+		}
+
+		id := le.Uint32(s[16:])
+		timeout := le.Uint64(s[24:])
+
+		if le.Uint16(s[40:])&subclockAbstime != 0 {
+			now, e := h.clock(id)
+			if e != ESUCCESS {
+				return e
+			}
+
+			timeout = max(timeout, now) - now
+		}
+
+		if clock < 0 || time.Duration(timeout) < wait {
+			wait, clock = time.Duration(timeout), i
+		}
+	}
+
+	if ready == 0 && wait > 0 {
+		t := time.NewTimer(wait)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ECANCELED
+		}
+	}
+
+	k := 0
+
+	for i := 0; i < int(n); i++ {
+		s := subs[i*subscriptionSize:]
+		if s[8] == eventClock && (ready != 0 || i != clock) {
+			continue
+		}
+
+		ev := events[k*eventSize : (k+1)*eventSize]
+		clear(ev)
+		copy(ev, s[:8]) // userdata
+		ev[10] = s[8]
+
+		if s[8] != eventClock {
+			f, e := h.file(le.Uint32(s[16:]))
+			if e != ESUCCESS {
+				le.PutUint16(ev[8:], uint16(e))
+			} else if f.dir {
+				le.PutUint16(ev[8:], uint16(EISDIR))
+			}
+		}
+
+		k++
+	}
+
+	return m.putU32(uint32(st[3]), uint32(k))
+}
+
+func notSupported(h *Host, m mem, st []uint64) Errno {
+	return ENOSYS
+}
+
+func (m mem) slice(p, n uint32) ([]byte, Errno) {
+	if uint64(p)+uint64(n) > uint64(len(m)) {
+		return nil, EFAULT
+	}
+
+	return m[p : p+n], ESUCCESS
+}
+
+// array returns n elements of size bytes each.
+func (m mem) array(p, n, size uint32) ([]byte, Errno) {
+	l := uint64(n) * uint64(size)
+
+	if uint64(p)+l > uint64(len(m)) {
+		return nil, EFAULT
+	}
+
+	return m[p : uint64(p)+l], ESUCCESS
+}
+
+func (m mem) putU32(p, v uint32) Errno {
+	b, e := m.slice(p, 4)
+	if e != ESUCCESS {
+		return e
+	}
+
+	le.PutUint32(b, v)
+
+	return ESUCCESS
+}
+
+func (m mem) putU64(p uint32, v uint64) Errno {
+	b, e := m.slice(p, 8)
+	if e != ESUCCESS {
+		return e
+	}
+
+	le.PutUint64(b, v)
+
+	return ESUCCESS
+}
+
+func (m mem) str(p, n uint32) (string, Errno) {
+	b, e := m.slice(p, n)
+
+	return string(b), e
+}
+
+// putStrings writes nul-terminated strings to buf and their pointers to ptrs.
+func (m mem) putStrings(list []string, ptrs, buf uint32) Errno {
+	for i, s := range list {
+		b, e := m.slice(buf, uint32(len(s)+1))
+		if e != ESUCCESS {
+			return e
+		}
+
+		copy(b, s)
+		b[len(s)] = 0
+
+		if e = m.putU32(ptrs+uint32(i)*4, buf); e != ESUCCESS {
+			return e
+		}
+
+		buf += uint32(len(s) + 1)
+	}
+
+	return ESUCCESS
+}
+
+func (m mem) putSizes(list []string, count, size uint32) Errno {
+	n := 0
+
+	for _, s := range list {
+		n += len(s) + 1
+	}
+
+	if e := m.putU32(count, uint32(len(list))); e != ESUCCESS {
+		return e
+	}
+
+	return m.putU32(size, uint32(n))
+}
+
+// iovecs returns the list of buffers described by the iovec array.
+func (m mem) iovecs(p, n uint32) ([][]byte, Errno) {
+	arr, e := m.array(p, n, 8)
+	if e != ESUCCESS {
+		return nil, e
+	}
+
+	iovs := make([][]byte, n)
+
+	for i := range iovs {
+		iovs[i], e = m.slice(le.Uint32(arr[i*8:]), le.Uint32(arr[i*8+4:]))
+		if e != ESUCCESS {
+			return nil, e
+		}
+	}
+
+	return iovs, ESUCCESS
+}
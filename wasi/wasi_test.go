@@ -0,0 +1,358 @@
+package wasi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+	"nikand.dev/go/wasm/interp"
+	"nikand.dev/go/wasm/wat"
+)
+
+type proxy struct {
+	tb   testing.TB
+	inst *interp.Instance
+	mem  []byte
+}
+
+func instantiate(tb testing.TB, h *Host, text string) *interp.Instance {
+	tb.Helper()
+
+	var p wat.Parser
+	var m wasm.Module
+	var r interp.Runtime
+	var l interp.Linker
+
+	err := p.Module([]byte(text), &m)
+	require.NoError(tb, err)
+
+	h.Define(&l)
+
+	inst, err := l.Instantiate(&r, &m)
+	require.NoError(tb, err)
+
+	return inst
+}
+
+// newProxy instantiates a module reexporting all the WASI functions,
+// so they are called with the module memory.
+func newProxy(tb testing.TB, h *Host) *proxy {
+	tb.Helper()
+
+	var b strings.Builder
+
+	b.WriteString("(module\n  (memory (export \"memory\") 1)\n")
+
+	for _, f := range funcs {
+		var params, args strings.Builder
+
+		for i, c := range f.params {
+			fmt.Fprintf(&params, " %v", map[rune]string{'i': "i32", 'I': "i64"}[c])
+			fmt.Fprintf(&args, " (local.get %d)", i)
+		}
+
+		fmt.Fprintf(&b, "  (import %q %q (func $%s (param%s) (result i32)))\n", Module, f.name, f.name, params.String())
+		fmt.Fprintf(&b, "  (func (export %q) (param%s) (result i32) (call $%s%s))\n", f.name, params.String(), f.name, args.String())
+	}
+
+	b.WriteString(")")
+
+	inst := instantiate(tb, h, b.String())
+
+	return &proxy{tb: tb, inst: inst, mem: inst.Memory("memory").Bytes()}
+}
+
+func (p *proxy) call(name string, args ...uint64) Errno {
+	p.tb.Helper()
+
+	res, err := p.inst.Call(name, args...)
+	require.NoError(p.tb, err)
+
+	return Errno(res[0])
+}
+
+// str puts s at ptr and returns ptr and len arguments.
+func (p *proxy) str(ptr uint64, s string) (uint64, uint64) {
+	copy(p.mem[ptr:], s)
+
+	return ptr, uint64(len(s))
+}
+
+func (p *proxy) u32(ptr uint64) uint32 { return le.Uint32(p.mem[ptr:]) }
+func (p *proxy) u64(ptr uint64) uint64 { return le.Uint64(p.mem[ptr:]) }
+
+func TestStart(tb *testing.T) {
+	var stdout bytes.Buffer
+
+	h := &Host{Stdout: &stdout}
+
+	inst := instantiate(tb, h, `(module
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "proc_exit" (func $exit (param i32)))
+  (memory (export "memory") 1)
+  (data (i32.const 0) "\10\00\00\00\06\00\00\00")
+  (data (i32.const 16) "hello\n")
+  (func (export "_start")
+    (call $exit (call $fd_write (i32.const 1) (i32.const 0) (i32.const 1) (i32.const 8)))))
+`)
+
+	_, err := inst.Call("_start")
+
+	var exit ExitError
+	if assert.True(tb, errors.As(err, &exit), "%v", err) {
+		assert.Equal(tb, uint32(0), exit.Code)
+	}
+
+	assert.Equal(tb, "hello\n", stdout.String())
+	assert.Equal(tb, uint32(6), le.Uint32(inst.Memory("memory").Bytes()[8:]))
+}
+
+func TestArgsEnvClock(tb *testing.T) {
+	now := time.Unix(100, 5)
+
+	h := &Host{
+		Args: []string{"prog", "arg"},
+		Env:  []string{"A=b"},
+		Now:  func() time.Time { return now },
+		Rand: strings.NewReader("random"),
+	}
+
+	p := newProxy(tb, h)
+
+	assert.Equal(tb, ESUCCESS, p.call("args_sizes_get", 0, 4))
+	assert.Equal(tb, []uint32{2, 9}, []uint32{p.u32(0), p.u32(4)})
+
+	assert.Equal(tb, ESUCCESS, p.call("args_get", 0, 100))
+	assert.Equal(tb, []uint32{100, 105}, []uint32{p.u32(0), p.u32(4)})
+	assert.Equal(tb, "prog\x00arg\x00", string(p.mem[100:109]))
+
+	assert.Equal(tb, ESUCCESS, p.call("environ_sizes_get", 0, 4))
+	assert.Equal(tb, []uint32{1, 4}, []uint32{p.u32(0), p.u32(4)})
+
+	assert.Equal(tb, ESUCCESS, p.call("environ_get", 0, 100))
+	assert.Equal(tb, "A=b\x00", string(p.mem[100:104]))
+
+	assert.Equal(tb, ESUCCESS, p.call("clock_time_get", clockRealtime, 0, 0))
+	assert.Equal(tb, uint64(100_000_000_005), p.u64(0))
+
+	assert.Equal(tb, ESUCCESS, p.call("clock_time_get", clockMonotonic, 0, 0))
+	assert.Equal(tb, EINVAL, p.call("clock_time_get", 10, 0, 0))
+
+	assert.Equal(tb, ESUCCESS, p.call("random_get", 0, 6))
+	assert.Equal(tb, "random", string(p.mem[:6]))
+	assert.Equal(tb, EIO, p.call("random_get", 0, 6))
+
+	assert.Equal(tb, EFAULT, p.call("args_get", 0, 1<<16-4))
+	assert.Equal(tb, EFAULT, p.call("random_get", 1<<16-4, 8))
+	assert.Equal(tb, ENOSYS, p.call("sock_accept", 0, 0, 0))
+}
+
+func TestFiles(tb *testing.T) {
+	var mfs MemFS
+
+	require.NoError(tb, mfs.Mkdir("dir", 0o755))
+	require.NoError(tb, mfs.WriteFile("dir/a.txt", []byte("abc"), 0o644))
+
+	p := newProxy(tb, &Host{FS: &mfs})
+
+	const root = 3
+
+	// preopen
+	assert.Equal(tb, ESUCCESS, p.call("fd_prestat_get", root, 0))
+	assert.Equal(tb, uint32(1), p.u32(4))
+	assert.Equal(tb, ESUCCESS, p.call("fd_prestat_dir_name", root, 0, 1))
+	assert.Equal(tb, "/", string(p.mem[:1]))
+	assert.Equal(tb, EBADF, p.call("fd_prestat_get", root+1, 0))
+
+	// create and write
+	path, plen := p.str(100, "dir/b.txt")
+	assert.Equal(tb, ESUCCESS, p.call("path_open", root, 0, path, plen, oflagCreat|oflagExcl, rightFdRead|rightFdWrite, 0, 0, 0))
+	fd := uint64(p.u32(0))
+
+	p.str(200, "hello world")
+	le.PutUint32(p.mem[300:], 200)
+	le.PutUint32(p.mem[304:], 11)
+	assert.Equal(tb, ESUCCESS, p.call("fd_write", fd, 300, 1, 0))
+	assert.Equal(tb, uint32(11), p.u32(0))
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_seek", fd, 6, 0, 0))
+	assert.Equal(tb, uint64(6), p.u64(0))
+
+	// read
+	le.PutUint32(p.mem[300:], 400)
+	le.PutUint32(p.mem[304:], 3)
+	le.PutUint32(p.mem[308:], 410)
+	le.PutUint32(p.mem[312:], 10)
+	assert.Equal(tb, ESUCCESS, p.call("fd_read", fd, 300, 2, 0))
+	assert.Equal(tb, uint32(5), p.u32(0))
+	assert.Equal(tb, "wor", string(p.mem[400:403]))
+	assert.Equal(tb, "ld", string(p.mem[410:412]))
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_filestat_get", fd, 0))
+	assert.Equal(tb, uint8(fileRegular), p.mem[16])
+	assert.Equal(tb, uint64(11), p.u64(32))
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_close", fd))
+	assert.Equal(tb, EBADF, p.call("fd_close", fd))
+
+	data, err := fs.ReadFile(&mfs, "dir/b.txt")
+	assert.NoError(tb, err)
+	assert.Equal(tb, "hello world", string(data))
+
+	assert.Equal(tb, EEXIST, p.call("path_open", root, 0, path, plen, oflagCreat|oflagExcl, rightFdWrite, 0, 0, 0))
+
+	// readdir
+	path, plen = p.str(100, "dir")
+	assert.Equal(tb, ESUCCESS, p.call("path_open", root, 0, path, plen, oflagDirectory, rightFdRead, 0, 0, 0))
+	fd = uint64(p.u32(0))
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_readdir", fd, 1000, 1000, 0, 0))
+	assert.Equal(tb, []string{".", "..", "a.txt", "b.txt"}, readdir(p.mem[1000:1000+p.u32(0)]))
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_readdir", fd, 1000, 30, 2, 0))
+	assert.Equal(tb, uint32(30), p.u32(0), "truncated")
+	assert.Equal(tb, uint64(3), p.u64(1000), "next cookie")
+
+	assert.Equal(tb, ESUCCESS, p.call("fd_readdir", fd, 1000, 1000, 4, 0))
+	assert.Equal(tb, uint32(0), p.u32(0))
+
+	// relative to the opened directory
+	path, plen = p.str(100, "a.txt")
+	assert.Equal(tb, ESUCCESS, p.call("path_filestat_get", fd, 0, path, plen, 0))
+	assert.Equal(tb, uint64(3), p.u64(32))
+
+	assert.Equal(tb, ENOTDIR, p.call("path_create_directory", 1, path, plen))
+	assert.Equal(tb, EISDIR, p.call("fd_write", fd, 300, 1, 0))
+
+	// modify
+	path, plen = p.str(100, "dir/a.txt")
+	npath, nplen := p.str(200, "c.txt")
+	assert.Equal(tb, ESUCCESS, p.call("path_rename", root, path, plen, root, npath, nplen))
+	assert.Equal(tb, ENOENT, p.call("path_unlink_file", root, path, plen))
+	assert.Equal(tb, ESUCCESS, p.call("path_unlink_file", root, npath, nplen))
+
+	path, plen = p.str(100, "dir")
+	assert.Equal(tb, EISDIR, p.call("path_unlink_file", root, path, plen))
+	assert.Equal(tb, ENOTEMPTY, p.call("path_remove_directory", root, path, plen))
+
+	path, plen = p.str(100, "new")
+	assert.Equal(tb, ESUCCESS, p.call("path_create_directory", root, path, plen))
+	assert.Equal(tb, EEXIST, p.call("path_create_directory", root, path, plen))
+	assert.Equal(tb, ESUCCESS, p.call("path_remove_directory", root, path, plen))
+}
+
+func TestSandbox(tb *testing.T) {
+	p := newProxy(tb, &Host{FS: fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("abc")},
+	}})
+
+	const root = 3
+
+	for _, name := range []string{"../a.txt", "/a.txt", "a/../../a.txt"} {
+		path, plen := p.str(100, name)
+		assert.Equal(tb, ENOTCAPABLE, p.call("path_open", root, 0, path, plen, 0, rightFdRead, 0, 0, 0), "%v", name)
+	}
+
+	path, plen := p.str(100, "a.txt")
+	assert.Equal(tb, ESUCCESS, p.call("path_open", root, 0, path, plen, 0, rightFdRead, 0, 0, 0))
+	assert.Equal(tb, EROFS, p.call("path_open", root, 0, path, plen, oflagTrunc, rightFdWrite, 0, 0, 0))
+	assert.Equal(tb, EROFS, p.call("path_unlink_file", root, path, plen))
+	assert.Equal(tb, EFAULT, p.call("path_open", root, 0, 1<<16-2, 4, 0, rightFdRead, 0, 0, 0))
+
+	fd := uint64(p.u32(0))
+
+	le.PutUint32(p.mem[300:], 200)
+	le.PutUint32(p.mem[304:], 1)
+	assert.Equal(tb, EBADF, p.call("fd_write", fd, 300, 1, 0))
+	assert.Equal(tb, ESPIPE, p.call("fd_seek", 1, 0, 0, 0))
+
+	p = newProxy(tb, &Host{})
+	assert.Equal(tb, EBADF, p.call("fd_prestat_get", root, 0))
+	assert.Equal(tb, ESUCCESS, p.call("fd_write", 1, 300, 1, 0), "discarded")
+	assert.Equal(tb, ESUCCESS, p.call("fd_read", 0, 300, 1, 0))
+	assert.Equal(tb, uint32(0), p.u32(0), "empty stdin")
+}
+
+func TestPollOneoff(tb *testing.T) {
+	p := newProxy(tb, &Host{})
+
+	// clock subscription
+	clear(p.mem[:48])
+	le.PutUint64(p.mem[24:], uint64(time.Millisecond))
+
+	assert.Equal(tb, ESUCCESS, p.call("poll_oneoff", 0, 100, 1, 200))
+	assert.Equal(tb, uint32(1), p.u32(200))
+
+	// sizes overflow uint32
+	assert.Equal(tb, EFAULT, p.call("poll_oneoff", 0, 0, 0x1000_0000, 0))
+	assert.Equal(tb, EFAULT, p.call("fd_write", 1, 0, 0x2000_0000, 0))
+	assert.Equal(tb, EFAULT, p.call("fd_read", 0, 0, 0x2000_0000, 0))
+
+	// waiting is interrupted by the call context
+	le.PutUint64(p.mem[24:], uint64(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	e := interp.Exec{Context: ctx}
+
+	_, err := e.Call(p.inst.Func("poll_oneoff"), 0, 100, 1, 200)
+	assert.ErrorIs(tb, err, context.DeadlineExceeded)
+}
+
+func TestMemFS(tb *testing.T) {
+	var m MemFS
+
+	require.NoError(tb, m.Mkdir("a", 0o755))
+	require.NoError(tb, m.Mkdir("a/b", 0o755))
+	require.NoError(tb, m.WriteFile("a/b/c.txt", []byte("c"), 0o644))
+	require.NoError(tb, m.WriteFile("d.txt", []byte("d"), 0o644))
+
+	err := fstest.TestFS(&m, "a/b/c.txt", "d.txt")
+	assert.NoError(tb, err)
+
+	_, err = m.Open("nope")
+	assert.ErrorIs(tb, err, fs.ErrNotExist)
+	assert.ErrorIs(tb, m.Mkdir("a", 0o755), fs.ErrExist)
+	assert.ErrorIs(tb, m.Mkdir("d.txt/e", 0o755), ENOTDIR)
+	assert.ErrorIs(tb, m.Remove("a"), ENOTEMPTY)
+	assert.ErrorIs(tb, m.Rename("a", "a/b/e"), fs.ErrInvalid)
+	assert.ErrorIs(tb, m.Rename("d.txt", "a"), EISDIR)
+
+	require.NoError(tb, m.Rename("a/b", "b"))
+	require.NoError(tb, m.Rename("d.txt", "b/c.txt"))
+
+	data, err := fs.ReadFile(&m, "b/c.txt")
+	assert.NoError(tb, err)
+	assert.Equal(tb, "d", string(data))
+
+	require.NoError(tb, m.Remove("b/c.txt"))
+	require.NoError(tb, m.Remove("b"))
+
+	f, err := m.Open("a")
+	require.NoError(tb, err)
+
+	list, err := f.(fs.ReadDirFile).ReadDir(-1)
+	assert.NoError(tb, err)
+	assert.Empty(tb, list)
+}
+
+func readdir(b []byte) (names []string) {
+	for len(b) >= direntSize {
+		n := int(le.Uint32(b[16:]))
+		names = append(names, string(b[direntSize:direntSize+n]))
+		b = b[direntSize+n:]
+	}
+
+	return names
+}
@@ -0,0 +1,523 @@
+package wat
+
+import "nikand.dev/go/wasm"
+
+// ops are single byte opcodes.
+var ops = [256]opInfo{
+	wasm.Unreachable:       {"unreachable", 0, 0, 0},
+	wasm.Nop:               {"nop", 0, 0, 0},
+	wasm.Block:             {"block", 0, 0, 0},
+	wasm.Loop:              {"loop", 0, 0, 0},
+	wasm.If:                {"if", 0, 0, 0},
+	wasm.Else:              {"else", 0, 0, 0},
+	wasm.End:               {"end", 0, 0, 0},
+	wasm.Br:                {"br", 0, 0, 0},
+	wasm.BrIf:              {"br_if", 0, 1, 0},
+	wasm.BrTable:           {"br_table", 0, 0, 0},
+	wasm.Ret:               {"return", 0, 0, 0},
+	wasm.Call:              {"call", 0, 0, 0},
+	wasm.CallIndir:         {"call_indirect", 0, 0, 0},
+	wasm.Drop:              {"drop", 0, 1, 0},
+	wasm.Select:            {"select", 0, 3, 1},
+	wasm.SelectT:           {"select", 0, 3, 1},
+	wasm.LocalGet:          {"local.get", 0, 0, 1},
+	wasm.LocalSet:          {"local.set", 0, 1, 0},
+	wasm.LocalTee:          {"local.tee", 0, 1, 1},
+	wasm.GlobalGet:         {"global.get", 0, 0, 1},
+	wasm.GlobalSet:         {"global.set", 0, 1, 0},
+	wasm.TableGet:          {"table.get", 0, 1, 1},
+	wasm.TableSet:          {"table.set", 0, 2, 0},
+	wasm.I32Load:           {"i32.load", 2, 1, 1},
+	wasm.I64Load:           {"i64.load", 3, 1, 1},
+	wasm.F32Load:           {"f32.load", 2, 1, 1},
+	wasm.F64Load:           {"f64.load", 3, 1, 1},
+	wasm.I32Load8S:         {"i32.load8_s", 0, 1, 1},
+	wasm.I32Load8U:         {"i32.load8_u", 0, 1, 1},
+	wasm.I32Load16S:        {"i32.load16_s", 1, 1, 1},
+	wasm.I32Load16U:        {"i32.load16_u", 1, 1, 1},
+	wasm.I64Load8S:         {"i64.load8_s", 0, 1, 1},
+	wasm.I64Load8U:         {"i64.load8_u", 0, 1, 1},
+	wasm.I64Load16S:        {"i64.load16_s", 1, 1, 1},
+	wasm.I64Load16U:        {"i64.load16_u", 1, 1, 1},
+	wasm.I64Load32S:        {"i64.load32_s", 2, 1, 1},
+	wasm.I64Load32U:        {"i64.load32_u", 2, 1, 1},
+	wasm.I32Store:          {"i32.store", 2, 2, 0},
+	wasm.I64Store:          {"i64.store", 3, 2, 0},
+	wasm.F32Store:          {"f32.store", 2, 2, 0},
+	wasm.F64Store:          {"f64.store", 3, 2, 0},
+	wasm.I32Store8:         {"i32.store8", 0, 2, 0},
+	wasm.I32Store16:        {"i32.store16", 1, 2, 0},
+	wasm.I64Store8:         {"i64.store8", 0, 2, 0},
+	wasm.I64Store16:        {"i64.store16", 1, 2, 0},
+	wasm.I64Store32:        {"i64.store32", 2, 2, 0},
+	wasm.MemorySize:        {"memory.size", 0, 0, 1},
+	wasm.MemoryGrow:        {"memory.grow", 0, 1, 1},
+	wasm.I32Const:          {"i32.const", 0, 0, 1},
+	wasm.I64Const:          {"i64.const", 0, 0, 1},
+	wasm.F32Const:          {"f32.const", 0, 0, 1},
+	wasm.F64Const:          {"f64.const", 0, 0, 1},
+	wasm.I32EqZ:            {"i32.eqz", 0, 1, 1},
+	wasm.I32Eq:             {"i32.eq", 0, 2, 1},
+	wasm.I32Ne:             {"i32.ne", 0, 2, 1},
+	wasm.I32LtS:            {"i32.lt_s", 0, 2, 1},
+	wasm.I32LtU:            {"i32.lt_u", 0, 2, 1},
+	wasm.I32GtS:            {"i32.gt_s", 0, 2, 1},
+	wasm.I32GtU:            {"i32.gt_u", 0, 2, 1},
+	wasm.I32LeS:            {"i32.le_s", 0, 2, 1},
+	wasm.I32LeU:            {"i32.le_u", 0, 2, 1},
+	wasm.I32GeS:            {"i32.ge_s", 0, 2, 1},
+	wasm.I32GeU:            {"i32.ge_u", 0, 2, 1},
+	wasm.I64EqZ:            {"i64.eqz", 0, 1, 1},
+	wasm.I64Eq:             {"i64.eq", 0, 2, 1},
+	wasm.I64Ne:             {"i64.ne", 0, 2, 1},
+	wasm.I64LtS:            {"i64.lt_s", 0, 2, 1},
+	wasm.I64LtU:            {"i64.lt_u", 0, 2, 1},
+	wasm.I64GtS:            {"i64.gt_s", 0, 2, 1},
+	wasm.I64GtU:            {"i64.gt_u", 0, 2, 1},
+	wasm.I64LeS:            {"i64.le_s", 0, 2, 1},
+	wasm.I64LeU:            {"i64.le_u", 0, 2, 1},
+	wasm.I64GeS:            {"i64.ge_s", 0, 2, 1},
+	wasm.I64GeU:            {"i64.ge_u", 0, 2, 1},
+	wasm.F32Eq:             {"f32.eq", 0, 2, 1},
+	wasm.F32Ne:             {"f32.ne", 0, 2, 1},
+	wasm.F32Lt:             {"f32.lt", 0, 2, 1},
+	wasm.F32Gt:             {"f32.gt", 0, 2, 1},
+	wasm.F32Le:             {"f32.le", 0, 2, 1},
+	wasm.F32Ge:             {"f32.ge", 0, 2, 1},
+	wasm.F64Eq:             {"f64.eq", 0, 2, 1},
+	wasm.F64Ne:             {"f64.ne", 0, 2, 1},
+	wasm.F64Lt:             {"f64.lt", 0, 2, 1},
+	wasm.F64Gt:             {"f64.gt", 0, 2, 1},
+	wasm.F64Le:             {"f64.le", 0, 2, 1},
+	wasm.F64Ge:             {"f64.ge", 0, 2, 1},
+	wasm.I32Clz:            {"i32.clz", 0, 1, 1},
+	wasm.I32Ctz:            {"i32.ctz", 0, 1, 1},
+	wasm.I32Popcnt:         {"i32.popcnt", 0, 1, 1},
+	wasm.I32Add:            {"i32.add", 0, 2, 1},
+	wasm.I32Sub:            {"i32.sub", 0, 2, 1},
+	wasm.I32Mul:            {"i32.mul", 0, 2, 1},
+	wasm.I32DivS:           {"i32.div_s", 0, 2, 1},
+	wasm.I32DivU:           {"i32.div_u", 0, 2, 1},
+	wasm.I32RemS:           {"i32.rem_s", 0, 2, 1},
+	wasm.I32RemU:           {"i32.rem_u", 0, 2, 1},
+	wasm.I32And:            {"i32.and", 0, 2, 1},
+	wasm.I32Or:             {"i32.or", 0, 2, 1},
+	wasm.I32Xor:            {"i32.xor", 0, 2, 1},
+	wasm.I32Shl:            {"i32.shl", 0, 2, 1},
+	wasm.I32ShrS:           {"i32.shr_s", 0, 2, 1},
+	wasm.I32ShrU:           {"i32.shr_u", 0, 2, 1},
+	wasm.I32RotL:           {"i32.rotl", 0, 2, 1},
+	wasm.I32RotR:           {"i32.rotr", 0, 2, 1},
+	wasm.I64Clz:            {"i64.clz", 0, 1, 1},
+	wasm.I64Ctz:            {"i64.ctz", 0, 1, 1},
+	wasm.I64Popcnt:         {"i64.popcnt", 0, 1, 1},
+	wasm.I64Add:            {"i64.add", 0, 2, 1},
+	wasm.I64Sub:            {"i64.sub", 0, 2, 1},
+	wasm.I64Mul:            {"i64.mul", 0, 2, 1},
+	wasm.I64DivS:           {"i64.div_s", 0, 2, 1},
+	wasm.I64DivU:           {"i64.div_u", 0, 2, 1},
+	wasm.I64RemS:           {"i64.rem_s", 0, 2, 1},
+	wasm.I64RemU:           {"i64.rem_u", 0, 2, 1},
+	wasm.I64And:            {"i64.and", 0, 2, 1},
+	wasm.I64Or:             {"i64.or", 0, 2, 1},
+	wasm.I64Xor:            {"i64.xor", 0, 2, 1},
+	wasm.I64Shl:            {"i64.shl", 0, 2, 1},
+	wasm.I64ShrS:           {"i64.shr_s", 0, 2, 1},
+	wasm.I64ShrU:           {"i64.shr_u", 0, 2, 1},
+	wasm.I64RotL:           {"i64.rotl", 0, 2, 1},
+	wasm.I64RotR:           {"i64.rotr", 0, 2, 1},
+	wasm.F32Abs:            {"f32.abs", 0, 1, 1},
+	wasm.F32Neg:            {"f32.neg", 0, 1, 1},
+	wasm.F32Ceil:           {"f32.ceil", 0, 1, 1},
+	wasm.F32Floor:          {"f32.floor", 0, 1, 1},
+	wasm.F32Trunc:          {"f32.trunc", 0, 1, 1},
+	wasm.F32Near:           {"f32.nearest", 0, 1, 1},
+	wasm.F32Sqrt:           {"f32.sqrt", 0, 1, 1},
+	wasm.F32Add:            {"f32.add", 0, 2, 1},
+	wasm.F32Sub:            {"f32.sub", 0, 2, 1},
+	wasm.F32Mul:            {"f32.mul", 0, 2, 1},
+	wasm.F32Div:            {"f32.div", 0, 2, 1},
+	wasm.F32Min:            {"f32.min", 0, 2, 1},
+	wasm.F32Max:            {"f32.max", 0, 2, 1},
+	wasm.F32CopySign:       {"f32.copysign", 0, 2, 1},
+	wasm.F64Abs:            {"f64.abs", 0, 1, 1},
+	wasm.F64Neg:            {"f64.neg", 0, 1, 1},
+	wasm.F64Ceil:           {"f64.ceil", 0, 1, 1},
+	wasm.F64Floor:          {"f64.floor", 0, 1, 1},
+	wasm.F64Trunc:          {"f64.trunc", 0, 1, 1},
+	wasm.F64Near:           {"f64.nearest", 0, 1, 1},
+	wasm.F64Sqrt:           {"f64.sqrt", 0, 1, 1},
+	wasm.F64Add:            {"f64.add", 0, 2, 1},
+	wasm.F64Sub:            {"f64.sub", 0, 2, 1},
+	wasm.F64Mul:            {"f64.mul", 0, 2, 1},
+	wasm.F64Div:            {"f64.div", 0, 2, 1},
+	wasm.F64Min:            {"f64.min", 0, 2, 1},
+	wasm.F64Max:            {"f64.max", 0, 2, 1},
+	wasm.F64CopySign:       {"f64.copysign", 0, 2, 1},
+	wasm.I32WrapI64:        {"i32.wrap_i64", 0, 1, 1},
+	wasm.I32TruncF32S:      {"i32.trunc_f32_s", 0, 1, 1},
+	wasm.I32TruncF32U:      {"i32.trunc_f32_u", 0, 1, 1},
+	wasm.I32TruncF64S:      {"i32.trunc_f64_s", 0, 1, 1},
+	wasm.I32TruncF64U:      {"i32.trunc_f64_u", 0, 1, 1},
+	wasm.I64ExtendI32S:     {"i64.extend_i32_s", 0, 1, 1},
+	wasm.I64ExtendI32U:     {"i64.extend_i32_u", 0, 1, 1},
+	wasm.I64TruncF32S:      {"i64.trunc_f32_s", 0, 1, 1},
+	wasm.I64TruncF32U:      {"i64.trunc_f32_u", 0, 1, 1},
+	wasm.I64TruncF64S:      {"i64.trunc_f64_s", 0, 1, 1},
+	wasm.I64TruncF64U:      {"i64.trunc_f64_u", 0, 1, 1},
+	wasm.F32ConvertI32S:    {"f32.convert_i32_s", 0, 1, 1},
+	wasm.F32ConvertI32U:    {"f32.convert_i32_u", 0, 1, 1},
+	wasm.F32ConvertI64S:    {"f32.convert_i64_s", 0, 1, 1},
+	wasm.F32ConvertI64U:    {"f32.convert_i64_u", 0, 1, 1},
+	wasm.F32DemoteF64:      {"f32.demote_f64", 0, 1, 1},
+	wasm.F64ConvertI32S:    {"f64.convert_i32_s", 0, 1, 1},
+	wasm.F64ConvertI32U:    {"f64.convert_i32_u", 0, 1, 1},
+	wasm.F64ConvertI64S:    {"f64.convert_i64_s", 0, 1, 1},
+	wasm.F64ConvertI64U:    {"f64.convert_i64_u", 0, 1, 1},
+	wasm.F64PromoteF32:     {"f64.promote_f32", 0, 1, 1},
+	wasm.I32ReinterpretF32: {"i32.reinterpret_f32", 0, 1, 1},
+	wasm.I64ReinterpretF64: {"i64.reinterpret_f64", 0, 1, 1},
+	wasm.F32ReinterpretI32: {"f32.reinterpret_i32", 0, 1, 1},
+	wasm.F64ReinterpretI64: {"f64.reinterpret_i64", 0, 1, 1},
+	wasm.I32Extend8S:       {"i32.extend8_s", 0, 1, 1},
+	wasm.I32Extend16S:      {"i32.extend16_s", 0, 1, 1},
+	wasm.I64Extend8S:       {"i64.extend8_s", 0, 1, 1},
+	wasm.I64Extend16S:      {"i64.extend16_s", 0, 1, 1},
+	wasm.I64Extend32S:      {"i64.extend32_s", 0, 1, 1},
+	wasm.RefNull:           {"ref.null", 0, 0, 1},
+	wasm.RefIsNull:         {"ref.is_null", 0, 1, 1},
+	wasm.RefFunc:           {"ref.func", 0, 0, 1},
+}
+
+// fcOps are FCExt prefixed opcodes.
+var fcOps = [...]opInfo{
+	wasm.FCI32TruncSatF32S: {"i32.trunc_sat_f32_s", 0, 1, 1},
+	wasm.FCI32TruncSatF32U: {"i32.trunc_sat_f32_u", 0, 1, 1},
+	wasm.FCI32TruncSatF64S: {"i32.trunc_sat_f64_s", 0, 1, 1},
+	wasm.FCI32TruncSatF64U: {"i32.trunc_sat_f64_u", 0, 1, 1},
+	wasm.FCI64TruncSatF32S: {"i64.trunc_sat_f32_s", 0, 1, 1},
+	wasm.FCI64TruncSatF32U: {"i64.trunc_sat_f32_u", 0, 1, 1},
+	wasm.FCI64TruncSatF64S: {"i64.trunc_sat_f64_s", 0, 1, 1},
+	wasm.FCI64TruncSatF64U: {"i64.trunc_sat_f64_u", 0, 1, 1},
+	wasm.FCMemoryInit:      {"memory.init", 0, 3, 0},
+	wasm.FCDataDrop:        {"data.drop", 0, 0, 0},
+	wasm.FCMemoryCopy:      {"memory.copy", 0, 3, 0},
+	wasm.FCMemoryFill:      {"memory.fill", 0, 3, 0},
+	wasm.FCTableInit:       {"table.init", 0, 3, 0},
+	wasm.FCElemDrop:        {"elem.drop", 0, 0, 0},
+	wasm.FCTableCopy:       {"table.copy", 0, 3, 0},
+	wasm.FCTableGrow:       {"table.grow", 0, 2, 1},
+	wasm.FCTableSize:       {"table.size", 0, 0, 1},
+	wasm.FCTableFill:       {"table.fill", 0, 3, 0},
+}
+
+// fdOps are FDExt prefixed (SIMD) opcodes.
+var fdOps = [...]opInfo{
+	wasm.FDV128Load:                  {"v128.load", 4, 1, 1},
+	wasm.FDV128Load8x8S:              {"v128.load8x8_s", 3, 1, 1},
+	wasm.FDV128Load8x8U:              {"v128.load8x8_u", 3, 1, 1},
+	wasm.FDV128Load16x4S:             {"v128.load16x4_s", 3, 1, 1},
+	wasm.FDV128Load16x4U:             {"v128.load16x4_u", 3, 1, 1},
+	wasm.FDV128Load32x2S:             {"v128.load32x2_s", 3, 1, 1},
+	wasm.FDV128Load32x2U:             {"v128.load32x2_u", 3, 1, 1},
+	wasm.FDV128Load8Splat:            {"v128.load8_splat", 0, 1, 1},
+	wasm.FDV128Load16Splat:           {"v128.load16_splat", 1, 1, 1},
+	wasm.FDV128Load32Splat:           {"v128.load32_splat", 2, 1, 1},
+	wasm.FDV128Load64Splat:           {"v128.load64_splat", 3, 1, 1},
+	wasm.FDV128Store:                 {"v128.store", 4, 2, 0},
+	wasm.FDV128Const:                 {"v128.const", 0, 0, 1},
+	wasm.FDI8x16Shuffle:              {"i8x16.shuffle", 0, 2, 1},
+	wasm.FDI8x16Swizzle:              {"i8x16.swizzle", 0, 2, 1},
+	wasm.FDI8x16Splat:                {"i8x16.splat", 0, 1, 1},
+	wasm.FDI16x8Splat:                {"i16x8.splat", 0, 1, 1},
+	wasm.FDI32x4Splat:                {"i32x4.splat", 0, 1, 1},
+	wasm.FDI64x2Splat:                {"i64x2.splat", 0, 1, 1},
+	wasm.FDF32x4Splat:                {"f32x4.splat", 0, 1, 1},
+	wasm.FDF64x2Splat:                {"f64x2.splat", 0, 1, 1},
+	wasm.FDI8x16ExtractLaneS:         {"i8x16.extract_lane_s", 0, 1, 1},
+	wasm.FDI8x16ExtractLaneU:         {"i8x16.extract_lane_u", 0, 1, 1},
+	wasm.FDI8x16ReplaceLane:          {"i8x16.replace_lane", 0, 2, 1},
+	wasm.FDI16x8ExtractLaneS:         {"i16x8.extract_lane_s", 0, 1, 1},
+	wasm.FDI16x8ExtractLaneU:         {"i16x8.extract_lane_u", 0, 1, 1},
+	wasm.FDI16x8ReplaceLane:          {"i16x8.replace_lane", 0, 2, 1},
+	wasm.FDI32x4ExtractLane:          {"i32x4.extract_lane", 0, 1, 1},
+	wasm.FDI32x4ReplaceLane:          {"i32x4.replace_lane", 0, 2, 1},
+	wasm.FDI64x2ExtractLane:          {"i64x2.extract_lane", 0, 1, 1},
+	wasm.FDI64x2ReplaceLane:          {"i64x2.replace_lane", 0, 2, 1},
+	wasm.FDF32x4ExtractLane:          {"f32x4.extract_lane", 0, 1, 1},
+	wasm.FDF32x4ReplaceLane:          {"f32x4.replace_lane", 0, 2, 1},
+	wasm.FDF64x2ExtractLane:          {"f64x2.extract_lane", 0, 1, 1},
+	wasm.FDF64x2ReplaceLane:          {"f64x2.replace_lane", 0, 2, 1},
+	wasm.FDI8x16Eq:                   {"i8x16.eq", 0, 2, 1},
+	wasm.FDI8x16Ne:                   {"i8x16.ne", 0, 2, 1},
+	wasm.FDI8x16LtS:                  {"i8x16.lt_s", 0, 2, 1},
+	wasm.FDI8x16LtU:                  {"i8x16.lt_u", 0, 2, 1},
+	wasm.FDI8x16GtS:                  {"i8x16.gt_s", 0, 2, 1},
+	wasm.FDI8x16GtU:                  {"i8x16.gt_u", 0, 2, 1},
+	wasm.FDI8x16LeS:                  {"i8x16.le_s", 0, 2, 1},
+	wasm.FDI8x16LeU:                  {"i8x16.le_u", 0, 2, 1},
+	wasm.FDI8x16GeS:                  {"i8x16.ge_s", 0, 2, 1},
+	wasm.FDI8x16GeU:                  {"i8x16.ge_u", 0, 2, 1},
+	wasm.FDI16x8Eq:                   {"i16x8.eq", 0, 2, 1},
+	wasm.FDI16x8Ne:                   {"i16x8.ne", 0, 2, 1},
+	wasm.FDI16x8LtS:                  {"i16x8.lt_s", 0, 2, 1},
+	wasm.FDI16x8LtU:                  {"i16x8.lt_u", 0, 2, 1},
+	wasm.FDI16x8GtS:                  {"i16x8.gt_s", 0, 2, 1},
+	wasm.FDI16x8GtU:                  {"i16x8.gt_u", 0, 2, 1},
+	wasm.FDI16x8LeS:                  {"i16x8.le_s", 0, 2, 1},
+	wasm.FDI16x8LeU:                  {"i16x8.le_u", 0, 2, 1},
+	wasm.FDI16x8GeS:                  {"i16x8.ge_s", 0, 2, 1},
+	wasm.FDI16x8GeU:                  {"i16x8.ge_u", 0, 2, 1},
+	wasm.FDI32x4Eq:                   {"i32x4.eq", 0, 2, 1},
+	wasm.FDI32x4Ne:                   {"i32x4.ne", 0, 2, 1},
+	wasm.FDI32x4LtS:                  {"i32x4.lt_s", 0, 2, 1},
+	wasm.FDI32x4LtU:                  {"i32x4.lt_u", 0, 2, 1},
+	wasm.FDI32x4GtS:                  {"i32x4.gt_s", 0, 2, 1},
+	wasm.FDI32x4GtU:                  {"i32x4.gt_u", 0, 2, 1},
+	wasm.FDI32x4LeS:                  {"i32x4.le_s", 0, 2, 1},
+	wasm.FDI32x4LeU:                  {"i32x4.le_u", 0, 2, 1},
+	wasm.FDI32x4GeS:                  {"i32x4.ge_s", 0, 2, 1},
+	wasm.FDI32x4GeU:                  {"i32x4.ge_u", 0, 2, 1},
+	wasm.FDF32x4Eq:                   {"f32x4.eq", 0, 2, 1},
+	wasm.FDF32x4Ne:                   {"f32x4.ne", 0, 2, 1},
+	wasm.FDF32x4Lt:                   {"f32x4.lt", 0, 2, 1},
+	wasm.FDF32x4Gt:                   {"f32x4.gt", 0, 2, 1},
+	wasm.FDF32x4Le:                   {"f32x4.le", 0, 2, 1},
+	wasm.FDF32x4Ge:                   {"f32x4.ge", 0, 2, 1},
+	wasm.FDF64x2Eq:                   {"f64x2.eq", 0, 2, 1},
+	wasm.FDF64x2Ne:                   {"f64x2.ne", 0, 2, 1},
+	wasm.FDF64x2Lt:                   {"f64x2.lt", 0, 2, 1},
+	wasm.FDF64x2Gt:                   {"f64x2.gt", 0, 2, 1},
+	wasm.FDF64x2Le:                   {"f64x2.le", 0, 2, 1},
+	wasm.FDF64x2Ge:                   {"f64x2.ge", 0, 2, 1},
+	wasm.FDV128Not:                   {"v128.not", 0, 1, 1},
+	wasm.FDV128And:                   {"v128.and", 0, 2, 1},
+	wasm.FDV128Andnot:                {"v128.andnot", 0, 2, 1},
+	wasm.FDV128Or:                    {"v128.or", 0, 2, 1},
+	wasm.FDV128Xor:                   {"v128.xor", 0, 2, 1},
+	wasm.FDV128Bitselect:             {"v128.bitselect", 0, 3, 1},
+	wasm.FDV128AnyTrue:               {"v128.any_true", 0, 1, 1},
+	wasm.FDV128Load8Lane:             {"v128.load8_lane", 0, 2, 1},
+	wasm.FDV128Load16Lane:            {"v128.load16_lane", 1, 2, 1},
+	wasm.FDV128Load32Lane:            {"v128.load32_lane", 2, 2, 1},
+	wasm.FDV128Load64Lane:            {"v128.load64_lane", 3, 2, 1},
+	wasm.FDV128Store8Lane:            {"v128.store8_lane", 0, 2, 0},
+	wasm.FDV128Store16Lane:           {"v128.store16_lane", 1, 2, 0},
+	wasm.FDV128Store32Lane:           {"v128.store32_lane", 2, 2, 0},
+	wasm.FDV128Store64Lane:           {"v128.store64_lane", 3, 2, 0},
+	wasm.FDV128Load32Zero:            {"v128.load32_zero", 2, 1, 1},
+	wasm.FDV128Load64Zero:            {"v128.load64_zero", 3, 1, 1},
+	wasm.FDF32x4DemoteF64x2Zero:      {"f32x4.demote_f64x2_zero", 0, 1, 1},
+	wasm.FDF64x2PromoteLowF32x4:      {"f64x2.promote_low_f32x4", 0, 1, 1},
+	wasm.FDI8x16Abs:                  {"i8x16.abs", 0, 1, 1},
+	wasm.FDI8x16Neg:                  {"i8x16.neg", 0, 1, 1},
+	wasm.FDI8x16Popcnt:               {"i8x16.popcnt", 0, 1, 1},
+	wasm.FDI8x16AllTrue:              {"i8x16.all_true", 0, 1, 1},
+	wasm.FDI8x16Bitmask:              {"i8x16.bitmask", 0, 1, 1},
+	wasm.FDI8x16NarrowI16x8S:         {"i8x16.narrow_i16x8_s", 0, 2, 1},
+	wasm.FDI8x16NarrowI16x8U:         {"i8x16.narrow_i16x8_u", 0, 2, 1},
+	wasm.FDF32x4Ceil:                 {"f32x4.ceil", 0, 1, 1},
+	wasm.FDF32x4Floor:                {"f32x4.floor", 0, 1, 1},
+	wasm.FDF32x4Trunc:                {"f32x4.trunc", 0, 1, 1},
+	wasm.FDF32x4Nearest:              {"f32x4.nearest", 0, 1, 1},
+	wasm.FDI8x16Shl:                  {"i8x16.shl", 0, 2, 1},
+	wasm.FDI8x16ShrS:                 {"i8x16.shr_s", 0, 2, 1},
+	wasm.FDI8x16ShrU:                 {"i8x16.shr_u", 0, 2, 1},
+	wasm.FDI8x16Add:                  {"i8x16.add", 0, 2, 1},
+	wasm.FDI8x16AddSatS:              {"i8x16.add_sat_s", 0, 2, 1},
+	wasm.FDI8x16AddSatU:              {"i8x16.add_sat_u", 0, 2, 1},
+	wasm.FDI8x16Sub:                  {"i8x16.sub", 0, 2, 1},
+	wasm.FDI8x16SubSatS:              {"i8x16.sub_sat_s", 0, 2, 1},
+	wasm.FDI8x16SubSatU:              {"i8x16.sub_sat_u", 0, 2, 1},
+	wasm.FDF64x2Ceil:                 {"f64x2.ceil", 0, 1, 1},
+	wasm.FDF64x2Floor:                {"f64x2.floor", 0, 1, 1},
+	wasm.FDI8x16MinS:                 {"i8x16.min_s", 0, 2, 1},
+	wasm.FDI8x16MinU:                 {"i8x16.min_u", 0, 2, 1},
+	wasm.FDI8x16MaxS:                 {"i8x16.max_s", 0, 2, 1},
+	wasm.FDI8x16MaxU:                 {"i8x16.max_u", 0, 2, 1},
+	wasm.FDF64x2Trunc:                {"f64x2.trunc", 0, 1, 1},
+	wasm.FDI8x16AvgrU:                {"i8x16.avgr_u", 0, 2, 1},
+	wasm.FDI16x8ExtaddPairwiseI8x16S: {"i16x8.extadd_pairwise_i8x16_s", 0, 1, 1},
+	wasm.FDI16x8ExtaddPairwiseI8x16U: {"i16x8.extadd_pairwise_i8x16_u", 0, 1, 1},
+	wasm.FDI32x4ExtaddPairwiseI16x8S: {"i32x4.extadd_pairwise_i16x8_s", 0, 1, 1},
+	wasm.FDI32x4ExtaddPairwiseI16x8U: {"i32x4.extadd_pairwise_i16x8_u", 0, 1, 1},
+	wasm.FDI16x8Abs:                  {"i16x8.abs", 0, 1, 1},
+	wasm.FDI16x8Neg:                  {"i16x8.neg", 0, 1, 1},
+	wasm.FDI16x8Q15mulrSatS:          {"i16x8.q15mulr_sat_s", 0, 2, 1},
+	wasm.FDI16x8AllTrue:              {"i16x8.all_true", 0, 1, 1},
+	wasm.FDI16x8Bitmask:              {"i16x8.bitmask", 0, 1, 1},
+	wasm.FDI16x8NarrowI32x4S:         {"i16x8.narrow_i32x4_s", 0, 2, 1},
+	wasm.FDI16x8NarrowI32x4U:         {"i16x8.narrow_i32x4_u", 0, 2, 1},
+	wasm.FDI16x8ExtendLowI8x16S:      {"i16x8.extend_low_i8x16_s", 0, 1, 1},
+	wasm.FDI16x8ExtendHighI8x16S:     {"i16x8.extend_high_i8x16_s", 0, 1, 1},
+	wasm.FDI16x8ExtendLowI8x16U:      {"i16x8.extend_low_i8x16_u", 0, 1, 1},
+	wasm.FDI16x8ExtendHighI8x16U:     {"i16x8.extend_high_i8x16_u", 0, 1, 1},
+	wasm.FDI16x8Shl:                  {"i16x8.shl", 0, 2, 1},
+	wasm.FDI16x8ShrS:                 {"i16x8.shr_s", 0, 2, 1},
+	wasm.FDI16x8ShrU:                 {"i16x8.shr_u", 0, 2, 1},
+	wasm.FDI16x8Add:                  {"i16x8.add", 0, 2, 1},
+	wasm.FDI16x8AddSatS:              {"i16x8.add_sat_s", 0, 2, 1},
+	wasm.FDI16x8AddSatU:              {"i16x8.add_sat_u", 0, 2, 1},
+	wasm.FDI16x8Sub:                  {"i16x8.sub", 0, 2, 1},
+	wasm.FDI16x8SubSatS:              {"i16x8.sub_sat_s", 0, 2, 1},
+	wasm.FDI16x8SubSatU:              {"i16x8.sub_sat_u", 0, 2, 1},
+	wasm.FDF64x2Nearest:              {"f64x2.nearest", 0, 1, 1},
+	wasm.FDI16x8Mul:                  {"i16x8.mul", 0, 2, 1},
+	wasm.FDI16x8MinS:                 {"i16x8.min_s", 0, 2, 1},
+	wasm.FDI16x8MinU:                 {"i16x8.min_u", 0, 2, 1},
+	wasm.FDI16x8MaxS:                 {"i16x8.max_s", 0, 2, 1},
+	wasm.FDI16x8MaxU:                 {"i16x8.max_u", 0, 2, 1},
+	wasm.FDI16x8AvgrU:                {"i16x8.avgr_u", 0, 2, 1},
+	wasm.FDI16x8ExtmulLowI8x16S:      {"i16x8.extmul_low_i8x16_s", 0, 2, 1},
+	wasm.FDI16x8ExtmulHighI8x16S:     {"i16x8.extmul_high_i8x16_s", 0, 2, 1},
+	wasm.FDI16x8ExtmulLowI8x16U:      {"i16x8.extmul_low_i8x16_u", 0, 2, 1},
+	wasm.FDI16x8ExtmulHighI8x16U:     {"i16x8.extmul_high_i8x16_u", 0, 2, 1},
+	wasm.FDI32x4Abs:                  {"i32x4.abs", 0, 1, 1},
+	wasm.FDI32x4Neg:                  {"i32x4.neg", 0, 1, 1},
+	wasm.FDI32x4AllTrue:              {"i32x4.all_true", 0, 1, 1},
+	wasm.FDI32x4Bitmask:              {"i32x4.bitmask", 0, 1, 1},
+	wasm.FDI32x4ExtendLowI16x8S:      {"i32x4.extend_low_i16x8_s", 0, 1, 1},
+	wasm.FDI32x4ExtendHighI16x8S:     {"i32x4.extend_high_i16x8_s", 0, 1, 1},
+	wasm.FDI32x4ExtendLowI16x8U:      {"i32x4.extend_low_i16x8_u", 0, 1, 1},
+	wasm.FDI32x4ExtendHighI16x8U:     {"i32x4.extend_high_i16x8_u", 0, 1, 1},
+	wasm.FDI32x4Shl:                  {"i32x4.shl", 0, 2, 1},
+	wasm.FDI32x4ShrS:                 {"i32x4.shr_s", 0, 2, 1},
+	wasm.FDI32x4ShrU:                 {"i32x4.shr_u", 0, 2, 1},
+	wasm.FDI32x4Add:                  {"i32x4.add", 0, 2, 1},
+	wasm.FDI32x4Sub:                  {"i32x4.sub", 0, 2, 1},
+	wasm.FDI32x4Mul:                  {"i32x4.mul", 0, 2, 1},
+	wasm.FDI32x4MinS:                 {"i32x4.min_s", 0, 2, 1},
+	wasm.FDI32x4MinU:                 {"i32x4.min_u", 0, 2, 1},
+	wasm.FDI32x4MaxS:                 {"i32x4.max_s", 0, 2, 1},
+	wasm.FDI32x4MaxU:                 {"i32x4.max_u", 0, 2, 1},
+	wasm.FDI32x4DotI16x8S:            {"i32x4.dot_i16x8_s", 0, 2, 1},
+	wasm.FDI32x4ExtmulLowI16x8S:      {"i32x4.extmul_low_i16x8_s", 0, 2, 1},
+	wasm.FDI32x4ExtmulHighI16x8S:     {"i32x4.extmul_high_i16x8_s", 0, 2, 1},
+	wasm.FDI32x4ExtmulLowI16x8U:      {"i32x4.extmul_low_i16x8_u", 0, 2, 1},
+	wasm.FDI32x4ExtmulHighI16x8U:     {"i32x4.extmul_high_i16x8_u", 0, 2, 1},
+	wasm.FDI64x2Abs:                  {"i64x2.abs", 0, 1, 1},
+	wasm.FDI64x2Neg:                  {"i64x2.neg", 0, 1, 1},
+	wasm.FDI64x2AllTrue:              {"i64x2.all_true", 0, 1, 1},
+	wasm.FDI64x2Bitmask:              {"i64x2.bitmask", 0, 1, 1},
+	wasm.FDI64x2ExtendLowI32x4S:      {"i64x2.extend_low_i32x4_s", 0, 1, 1},
+	wasm.FDI64x2ExtendHighI32x4S:     {"i64x2.extend_high_i32x4_s", 0, 1, 1},
+	wasm.FDI64x2ExtendLowI32x4U:      {"i64x2.extend_low_i32x4_u", 0, 1, 1},
+	wasm.FDI64x2ExtendHighI32x4U:     {"i64x2.extend_high_i32x4_u", 0, 1, 1},
+	wasm.FDI64x2Shl:                  {"i64x2.shl", 0, 2, 1},
+	wasm.FDI64x2ShrS:                 {"i64x2.shr_s", 0, 2, 1},
+	wasm.FDI64x2ShrU:                 {"i64x2.shr_u", 0, 2, 1},
+	wasm.FDI64x2Add:                  {"i64x2.add", 0, 2, 1},
+	wasm.FDI64x2Sub:                  {"i64x2.sub", 0, 2, 1},
+	wasm.FDI64x2Mul:                  {"i64x2.mul", 0, 2, 1},
+	wasm.FDI64x2Eq:                   {"i64x2.eq", 0, 2, 1},
+	wasm.FDI64x2Ne:                   {"i64x2.ne", 0, 2, 1},
+	wasm.FDI64x2LtS:                  {"i64x2.lt_s", 0, 2, 1},
+	wasm.FDI64x2GtS:                  {"i64x2.gt_s", 0, 2, 1},
+	wasm.FDI64x2LeS:                  {"i64x2.le_s", 0, 2, 1},
+	wasm.FDI64x2GeS:                  {"i64x2.ge_s", 0, 2, 1},
+	wasm.FDI64x2ExtmulLowI32x4S:      {"i64x2.extmul_low_i32x4_s", 0, 2, 1},
+	wasm.FDI64x2ExtmulHighI32x4S:     {"i64x2.extmul_high_i32x4_s", 0, 2, 1},
+	wasm.FDI64x2ExtmulLowI32x4U:      {"i64x2.extmul_low_i32x4_u", 0, 2, 1},
+	wasm.FDI64x2ExtmulHighI32x4U:     {"i64x2.extmul_high_i32x4_u", 0, 2, 1},
+	wasm.FDF32x4Abs:                  {"f32x4.abs", 0, 1, 1},
+	wasm.FDF32x4Neg:                  {"f32x4.neg", 0, 1, 1},
+	wasm.FDF32x4Sqrt:                 {"f32x4.sqrt", 0, 1, 1},
+	wasm.FDF32x4Add:                  {"f32x4.add", 0, 2, 1},
+	wasm.FDF32x4Sub:                  {"f32x4.sub", 0, 2, 1},
+	wasm.FDF32x4Mul:                  {"f32x4.mul", 0, 2, 1},
+	wasm.FDF32x4Div:                  {"f32x4.div", 0, 2, 1},
+	wasm.FDF32x4Min:                  {"f32x4.min", 0, 2, 1},
+	wasm.FDF32x4Max:                  {"f32x4.max", 0, 2, 1},
+	wasm.FDF32x4Pmin:                 {"f32x4.pmin", 0, 2, 1},
+	wasm.FDF32x4Pmax:                 {"f32x4.pmax", 0, 2, 1},
+	wasm.FDF64x2Abs:                  {"f64x2.abs", 0, 1, 1},
+	wasm.FDF64x2Neg:                  {"f64x2.neg", 0, 1, 1},
+	wasm.FDF64x2Sqrt:                 {"f64x2.sqrt", 0, 1, 1},
+	wasm.FDF64x2Add:                  {"f64x2.add", 0, 2, 1},
+	wasm.FDF64x2Sub:                  {"f64x2.sub", 0, 2, 1},
+	wasm.FDF64x2Mul:                  {"f64x2.mul", 0, 2, 1},
+	wasm.FDF64x2Div:                  {"f64x2.div", 0, 2, 1},
+	wasm.FDF64x2Min:                  {"f64x2.min", 0, 2, 1},
+	wasm.FDF64x2Max:                  {"f64x2.max", 0, 2, 1},
+	wasm.FDF64x2Pmin:                 {"f64x2.pmin", 0, 2, 1},
+	wasm.FDF64x2Pmax:                 {"f64x2.pmax", 0, 2, 1},
+	wasm.FDI32x4TruncSatF32x4S:       {"i32x4.trunc_sat_f32x4_s", 0, 1, 1},
+	wasm.FDI32x4TruncSatF32x4U:       {"i32x4.trunc_sat_f32x4_u", 0, 1, 1},
+	wasm.FDF32x4ConvertI32x4S:        {"f32x4.convert_i32x4_s", 0, 1, 1},
+	wasm.FDF32x4ConvertI32x4U:        {"f32x4.convert_i32x4_u", 0, 1, 1},
+	wasm.FDI32x4TruncSatF64x2SZero:   {"i32x4.trunc_sat_f64x2_s_zero", 0, 1, 1},
+	wasm.FDI32x4TruncSatF64x2UZero:   {"i32x4.trunc_sat_f64x2_u_zero", 0, 1, 1},
+	wasm.FDF64x2ConvertLowI32x4S:     {"f64x2.convert_low_i32x4_s", 0, 1, 1},
+	wasm.FDF64x2ConvertLowI32x4U:     {"f64x2.convert_low_i32x4_u", 0, 1, 1},
+}
+
+// feOps are FEExt prefixed (threads) opcodes.
+var feOps = [...]opInfo{
+	wasm.FEMemoryAtomicNotify:     {"memory.atomic.notify", 2, 2, 1},
+	wasm.FEMemoryAtomicWait32:     {"memory.atomic.wait32", 2, 3, 1},
+	wasm.FEMemoryAtomicWait64:     {"memory.atomic.wait64", 3, 3, 1},
+	wasm.FEAtomicFence:            {"atomic.fence", 0, 0, 0},
+	wasm.FEI32AtomicLoad:          {"i32.atomic.load", 2, 1, 1},
+	wasm.FEI64AtomicLoad:          {"i64.atomic.load", 3, 1, 1},
+	wasm.FEI32AtomicLoad8U:        {"i32.atomic.load8_u", 0, 1, 1},
+	wasm.FEI32AtomicLoad16U:       {"i32.atomic.load16_u", 1, 1, 1},
+	wasm.FEI64AtomicLoad8U:        {"i64.atomic.load8_u", 0, 1, 1},
+	wasm.FEI64AtomicLoad16U:       {"i64.atomic.load16_u", 1, 1, 1},
+	wasm.FEI64AtomicLoad32U:       {"i64.atomic.load32_u", 2, 1, 1},
+	wasm.FEI32AtomicStore:         {"i32.atomic.store", 2, 2, 0},
+	wasm.FEI64AtomicStore:         {"i64.atomic.store", 3, 2, 0},
+	wasm.FEI32AtomicStore8:        {"i32.atomic.store8", 0, 2, 0},
+	wasm.FEI32AtomicStore16:       {"i32.atomic.store16", 1, 2, 0},
+	wasm.FEI64AtomicStore8:        {"i64.atomic.store8", 0, 2, 0},
+	wasm.FEI64AtomicStore16:       {"i64.atomic.store16", 1, 2, 0},
+	wasm.FEI64AtomicStore32:       {"i64.atomic.store32", 2, 2, 0},
+	wasm.FEI32AtomicRmwAdd:        {"i32.atomic.rmw.add", 2, 2, 1},
+	wasm.FEI64AtomicRmwAdd:        {"i64.atomic.rmw.add", 3, 2, 1},
+	wasm.FEI32AtomicRmw8AddU:      {"i32.atomic.rmw8.add_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16AddU:     {"i32.atomic.rmw16.add_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8AddU:      {"i64.atomic.rmw8.add_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16AddU:     {"i64.atomic.rmw16.add_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32AddU:     {"i64.atomic.rmw32.add_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwSub:        {"i32.atomic.rmw.sub", 2, 2, 1},
+	wasm.FEI64AtomicRmwSub:        {"i64.atomic.rmw.sub", 3, 2, 1},
+	wasm.FEI32AtomicRmw8SubU:      {"i32.atomic.rmw8.sub_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16SubU:     {"i32.atomic.rmw16.sub_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8SubU:      {"i64.atomic.rmw8.sub_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16SubU:     {"i64.atomic.rmw16.sub_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32SubU:     {"i64.atomic.rmw32.sub_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwAnd:        {"i32.atomic.rmw.and", 2, 2, 1},
+	wasm.FEI64AtomicRmwAnd:        {"i64.atomic.rmw.and", 3, 2, 1},
+	wasm.FEI32AtomicRmw8AndU:      {"i32.atomic.rmw8.and_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16AndU:     {"i32.atomic.rmw16.and_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8AndU:      {"i64.atomic.rmw8.and_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16AndU:     {"i64.atomic.rmw16.and_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32AndU:     {"i64.atomic.rmw32.and_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwOr:         {"i32.atomic.rmw.or", 2, 2, 1},
+	wasm.FEI64AtomicRmwOr:         {"i64.atomic.rmw.or", 3, 2, 1},
+	wasm.FEI32AtomicRmw8OrU:       {"i32.atomic.rmw8.or_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16OrU:      {"i32.atomic.rmw16.or_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8OrU:       {"i64.atomic.rmw8.or_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16OrU:      {"i64.atomic.rmw16.or_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32OrU:      {"i64.atomic.rmw32.or_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwXor:        {"i32.atomic.rmw.xor", 2, 2, 1},
+	wasm.FEI64AtomicRmwXor:        {"i64.atomic.rmw.xor", 3, 2, 1},
+	wasm.FEI32AtomicRmw8XorU:      {"i32.atomic.rmw8.xor_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16XorU:     {"i32.atomic.rmw16.xor_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8XorU:      {"i64.atomic.rmw8.xor_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16XorU:     {"i64.atomic.rmw16.xor_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32XorU:     {"i64.atomic.rmw32.xor_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwXchg:       {"i32.atomic.rmw.xchg", 2, 2, 1},
+	wasm.FEI64AtomicRmwXchg:       {"i64.atomic.rmw.xchg", 3, 2, 1},
+	wasm.FEI32AtomicRmw8XchgU:     {"i32.atomic.rmw8.xchg_u", 0, 2, 1},
+	wasm.FEI32AtomicRmw16XchgU:    {"i32.atomic.rmw16.xchg_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw8XchgU:     {"i64.atomic.rmw8.xchg_u", 0, 2, 1},
+	wasm.FEI64AtomicRmw16XchgU:    {"i64.atomic.rmw16.xchg_u", 1, 2, 1},
+	wasm.FEI64AtomicRmw32XchgU:    {"i64.atomic.rmw32.xchg_u", 2, 2, 1},
+	wasm.FEI32AtomicRmwCmpxchg:    {"i32.atomic.rmw.cmpxchg", 2, 3, 1},
+	wasm.FEI64AtomicRmwCmpxchg:    {"i64.atomic.rmw.cmpxchg", 3, 3, 1},
+	wasm.FEI32AtomicRmw8CmpxchgU:  {"i32.atomic.rmw8.cmpxchg_u", 0, 3, 1},
+	wasm.FEI32AtomicRmw16CmpxchgU: {"i32.atomic.rmw16.cmpxchg_u", 1, 3, 1},
+	wasm.FEI64AtomicRmw8CmpxchgU:  {"i64.atomic.rmw8.cmpxchg_u", 0, 3, 1},
+	wasm.FEI64AtomicRmw16CmpxchgU: {"i64.atomic.rmw16.cmpxchg_u", 1, 3, 1},
+	wasm.FEI64AtomicRmw32CmpxchgU: {"i64.atomic.rmw32.cmpxchg_u", 2, 3, 1},
+}
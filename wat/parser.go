@@ -0,0 +1,1143 @@
+package wat
+
+import (
+	"sort"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// Parser parses the text format into a Module.
+	// Both a single (module ...) and a bare list of module fields are accepted.
+	Parser struct {
+		// DebugNames generates the name section from identifiers.
+		DebugNames bool
+
+		src []byte
+		m   *wasm.Module
+		e   wasm.Encoder
+
+		types, funcs, tables, mems, globals, elems, data space
+
+		// item indexes assigned at the declaration pass
+		index map[*sexp]wasm.Index
+
+		// current function
+		locals     space
+		labels     []string // label ids of the enclosing blocks
+		label      wasm.Index
+		labelNames wasm.NameMap
+
+		localNames, allLabels wasm.IndirectNameMap
+
+		usesData bool
+	}
+
+	// space is an index space with identifiers.
+	space struct {
+		ids   map[string]wasm.Index
+		names wasm.NameMap
+	}
+
+	// cursor iterates over list elements.
+	cursor struct {
+		l []*sexp
+		i int
+	}
+)
+
+// Assemble parses text module and encodes it in the binary format.
+func Assemble(text []byte) ([]byte, error) {
+	var p Parser
+	var m wasm.Module
+
+	err := p.Module(text, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.e.Module(nil, &m), nil
+}
+
+// Module parses text and fills m.
+func (p *Parser) Module(text []byte, m *wasm.Module) (err error) {
+	*p = Parser{
+		DebugNames: p.DebugNames,
+		src:        text,
+		m:          m,
+		index:      map[*sexp]wasm.Index{},
+	}
+
+	*m = wasm.Module{
+		Version: 1,
+	}
+
+	fields, err := parseSexps(text)
+	if err != nil {
+		return err
+	}
+
+	var name []byte
+
+	if len(fields) == 1 && fields[0].is("module") {
+		fields = fields[0].list[1:]
+
+		if len(fields) != 0 && fields[0].id() {
+			name = fields[0].text[1:]
+			fields = fields[1:]
+		}
+	}
+
+	for _, f := range fields {
+		if f.kind != sList || f.head() == "" {
+			return p.errorf(f, "module field expected")
+		}
+	}
+
+	err = p.declare(fields)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		err = p.field(f)
+		if err != nil {
+			return errors.Wrap(err, "%v", f.head())
+		}
+	}
+
+	if p.usesData {
+		m.DataCount = len(m.Data)
+		m.HasDataCount = true
+	}
+
+	if p.DebugNames {
+		p.debugNames(name)
+	}
+
+	return nil
+}
+
+// declare assigns indexes and identifiers to all the module items
+// so they can be referenced before they are defined.
+// Imported items go first in each index space.
+func (p *Parser) declare(fields []*sexp) (err error) {
+	var imported, defined [4]wasm.Index
+	var elems, datas wasm.Index
+
+	for _, f := range fields {
+		if kind, imp, ok := fieldKind(f); ok && imp {
+			defined[kind]++
+		}
+	}
+
+	spaces := [4]*space{&p.funcs, &p.tables, &p.mems, &p.globals}
+
+	for _, f := range fields {
+		switch f.head() {
+		case "type":
+			c := cursor{l: f.list[1:]}
+
+			err = p.types.define(p, c.optID(), wasm.Index(len(p.m.Type)))
+			if err != nil {
+				return err
+			}
+
+			s := c.next()
+			if s == nil || !s.is("func") || !c.done() {
+				return p.errorf(f, "func type expected")
+			}
+
+			fc := cursor{l: s.list[1:]}
+
+			ft, _, _, _, err := p.funcType(&fc, false)
+			if err != nil {
+				return err
+			}
+
+			if !fc.done() {
+				return p.errorf(fc.peek(), "unexpected token")
+			}
+
+			p.m.Type = append(p.m.Type, ft)
+
+			continue
+		case "elem":
+			err = p.elems.define(p, (&cursor{l: f.list[1:]}).optID(), elems)
+			elems++
+		case "data":
+			err = p.data.define(p, (&cursor{l: f.list[1:]}).optID(), datas)
+			datas++
+		case "table":
+			if hasInline(f, "elem") {
+				elems++
+			}
+		case "memory":
+			if hasInline(f, "data") {
+				datas++
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		kind, imp, ok := fieldKind(f)
+		if !ok {
+			continue
+		}
+
+		id := (&cursor{l: f.list[1:]}).optID()
+		if f.is("import") {
+			if len(f.list) < 4 || f.list[3].kind != sList {
+				return p.errorf(f, "import description expected")
+			}
+
+			id = (&cursor{l: f.list[3].list[1:]}).optID()
+		}
+
+		var x wasm.Index
+
+		if imp {
+			x = imported[kind]
+			imported[kind]++
+		} else {
+			x = defined[kind]
+			defined[kind]++
+		}
+
+		p.index[f] = x
+
+		err = spaces[kind].define(p, id, x)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) field(f *sexp) (err error) {
+	c := &cursor{l: f.list[1:]}
+
+	switch f.head() {
+	case "type":
+		return nil
+	case "import":
+		mod, name, err := p.importName(f, c)
+		if err != nil {
+			return err
+		}
+
+		desc := c.next()
+		if desc == nil || desc.kind != sList || !c.done() {
+			return p.errorf(f, "import description expected")
+		}
+
+		dc := &cursor{l: desc.list[1:]}
+		dc.optID()
+
+		return p.importDesc(desc, desc.head(), dc, mod, name)
+	case "func", "table", "memory", "global":
+		return p.item(f, c)
+	case "export":
+		s := c.next()
+		if s == nil || s.kind != sString {
+			return p.errorf(f, "export name expected")
+		}
+
+		desc := c.next()
+		if desc == nil || desc.kind != sList || len(desc.list) != 2 || !c.done() {
+			return p.errorf(f, "export description expected")
+		}
+
+		var sp *space
+		var kind byte
+
+		switch desc.head() {
+		case "func":
+			sp, kind = &p.funcs, wasm.ExternFunc
+		case "table":
+			sp, kind = &p.tables, wasm.ExternTable
+		case "memory":
+			sp, kind = &p.mems, wasm.ExternMemory
+		case "global":
+			sp, kind = &p.globals, wasm.ExternGlobal
+		default:
+			return p.errorf(desc, "unsupported export kind")
+		}
+
+		x, err := sp.index(p, desc.list[1])
+		if err != nil {
+			return err
+		}
+
+		p.m.Export = append(p.m.Export, wasm.Export{Name: s.text, ExportType: kind, Index: x})
+
+		return nil
+	case "start":
+		if len(f.list) != 2 {
+			return p.errorf(f, "function index expected")
+		}
+
+		p.m.Start, err = p.funcs.index(p, f.list[1])
+		p.m.HasStart = true
+
+		return err
+	case "elem":
+		return p.element(f, c)
+	case "data":
+		return p.dataSegment(f, c)
+	default:
+		return p.errorf(f, "unsupported module field")
+	}
+}
+
+// item parses func, table, memory or global definition or inline import.
+func (p *Parser) item(f *sexp, c *cursor) (err error) {
+	kind, _, _ := fieldKind(f)
+	x := p.index[f]
+
+	c.optID()
+
+	for c.peek() != nil && c.peek().is("export") {
+		s := c.next()
+
+		if len(s.list) != 2 || s.list[1].kind != sString {
+			return p.errorf(s, "export name expected")
+		}
+
+		p.m.Export = append(p.m.Export, wasm.Export{Name: s.list[1].text, ExportType: kind, Index: x})
+	}
+
+	if s := c.peek(); s != nil && s.is("import") {
+		c.next()
+
+		mod, name, err := p.importName(s, &cursor{l: s.list[1:]})
+		if err != nil {
+			return err
+		}
+
+		return p.importDesc(f, f.head(), c, mod, name)
+	}
+
+	switch f.head() {
+	case "func":
+		return p.function(f, x, c)
+	case "table":
+		return p.table(f, x, c)
+	case "memory":
+		return p.memory(f, x, c)
+	default:
+		g, err := p.globalType(f, c)
+		if err != nil {
+			return err
+		}
+
+		g.Expr, err = p.constExpr(c)
+		if err != nil {
+			return err
+		}
+
+		p.m.Global = append(p.m.Global, g)
+
+		return nil
+	}
+}
+
+func (p *Parser) importName(f *sexp, c *cursor) (mod, name []byte, err error) {
+	m, n := c.next(), c.next()
+
+	if m == nil || n == nil || m.kind != sString || n.kind != sString {
+		return nil, nil, p.errorf(f, "import module and name expected")
+	}
+
+	return m.text, n.text, nil
+}
+
+func (p *Parser) importDesc(f *sexp, kind string, c *cursor, mod, name []byte) (err error) {
+	im := wasm.Import{Module: mod, Name: name}
+
+	switch kind {
+	case "func":
+		x, _, err := p.typeUse(c)
+		if err != nil {
+			return err
+		}
+
+		im.SetFunc(x)
+	case "table":
+		t, err := p.tableType(f, c)
+		if err != nil {
+			return err
+		}
+
+		im.SetTable(t)
+	case "memory":
+		l, err := p.limits(f, c, true)
+		if err != nil {
+			return err
+		}
+
+		im.SetMemory(l)
+	case "global":
+		g, err := p.globalType(f, c)
+		if err != nil {
+			return err
+		}
+
+		im.SetGlobal(g)
+	default:
+		return p.errorf(f, "unsupported import kind")
+	}
+
+	if !c.done() {
+		return p.errorf(c.peek(), "unexpected token")
+	}
+
+	p.m.Import = append(p.m.Import, im)
+
+	return nil
+}
+
+func (p *Parser) function(f *sexp, fn wasm.Index, c *cursor) (err error) {
+	tp, params, err := p.typeUse(c)
+	if err != nil {
+		return err
+	}
+
+	p.locals = space{}
+	p.labels = p.labels[:0]
+	p.label = 0
+	p.labelNames = nil
+
+	for j, id := range params {
+		err = p.locals.define(p, id, wasm.Index(j))
+		if err != nil {
+			return err
+		}
+	}
+
+	var locals wasm.ResultType
+
+	for c.peek() != nil && c.peek().is("local") {
+		s := c.next()
+
+		_, err = p.vars(s, &locals, len(params))
+		if err != nil {
+			return err
+		}
+	}
+
+	b := p.e.Int(nil, localGroups(locals))
+
+	for j := 0; j < len(locals); {
+		k := j
+		for k < len(locals) && locals[k] == locals[j] {
+			k++
+		}
+
+		b = p.e.Int(b, k-j)
+		b = append(b, byte(locals[j]))
+
+		j = k
+	}
+
+	b, err = p.instrs(b, c)
+	if err != nil {
+		return err
+	}
+
+	if len(p.labels) != 0 {
+		return p.errorf(f, "unclosed block")
+	}
+
+	b = append(b, wasm.End)
+
+	p.m.Function = append(p.m.Function, tp)
+	p.m.Code = append(p.m.Code, b)
+
+	if len(p.locals.names) != 0 {
+		p.localNames = append(p.localNames, wasm.IndirectNaming{Index: fn, Names: p.locals.names})
+	}
+
+	if len(p.labelNames) != 0 {
+		p.allLabels = append(p.allLabels, wasm.IndirectNaming{Index: fn, Names: p.labelNames})
+	}
+
+	return nil
+}
+
+func localGroups(l wasm.ResultType) (n int) {
+	for j := range l {
+		if j == 0 || l[j] != l[j-1] {
+			n++
+		}
+	}
+
+	return n
+}
+
+// vars parses param or local declaration appending types to tps.
+// It returns identifiers of the declared variables, empty for the unnamed ones.
+func (p *Parser) vars(s *sexp, tps *wasm.ResultType, base int) (ids []*sexp, err error) {
+	c := cursor{l: s.list[1:]}
+
+	if id := c.optID(); id != nil {
+		tp, err := p.valueType(c.next(), s)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.done() {
+			return nil, p.errorf(c.peek(), "single type expected after identifier")
+		}
+
+		*tps = append(*tps, tp)
+
+		if s.is("local") {
+			err = p.locals.define(p, id, wasm.Index(base+len(*tps)-1))
+		}
+
+		return []*sexp{id}, err
+	}
+
+	for !c.done() {
+		tp, err := p.valueType(c.next(), s)
+		if err != nil {
+			return nil, err
+		}
+
+		*tps = append(*tps, tp)
+		ids = append(ids, nil)
+	}
+
+	return ids, nil
+}
+
+// typeUse parses function type reference and inline params and results.
+// It returns type index and param identifiers.
+func (p *Parser) typeUse(c *cursor) (x wasm.Index, params []*sexp, err error) {
+	st := c.peek()
+
+	ft, params, x, explicit, err := p.funcType(c, true)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !explicit {
+		return p.findType(ft), params, nil
+	}
+
+	if int(x) >= len(p.m.Type) {
+		return 0, nil, p.errorf(st, "type index out of range: %d", x)
+	}
+
+	tp := p.m.Type[x]
+
+	if len(ft.Params) == 0 && len(ft.Result) == 0 {
+		return x, make([]*sexp, len(tp.Params)), nil
+	}
+
+	if !equalTypes(tp.Params, ft.Params) || !equalTypes(tp.Result, ft.Result) {
+		return 0, nil, p.errorf(st, "inline function type doesn't match type %d", x)
+	}
+
+	return x, params, nil
+}
+
+// funcType parses (type x)? (param ...)* (result ...)*.
+func (p *Parser) funcType(c *cursor, typeRef bool) (ft wasm.FuncType, params []*sexp, x wasm.Index, explicit bool, err error) {
+	if s := c.peek(); typeRef && s != nil && s.is("type") {
+		c.next()
+
+		if len(s.list) != 2 {
+			return ft, nil, 0, false, p.errorf(s, "type index expected")
+		}
+
+		x, err = p.types.index(p, s.list[1])
+		if err != nil {
+			return
+		}
+
+		explicit = true
+	}
+
+	for c.peek() != nil && c.peek().is("param") {
+		ids, err := p.vars(c.next(), &ft.Params, 0)
+		if err != nil {
+			return ft, nil, 0, false, err
+		}
+
+		params = append(params, ids...)
+	}
+
+	for c.peek() != nil && c.peek().is("result") {
+		s := c.next()
+
+		for _, t := range s.list[1:] {
+			tp, err := p.valueType(t, s)
+			if err != nil {
+				return ft, nil, 0, false, err
+			}
+
+			ft.Result = append(ft.Result, tp)
+		}
+	}
+
+	return ft, params, x, explicit, nil
+}
+
+// findType returns index of the first matching type adding it if there is no one.
+func (p *Parser) findType(ft wasm.FuncType) wasm.Index {
+	for j, tp := range p.m.Type {
+		if equalTypes(tp.Params, ft.Params) && equalTypes(tp.Result, ft.Result) {
+			return wasm.Index(j)
+		}
+	}
+
+	p.m.Type = append(p.m.Type, ft)
+
+	return wasm.Index(len(p.m.Type) - 1)
+}
+
+func (p *Parser) table(f *sexp, x wasm.Index, c *cursor) (err error) {
+	if s := c.peek(); s != nil && s.atom() && isRefType(s.text) {
+		elem := c.next()
+
+		el := c.next()
+		if el == nil || !el.is("elem") || !c.done() {
+			return p.errorf(f, "inline elem expected")
+		}
+
+		tp, _ := p.valueType(elem, f)
+
+		seg := wasm.Element{
+			Mode:  wasm.ElemActive,
+			Table: x,
+			Type:  tp,
+			Expr:  wasm.Code{wasm.I32Const, 0, wasm.End},
+		}
+
+		err = p.elemList(el, &cursor{l: el.list[1:]}, &seg, tp)
+		if err != nil {
+			return err
+		}
+
+		n := len(seg.Funcs) + len(seg.Init)
+
+		p.m.Table = append(p.m.Table, wasm.Table{Type: tp, Limits: wasm.Limits{Lo: n, Hi: n}})
+		p.m.Element = append(p.m.Element, seg)
+
+		return nil
+	}
+
+	t, err := p.tableType(f, c)
+	if err != nil {
+		return err
+	}
+
+	if !c.done() {
+		return p.errorf(c.peek(), "unexpected token")
+	}
+
+	p.m.Table = append(p.m.Table, t)
+
+	return nil
+}
+
+func (p *Parser) memory(f *sexp, x wasm.Index, c *cursor) (err error) {
+	if s := c.peek(); s != nil && s.is("data") {
+		c.next()
+
+		var init []byte
+
+		for _, d := range s.list[1:] {
+			if d.kind != sString {
+				return p.errorf(d, "string expected")
+			}
+
+			init = append(init, d.text...)
+		}
+
+		const page = 1 << 16
+		n := (len(init) + page - 1) / page
+
+		p.m.Memory = append(p.m.Memory, wasm.Limits{Lo: n, Hi: n})
+		p.m.Data = append(p.m.Data, wasm.Data{
+			Mode:   wasm.DataActive,
+			Memory: x,
+			Expr:   wasm.Code{wasm.I32Const, 0, wasm.End},
+			Init:   init,
+		})
+
+		return nil
+	}
+
+	l, err := p.limits(f, c, true)
+	if err != nil {
+		return err
+	}
+
+	if !c.done() {
+		return p.errorf(c.peek(), "unexpected token")
+	}
+
+	p.m.Memory = append(p.m.Memory, l)
+
+	return nil
+}
+
+func (p *Parser) element(f *sexp, c *cursor) (err error) {
+	c.optID()
+
+	el := wasm.Element{Mode: wasm.ElemPassive}
+
+	if s := c.peek(); s != nil && s.atom() && string(s.text) == "declare" {
+		c.next()
+		el.Mode = wasm.ElemDeclarative
+	}
+
+	if s := c.peek(); s != nil && s.is("table") {
+		c.next()
+
+		if len(s.list) != 2 {
+			return p.errorf(s, "table index expected")
+		}
+
+		el.Table, err = p.tables.index(p, s.list[1])
+		if err != nil {
+			return err
+		}
+
+		el.Mode = wasm.ElemActive
+	}
+
+	if s := c.peek(); s != nil && (s.is("offset") || s.kind == sList && isInstr(s.head())) {
+		c.next()
+
+		el.Mode = wasm.ElemActive
+
+		el.Expr, err = p.offset(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	if el.Mode == wasm.ElemActive && el.Expr == nil {
+		return p.errorf(f, "offset expected")
+	}
+
+	tp := wasm.Type(wasm.FuncRef)
+
+	if s := c.peek(); s != nil && s.atom() && isRefType(s.text) {
+		c.next()
+
+		tp, _ = p.valueType(s, f)
+	}
+
+	el.Type = tp
+
+	err = p.elemList(f, c, &el, tp)
+	if err != nil {
+		return err
+	}
+
+	p.m.Element = append(p.m.Element, el)
+
+	return nil
+}
+
+// elemList parses 'func' funcidx* or elemexpr* list.
+func (p *Parser) elemList(f *sexp, c *cursor, el *wasm.Element, tp wasm.Type) (err error) {
+	if s := c.peek(); s != nil && s.atom() && string(s.text) == "func" {
+		c.next()
+	}
+
+	for !c.done() {
+		s := c.next()
+
+		if s.kind != sList {
+			x, err := p.funcs.index(p, s)
+			if err != nil {
+				return err
+			}
+
+			el.Funcs = append(el.Funcs, x)
+
+			continue
+		}
+
+		items := []*sexp{s}
+		if s.is("item") {
+			items = s.list[1:]
+		}
+
+		expr, err := p.constExpr(&cursor{l: items})
+		if err != nil {
+			return err
+		}
+
+		el.Init = append(el.Init, expr)
+	}
+
+	if len(el.Funcs) != 0 && len(el.Init) != 0 {
+		return p.errorf(f, "mixed function indexes and expressions")
+	}
+
+	return nil
+}
+
+func (p *Parser) dataSegment(f *sexp, c *cursor) (err error) {
+	c.optID()
+
+	d := wasm.Data{Mode: wasm.DataPassive}
+
+	if s := c.peek(); s != nil && s.is("memory") {
+		c.next()
+
+		if len(s.list) != 2 {
+			return p.errorf(s, "memory index expected")
+		}
+
+		d.Memory, err = p.mems.index(p, s.list[1])
+		if err != nil {
+			return err
+		}
+
+		d.Mode = wasm.DataActive
+	}
+
+	if s := c.peek(); s != nil && (s.is("offset") || s.kind == sList && isInstr(s.head())) {
+		c.next()
+
+		d.Mode = wasm.DataActive
+
+		d.Expr, err = p.offset(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.Mode == wasm.DataActive && d.Expr == nil {
+		return p.errorf(f, "offset expected")
+	}
+
+	d.Init = []byte{}
+
+	for !c.done() {
+		s := c.next()
+
+		if s.kind != sString {
+			return p.errorf(s, "string expected")
+		}
+
+		d.Init = append(d.Init, s.text...)
+	}
+
+	p.m.Data = append(p.m.Data, d)
+
+	return nil
+}
+
+// offset parses (offset instr*) or a single folded instruction.
+func (p *Parser) offset(s *sexp) (wasm.Code, error) {
+	if s.is("offset") {
+		return p.constExpr(&cursor{l: s.list[1:]})
+	}
+
+	return p.constExpr(&cursor{l: []*sexp{s}})
+}
+
+// constExpr parses the rest of c as instructions adding End.
+func (p *Parser) constExpr(c *cursor) (wasm.Code, error) {
+	p.labels = p.labels[:0]
+
+	b, err := p.instrs(nil, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, wasm.End), nil
+}
+
+func (p *Parser) tableType(f *sexp, c *cursor) (t wasm.Table, err error) {
+	t.Limits, err = p.limits(f, c, false)
+	if err != nil {
+		return t, err
+	}
+
+	s := c.next()
+	if s == nil || !s.atom() || !isRefType(s.text) {
+		return t, p.errorf(f, "reference type expected")
+	}
+
+	t.Type, err = p.valueType(s, f)
+
+	return t, err
+}
+
+func (p *Parser) limits(f *sexp, c *cursor, memory bool) (l wasm.Limits, err error) {
+	s := c.next()
+	if s == nil || !s.atom() {
+		return l, p.errorf(f, "limits expected")
+	}
+
+	lo, err := parseUint(s.text, 32)
+	if err != nil {
+		return l, p.wrap(s, err)
+	}
+
+	l.Lo, l.Hi = int(lo), -1
+
+	if s := c.peek(); s != nil && s.atom() && isDigit(s.text) {
+		c.next()
+
+		hi, err := parseUint(s.text, 32)
+		if err != nil {
+			return l, p.wrap(s, err)
+		}
+
+		l.Hi = int(hi)
+	}
+
+	if s := c.peek(); memory && s != nil && s.atom() && string(s.text) == "shared" {
+		c.next()
+		l.Shared = true
+	}
+
+	return l, nil
+}
+
+func (p *Parser) globalType(f *sexp, c *cursor) (g wasm.Global, err error) {
+	s := c.next()
+	if s == nil {
+		return g, p.errorf(f, "global type expected")
+	}
+
+	if s.is("mut") {
+		if len(s.list) != 2 {
+			return g, p.errorf(s, "value type expected")
+		}
+
+		g.Mut = 1
+		s = s.list[1]
+	}
+
+	g.Type, err = p.valueType(s, f)
+
+	return g, err
+}
+
+func (p *Parser) valueType(s, parent *sexp) (wasm.Type, error) {
+	if s == nil {
+		return 0, p.errorf(parent, "value type expected")
+	}
+
+	if s.atom() {
+		switch string(s.text) {
+		case "i32":
+			return wasm.I32, nil
+		case "i64":
+			return wasm.I64, nil
+		case "f32":
+			return wasm.F32, nil
+		case "f64":
+			return wasm.F64, nil
+		case "v128":
+			return wasm.V128, nil
+		case "funcref", "anyfunc":
+			return wasm.FuncRef, nil
+		case "externref":
+			return wasm.ExternRef, nil
+		}
+	}
+
+	return 0, p.errorf(s, "unsupported value type")
+}
+
+func (p *Parser) debugNames(module []byte) {
+	n := wasm.Names{
+		Module:   module,
+		Funcs:    p.funcs.sorted(),
+		Locals:   p.localNames,
+		Labels:   p.allLabels,
+		Types:    p.types.sorted(),
+		Tables:   p.tables.sorted(),
+		Memories: p.mems.sorted(),
+		Globals:  p.globals.sorted(),
+		Elems:    p.elems.sorted(),
+		Data:     p.data.sorted(),
+	}
+
+	sort.Slice(n.Locals, func(i, j int) bool { return n.Locals[i].Index < n.Locals[j].Index })
+	sort.Slice(n.Labels, func(i, j int) bool { return n.Labels[i].Index < n.Labels[j].Index })
+
+	p.m.Custom = append(p.m.Custom, wasm.Custom{
+		Name: []byte(wasm.NameSectionName),
+		Data: p.e.Names(nil, &n),
+	})
+}
+
+func (p *Parser) errorf(s *sexp, format string, args ...interface{}) error {
+	if s == nil {
+		return errors.New(format+" at the end of list", args...)
+	}
+
+	return posError(p.src, s.pos, errors.New(format, args...))
+}
+
+func (p *Parser) wrap(s *sexp, err error) error {
+	return posError(p.src, s.pos, err)
+}
+
+// define adds identifier if id is not nil.
+func (sp *space) define(p *Parser, id *sexp, x wasm.Index) error {
+	if id == nil {
+		return nil
+	}
+
+	if sp.ids == nil {
+		sp.ids = map[string]wasm.Index{}
+	}
+
+	name := string(id.text)
+
+	if _, ok := sp.ids[name]; ok {
+		return p.errorf(id, "duplicate identifier: %s", name)
+	}
+
+	sp.ids[name] = x
+	sp.names = append(sp.names, wasm.Naming{Index: x, Name: id.text[1:]})
+
+	return nil
+}
+
+// index resolves identifier or numeric index.
+func (sp *space) index(p *Parser, s *sexp) (wasm.Index, error) {
+	if s == nil || !s.atom() {
+		return 0, p.errorf(s, "index expected")
+	}
+
+	if s.id() {
+		x, ok := sp.ids[string(s.text)]
+		if !ok {
+			return 0, p.errorf(s, "unknown identifier: %s", s.text)
+		}
+
+		return x, nil
+	}
+
+	x, err := parseUint(s.text, 32)
+	if err != nil {
+		return 0, p.wrap(s, err)
+	}
+
+	return wasm.Index(x), nil
+}
+
+func (sp *space) sorted() wasm.NameMap {
+	sort.Slice(sp.names, func(i, j int) bool { return sp.names[i].Index < sp.names[j].Index })
+
+	return sp.names
+}
+
+// fieldKind returns the extern kind of func, table, memory, global or import field
+// and whether it's imported.
+func fieldKind(f *sexp) (kind byte, imported, ok bool) {
+	name := f.head()
+
+	if name == "import" {
+		if len(f.list) < 4 {
+			return 0, false, false
+		}
+
+		name = f.list[3].head()
+		imported = true
+	} else {
+		imported = hasInline(f, "import")
+	}
+
+	switch name {
+	case "func":
+		return wasm.ExternFunc, imported, true
+	case "table":
+		return wasm.ExternTable, imported, true
+	case "memory":
+		return wasm.ExternMemory, imported, true
+	case "global":
+		return wasm.ExternGlobal, imported, true
+	}
+
+	return 0, false, false
+}
+
+// hasInline reports whether the field has inline import, elem or data.
+func hasInline(f *sexp, kw string) bool {
+	for _, s := range f.list[1:] {
+		if s.is(kw) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRefType(s []byte) bool {
+	switch string(s) {
+	case "funcref", "externref", "anyfunc":
+		return true
+	}
+
+	return false
+}
+
+func isDigit(s []byte) bool {
+	return len(s) != 0 && s[0] >= '0' && s[0] <= '9'
+}
+
+func equalTypes(a, b wasm.ResultType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for j := range a {
+		if a[j] != b[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *cursor) peek() *sexp {
+	if c.i == len(c.l) {
+		return nil
+	}
+
+	return c.l[c.i]
+}
+
+func (c *cursor) next() *sexp {
+	s := c.peek()
+	if s != nil {
+		c.i++
+	}
+
+	return s
+}
+
+func (c *cursor) done() bool {
+	return c.i == len(c.l)
+}
+
+// optID consumes identifier if there is one.
+func (c *cursor) optID() *sexp {
+	if s := c.peek(); s != nil && s.id() {
+		c.i++
+		return s
+	}
+
+	return nil
+}
@@ -0,0 +1,751 @@
+package wat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strconv"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// instrDef is an instruction found by its name.
+	instrDef struct {
+		op   wasm.Opcode
+		ext  uint32
+		info opInfo
+	}
+)
+
+var instrByName = map[string]instrDef{}
+
+func init() {
+	for op, info := range ops {
+		switch {
+		case info.name == "", op == wasm.SelectT:
+			continue
+		}
+
+		instrByName[info.name] = instrDef{op: wasm.Opcode(op), info: info}
+	}
+
+	for _, t := range []struct {
+		op  wasm.Opcode
+		tab []opInfo
+	}{
+		{wasm.FCExt, fcOps[:]},
+		{wasm.FDExt, fdOps[:]},
+		{wasm.FEExt, feOps[:]},
+	} {
+		for ext, info := range t.tab {
+			if info.name != "" {
+				instrByName[info.name] = instrDef{op: t.op, ext: uint32(ext), info: info}
+			}
+		}
+	}
+}
+
+// isInstr reports whether name is an instruction name.
+func isInstr(name string) bool {
+	_, ok := instrByName[name]
+
+	return ok
+}
+
+// instrs parses flat and folded instructions until the end of c.
+func (p *Parser) instrs(b []byte, c *cursor) (_ []byte, err error) {
+	for !c.done() {
+		s := c.next()
+
+		switch s.kind {
+		case sList:
+			b, err = p.folded(b, s)
+		case sAtom:
+			b, err = p.plain(b, s, c)
+		default:
+			err = p.errorf(s, "instruction expected")
+		}
+
+		if err != nil {
+			return b, err
+		}
+	}
+
+	return b, nil
+}
+
+// plain parses flat instruction s with immediates taken from c.
+func (p *Parser) plain(b []byte, s *sexp, c *cursor) (_ []byte, err error) {
+	switch name := string(s.text); name {
+	case "block", "loop", "if":
+		in, err := p.block(s, c)
+		if err != nil {
+			return b, err
+		}
+
+		return p.e.Instruction(b, in), nil
+	case "else", "end":
+		if len(p.labels) == 0 {
+			return b, p.errorf(s, "%v outside of block", name)
+		}
+
+		if id := c.optID(); id != nil && string(id.text) != p.labels[len(p.labels)-1] {
+			return b, p.errorf(id, "label mismatch")
+		}
+
+		if name == "else" {
+			return append(b, wasm.Else), nil
+		}
+
+		p.labels = p.labels[:len(p.labels)-1]
+
+		return append(b, wasm.End), nil
+	}
+
+	in, err := p.instr(s, c)
+	if err != nil {
+		return b, err
+	}
+
+	return p.e.Instruction(b, in), nil
+}
+
+// folded parses folded instruction with its operands and nested blocks.
+func (p *Parser) folded(b []byte, s *sexp) (_ []byte, err error) {
+	if len(s.list) == 0 || !s.list[0].atom() {
+		return b, p.errorf(s, "instruction expected")
+	}
+
+	op := s.list[0]
+	c := &cursor{l: s.list[1:]}
+
+	switch string(op.text) {
+	case "block", "loop":
+		in, err := p.block(op, c)
+		if err != nil {
+			return b, err
+		}
+
+		b = p.e.Instruction(b, in)
+
+		b, err = p.instrs(b, c)
+		if err != nil {
+			return b, err
+		}
+
+		p.labels = p.labels[:len(p.labels)-1]
+
+		return append(b, wasm.End), nil
+	case "if":
+		label := c.optID()
+
+		bt, err := p.blockType(c)
+		if err != nil {
+			return b, err
+		}
+
+		for c.peek() != nil && c.peek().kind == sList && !c.peek().is("then") {
+			b, err = p.folded(b, c.next())
+			if err != nil {
+				return b, err
+			}
+		}
+
+		p.pushLabel(label)
+
+		b = p.e.Instruction(b, wasm.Instruction{Opcode: wasm.If, BlockType: bt})
+
+		then := c.next()
+		if then == nil || !then.is("then") {
+			return b, p.errorf(s, "then expected")
+		}
+
+		b, err = p.instrs(b, &cursor{l: then.list[1:]})
+		if err != nil {
+			return b, err
+		}
+
+		if els := c.next(); els != nil {
+			if !els.is("else") || !c.done() {
+				return b, p.errorf(els, "else expected")
+			}
+
+			b = append(b, wasm.Else)
+
+			b, err = p.instrs(b, &cursor{l: els.list[1:]})
+			if err != nil {
+				return b, err
+			}
+		}
+
+		p.labels = p.labels[:len(p.labels)-1]
+
+		return append(b, wasm.End), nil
+	}
+
+	in, err := p.instr(op, c)
+	if err != nil {
+		return b, err
+	}
+
+	for !c.done() {
+		x := c.next()
+
+		if x.kind != sList {
+			return b, p.errorf(x, "folded instruction expected")
+		}
+
+		b, err = p.folded(b, x)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	return p.e.Instruction(b, in), nil
+}
+
+// block parses block, loop or if label and block type and pushes the label.
+func (p *Parser) block(s *sexp, c *cursor) (in wasm.Instruction, err error) {
+	in.Opcode = instrByName[string(s.text)].op
+
+	label := c.optID()
+
+	in.BlockType, err = p.blockType(c)
+	if err != nil {
+		return in, err
+	}
+
+	p.pushLabel(label)
+
+	return in, nil
+}
+
+func (p *Parser) pushLabel(id *sexp) {
+	var name string
+
+	if id != nil {
+		name = string(id.text)
+
+		p.labelNames = append(p.labelNames, wasm.Naming{Index: p.label, Name: id.text[1:]})
+	}
+
+	p.labels = append(p.labels, name)
+	p.label++
+}
+
+func (p *Parser) blockType(c *cursor) (bt wasm.BlockType, err error) {
+	st := c.peek()
+
+	ft, _, x, explicit, err := p.funcType(c, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if explicit {
+		if int(x) >= len(p.m.Type) {
+			return 0, p.errorf(st, "type index out of range: %d", x)
+		}
+
+		return wasm.TypeIndexBlockType(x), nil
+	}
+
+	switch {
+	case len(ft.Params) != 0 || len(ft.Result) > 1:
+		return wasm.TypeIndexBlockType(p.findType(ft)), nil
+	case len(ft.Result) == 1:
+		return wasm.ValueBlockType(ft.Result[0]), nil
+	default:
+		return wasm.BlockEmpty, nil
+	}
+}
+
+// instr parses plain instruction immediates.
+func (p *Parser) instr(s *sexp, c *cursor) (in wasm.Instruction, err error) {
+	def, ok := instrByName[string(s.text)]
+	if !ok {
+		return in, p.errorf(s, "unknown instruction: %s", s.text)
+	}
+
+	in.Opcode = def.op
+	in.Ext = def.ext
+
+	switch op := def.op; {
+	case op == wasm.Block || op == wasm.Loop || op == wasm.If || op == wasm.Else || op == wasm.End:
+		return in, p.errorf(s, "unexpected %s", s.text)
+	case op == wasm.Br || op == wasm.BrIf:
+		in.Label, err = p.labelIndex(s, c.next())
+	case op == wasm.BrTable:
+		for p.peekIndex(c) {
+			var x wasm.Index
+
+			x, err = p.labelIndex(s, c.next())
+			if err != nil {
+				return in, err
+			}
+
+			in.Labels = append(in.Labels, x)
+		}
+
+		if len(in.Labels) == 0 {
+			return in, p.errorf(s, "label expected")
+		}
+
+		in.Label = in.Labels[len(in.Labels)-1]
+		in.Labels = in.Labels[:len(in.Labels)-1]
+	case op == wasm.Call || op == wasm.RefFunc:
+		in.Index, err = p.funcs.index(p, c.next())
+	case op == wasm.CallIndir:
+		if p.peekIndex(c) {
+			in.Table, err = p.tables.index(p, c.next())
+			if err != nil {
+				return in, err
+			}
+		}
+
+		var params []*sexp
+
+		in.Index, params, err = p.typeUse(c)
+
+		for _, id := range params {
+			if id != nil {
+				return in, p.errorf(id, "named params are not allowed here")
+			}
+		}
+	case op == wasm.Select:
+		for c.peek() != nil && c.peek().is("result") {
+			in.Opcode = wasm.SelectT
+
+			r := c.next()
+
+			for _, t := range r.list[1:] {
+				tp, err := p.valueType(t, r)
+				if err != nil {
+					return in, err
+				}
+
+				in.Types = append(in.Types, tp)
+			}
+		}
+	case op >= wasm.LocalGet && op <= wasm.LocalTee:
+		in.Index, err = p.locals.index(p, c.next())
+	case op == wasm.GlobalGet || op == wasm.GlobalSet:
+		in.Index, err = p.globals.index(p, c.next())
+	case op == wasm.TableGet || op == wasm.TableSet:
+		in.Table, err = p.optIndex(&p.tables, c)
+	case op >= wasm.I32Load && op <= wasm.I64Store32:
+		in.MemArg, err = p.memArg(c, def.info.align)
+	case op == wasm.MemorySize || op == wasm.MemoryGrow:
+		in.Memory, err = p.optIndex(&p.mems, c)
+	case op == wasm.I32Const:
+		var v uint64
+		v, err = p.intArg(s, c.next(), 32)
+		in.I32 = int32(uint32(v))
+	case op == wasm.I64Const:
+		var v uint64
+		v, err = p.intArg(s, c.next(), 64)
+		in.I64 = int64(v)
+	case op == wasm.F32Const:
+		var v uint64
+		v, err = p.floatArg(s, c.next(), 32)
+		in.F32 = math.Float32frombits(uint32(v))
+	case op == wasm.F64Const:
+		var v uint64
+		v, err = p.floatArg(s, c.next(), 64)
+		in.F64 = math.Float64frombits(v)
+	case op == wasm.RefNull:
+		x := c.next()
+
+		switch {
+		case x == nil || !x.atom():
+			err = p.errorf(s, "heap type expected")
+		case string(x.text) == "func":
+			in.RefType = wasm.FuncRef
+		case string(x.text) == "extern":
+			in.RefType = wasm.ExternRef
+		default:
+			err = p.errorf(x, "unsupported heap type")
+		}
+	case op == wasm.FCExt:
+		err = p.fcImmediates(s, c, &in)
+	case op == wasm.FDExt:
+		err = p.fdImmediates(s, c, &in, def.info)
+	case op == wasm.FEExt:
+		if in.Ext != wasm.FEAtomicFence {
+			in.MemArg, err = p.memArg(c, def.info.align)
+		}
+	}
+
+	if err != nil {
+		return in, err
+	}
+
+	return in, nil
+}
+
+func (p *Parser) fcImmediates(s *sexp, c *cursor, in *wasm.Instruction) (err error) {
+	if in.Ext <= wasm.FCI64TruncSatF64U {
+		return nil
+	}
+
+	var x []*sexp
+
+	for p.peekIndex(c) && len(x) < 2 {
+		x = append(x, c.next())
+	}
+
+	// first, second index
+	idx := func(sp1, sp2 *space, dst1, dst2 *wasm.Index, min, max int) (err error) {
+		if len(x) < min || len(x) > max {
+			return p.errorf(s, "%d to %d indexes expected", min, max)
+		}
+
+		if len(x) == 0 {
+			return nil
+		}
+
+		if len(x) == 1 {
+			*dst2, err = sp2.index(p, x[0])
+			return err
+		}
+
+		*dst1, err = sp1.index(p, x[0])
+		if err != nil {
+			return err
+		}
+
+		*dst2, err = sp2.index(p, x[1])
+
+		return err
+	}
+
+	switch in.Ext {
+	case wasm.FCMemoryInit:
+		p.usesData = true
+		return idx(&p.mems, &p.data, &in.Memory, &in.Index, 1, 2)
+	case wasm.FCDataDrop:
+		p.usesData = true
+		return idx(nil, &p.data, nil, &in.Index, 1, 1)
+	case wasm.FCMemoryCopy:
+		if len(x) == 1 {
+			return p.errorf(s, "both memory indexes expected")
+		}
+
+		return idx(&p.mems, &p.mems, &in.Memory, &in.Src, 0, 2)
+	case wasm.FCMemoryFill:
+		return idx(nil, &p.mems, nil, &in.Memory, 0, 1)
+	case wasm.FCTableInit:
+		return idx(&p.tables, &p.elems, &in.Table, &in.Index, 1, 2)
+	case wasm.FCElemDrop:
+		return idx(nil, &p.elems, nil, &in.Index, 1, 1)
+	case wasm.FCTableCopy:
+		if len(x) == 1 {
+			return p.errorf(s, "both table indexes expected")
+		}
+
+		return idx(&p.tables, &p.tables, &in.Table, &in.Src, 0, 2)
+	default: // FCTableGrow, FCTableSize, FCTableFill
+		return idx(nil, &p.tables, nil, &in.Table, 0, 1)
+	}
+}
+
+func (p *Parser) fdImmediates(s *sexp, c *cursor, in *wasm.Instruction, info opInfo) (err error) {
+	switch in.Ext {
+	case wasm.FDV128Const:
+		return p.v128Const(s, c, in)
+	case wasm.FDI8x16Shuffle:
+		for j := range in.V128 {
+			x := c.next()
+			if x == nil || !x.atom() {
+				return p.errorf(s, "16 lane indexes expected")
+			}
+
+			v, err := parseUint(x.text, 8)
+			if err != nil {
+				return p.wrap(x, err)
+			}
+
+			in.V128[j] = byte(v)
+		}
+
+		return nil
+	}
+
+	if isSIMDMemory(info.name) {
+		in.MemArg, err = p.memArg(c, info.align)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isSIMDLane(info.name) {
+		x := c.next()
+		if x == nil || !x.atom() {
+			return p.errorf(s, "lane index expected")
+		}
+
+		v, err := parseUint(x.text, 8)
+		if err != nil {
+			return p.wrap(x, err)
+		}
+
+		in.Lane = byte(v)
+	}
+
+	return nil
+}
+
+func (p *Parser) v128Const(s *sexp, c *cursor, in *wasm.Instruction) (err error) {
+	shape := c.next()
+	if shape == nil || !shape.atom() {
+		return p.errorf(s, "v128 shape expected")
+	}
+
+	var size int
+	var float bool
+
+	switch string(shape.text) {
+	case "i8x16":
+		size = 1
+	case "i16x8":
+		size = 2
+	case "i32x4":
+		size = 4
+	case "i64x2":
+		size = 8
+	case "f32x4":
+		size, float = 4, true
+	case "f64x2":
+		size, float = 8, true
+	default:
+		return p.errorf(shape, "unsupported v128 shape")
+	}
+
+	for j := 0; j < len(in.V128); j += size {
+		var v uint64
+
+		if float {
+			v, err = p.floatArg(s, c.next(), size*8)
+		} else {
+			v, err = p.intArg(s, c.next(), size*8)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], v)
+		copy(in.V128[j:j+size], buf[:])
+	}
+
+	return nil
+}
+
+// memArg parses optional offset=N and align=N.
+func (p *Parser) memArg(c *cursor, natural int8) (m wasm.MemArg, err error) {
+	m.Align = int(natural)
+
+	if x := c.peek(); x != nil && x.atom() && bytes.HasPrefix(x.text, []byte("offset=")) {
+		c.next()
+
+		v, err := parseUint(x.text[len("offset="):], 64)
+		if err != nil {
+			return m, p.wrap(x, err)
+		}
+
+		m.Offset = int(v)
+	}
+
+	if x := c.peek(); x != nil && x.atom() && bytes.HasPrefix(x.text, []byte("align=")) {
+		c.next()
+
+		v, err := parseUint(x.text[len("align="):], 32)
+		if err != nil {
+			return m, p.wrap(x, err)
+		}
+
+		if v == 0 || v&(v-1) != 0 {
+			return m, p.errorf(x, "alignment must be a power of two")
+		}
+
+		m.Align = bits.TrailingZeros64(v)
+	}
+
+	return m, nil
+}
+
+func (p *Parser) labelIndex(s, x *sexp) (wasm.Index, error) {
+	if x == nil || !x.atom() {
+		return 0, p.errorf(s, "label expected")
+	}
+
+	if !x.id() {
+		v, err := parseUint(x.text, 32)
+		if err != nil {
+			return 0, p.wrap(x, err)
+		}
+
+		return wasm.Index(v), nil
+	}
+
+	for j := len(p.labels) - 1; j >= 0; j-- {
+		if p.labels[j] == string(x.text) {
+			return wasm.Index(len(p.labels) - 1 - j), nil
+		}
+	}
+
+	return 0, p.errorf(x, "unknown label: %s", x.text)
+}
+
+// peekIndex reports whether the next element is an identifier or a number.
+func (p *Parser) peekIndex(c *cursor) bool {
+	x := c.peek()
+
+	return x != nil && (x.id() || x.atom() && isDigit(x.text))
+}
+
+func (p *Parser) optIndex(sp *space, c *cursor) (wasm.Index, error) {
+	if !p.peekIndex(c) {
+		return 0, nil
+	}
+
+	return sp.index(p, c.next())
+}
+
+func (p *Parser) intArg(s, x *sexp, size int) (uint64, error) {
+	if x == nil || !x.atom() {
+		return 0, p.errorf(s, "integer expected")
+	}
+
+	v, err := parseInt(x.text, size)
+	if err != nil {
+		return 0, p.wrap(x, err)
+	}
+
+	return v, nil
+}
+
+func (p *Parser) floatArg(s, x *sexp, size int) (uint64, error) {
+	if x == nil || !x.atom() {
+		return 0, p.errorf(s, "float expected")
+	}
+
+	v, err := parseFloat(x.text, size)
+	if err != nil {
+		return 0, p.wrap(x, err)
+	}
+
+	return v, nil
+}
+
+// parseUint parses unsigned decimal or hex integer.
+func parseUint(s []byte, size int) (uint64, error) {
+	digits := s
+	base := 10
+
+	if bytes.HasPrefix(digits, []byte("0x")) {
+		digits, base = digits[2:], 16
+	}
+
+	if len(digits) == 0 || digits[0] == '_' || digits[len(digits)-1] == '_' || bytes.Contains(digits, []byte("__")) {
+		return 0, errors.New("bad integer: %s", s)
+	}
+
+	v, err := strconv.ParseUint(string(bytes.ReplaceAll(digits, []byte("_"), nil)), base, size)
+	if err != nil {
+		return 0, errors.New("bad integer: %s", s)
+	}
+
+	return v, nil
+}
+
+// parseInt parses signed or unsigned integer of size bits.
+// The result is the two's complement representation.
+func parseInt(s []byte, size int) (uint64, error) {
+	neg := len(s) != 0 && s[0] == '-'
+
+	digits := s
+	if len(s) != 0 && (s[0] == '-' || s[0] == '+') {
+		digits = s[1:]
+	}
+
+	v, err := parseUint(digits, 64)
+	if err != nil {
+		return 0, errors.New("bad integer: %s", s)
+	}
+
+	mask := uint64(1)<<size - 1
+
+	switch {
+	case !neg && v > mask,
+		neg && v > uint64(1)<<(size-1):
+		return 0, errors.New("integer out of range: %s", s)
+	}
+
+	if neg {
+		v = -v
+	}
+
+	return v & mask, nil
+}
+
+// parseFloat parses float including inf and nan with payload.
+// The result is the float bits.
+func parseFloat(s []byte, size int) (uint64, error) {
+	neg := len(s) != 0 && s[0] == '-'
+
+	body := s
+	if len(s) != 0 && (s[0] == '-' || s[0] == '+') {
+		body = s[1:]
+	}
+
+	mant := 52
+	if size == 32 {
+		mant = 23
+	}
+
+	var sign uint64
+	if neg {
+		sign = 1 << (size - 1)
+	}
+
+	exp := (uint64(1)<<(size-1-mant) - 1) << mant
+
+	switch {
+	case string(body) == "inf":
+		return sign | exp, nil
+	case string(body) == "nan":
+		return sign | exp | 1<<(mant-1), nil
+	case bytes.HasPrefix(body, []byte("nan:0x")):
+		payload, err := parseUint(body[len("nan:"):], 64)
+		if err != nil || payload == 0 || payload >= 1<<mant {
+			return 0, errors.New("bad nan payload: %s", s)
+		}
+
+		return sign | exp | payload, nil
+	}
+
+	if len(body) == 0 || body[0] == '_' || bytes.Contains(body, []byte("__")) {
+		return 0, errors.New("bad float: %s", s)
+	}
+
+	str := string(bytes.ReplaceAll(s, []byte("_"), nil))
+
+	if bytes.HasPrefix(body, []byte("0x")) && !bytes.ContainsAny(body, "pP") {
+		str += "p0"
+	}
+
+	f, err := strconv.ParseFloat(str, size)
+	if err != nil {
+		return 0, errors.New("bad float: %s", s)
+	}
+
+	if size == 32 {
+		return uint64(math.Float32bits(float32(f))), nil
+	}
+
+	return math.Float64bits(f), nil
+}
@@ -0,0 +1,170 @@
+package wat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+)
+
+func TestParserModule(tb *testing.T) {
+	text := `(module $m
+  (import "env" "log" (func $log (param i32)))
+  (memory (export "mem") (data "ab\n" "\01"))
+  (table 2 funcref)
+  (global $g (mut i64) (i64.const -1))
+  (func $add (export "add") (param $a i32) (param $b i32) (result i32) (local i32)
+    (i32.add (local.get $a) (local.get $b)))
+  (func (param i32)
+    block $out
+      local.get 0
+      br_if $out
+      i32.const 7
+      call $log
+    end
+    (if (local.get 0) (then (nop)) (else (call $log (i32.const 0x10)))))
+  (elem (i32.const 0) $add 2)
+  (start 2))
+`
+
+	var p Parser
+	var m wasm.Module
+
+	err := p.Module([]byte(text), &m)
+	require.NoError(tb, err)
+
+	assert.Len(tb, m.Type, 2)
+	assert.Equal(tb, []wasm.Index{1, 0}, m.Function)
+	assert.Equal(tb, []wasm.Limits{{Lo: 1, Hi: 1}}, m.Memory)
+	assert.Equal(tb, wasm.Index(2), m.Start)
+	assert.Equal(tb, []wasm.Export{
+		{Name: []byte("mem"), ExportType: wasm.ExternMemory, Index: 0},
+		{Name: []byte("add"), ExportType: wasm.ExternFunc, Index: 1},
+	}, m.Export)
+
+	if assert.Len(tb, m.Data, 1) {
+		assert.Equal(tb, []byte("ab\n\x01"), m.Data[0].Init)
+	}
+
+	if assert.Len(tb, m.Global, 1) {
+		assert.Equal(tb, wasm.Code{wasm.I64Const, 0x7f, wasm.End}, m.Global[0].Expr)
+	}
+
+	if assert.Len(tb, m.Code, 2) {
+		assert.Equal(tb, wasm.Code{
+			1, 1, wasm.I32,
+			wasm.LocalGet, 0,
+			wasm.LocalGet, 1,
+			wasm.I32Add,
+			wasm.End,
+		}, m.Code[0])
+
+		assert.Equal(tb, wasm.Code{
+			0,
+			wasm.Block, 0x40,
+			wasm.LocalGet, 0,
+			wasm.BrIf, 0,
+			wasm.I32Const, 7,
+			wasm.Call, 0,
+			wasm.End,
+			wasm.LocalGet, 0,
+			wasm.If, 0x40,
+			wasm.Nop,
+			wasm.Else,
+			wasm.I32Const, 0x10,
+			wasm.Call, 0,
+			wasm.End,
+			wasm.End,
+		}, m.Code[1])
+	}
+}
+
+func TestParserRoundTrip(tb *testing.T) {
+	for _, folded := range []bool{false, true} {
+		pr := Printer{Folded: folded}
+
+		text, err := pr.Module(nil, testModule(tb))
+		require.NoError(tb, err)
+
+		p := Parser{DebugNames: true}
+		var m wasm.Module
+
+		err = p.Module(text, &m)
+		require.NoError(tb, err, "folded %v", folded)
+
+		text2, err := pr.Module(nil, &m)
+		require.NoError(tb, err)
+
+		assert.Equal(tb, string(text), string(text2), "folded %v", folded)
+	}
+}
+
+func TestParserErrors(tb *testing.T) {
+	for _, tc := range []struct {
+		text string
+		exp  string
+	}{
+		{"(module\n  (func (call $nope)))", "2:15"},
+		{"(module (func i32.const 1 drop", "unclosed paren"},
+		{"(module (memory 1) (data (i32.const 0) \"\\q\"))", "bad escape"},
+		{"(module (func (i32.const 0x1_0000_0000) drop))", "out of range"},
+		{"(module (func $f) (func $f))", "duplicate"},
+	} {
+		var p Parser
+		var m wasm.Module
+
+		err := p.Module([]byte(tc.text), &m)
+		if assert.Error(tb, err, tc.text) {
+			assert.Contains(tb, err.Error(), tc.exp, tc.text)
+		}
+	}
+}
+
+func TestParseNumbers(tb *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		size int
+		exp  uint64
+	}{
+		{"0", 32, 0},
+		{"-1", 32, 0xffff_ffff},
+		{"0xffff_ffff", 32, 0xffff_ffff},
+		{"-0x8000_0000", 32, 0x8000_0000},
+		{"1_000", 64, 1000},
+		{"-1", 64, 0xffff_ffff_ffff_ffff},
+	} {
+		v, err := parseInt([]byte(tc.s), tc.size)
+		if assert.NoError(tb, err, tc.s) {
+			assert.Equal(tb, tc.exp, v, tc.s)
+		}
+	}
+
+	for _, s := range []string{"", "0x", "1__0", "_1", "4294967296", "-2147483649"} {
+		_, err := parseInt([]byte(s), 32)
+		assert.Error(tb, err, s)
+	}
+
+	for _, tc := range []struct {
+		s    string
+		size int
+		exp  uint64
+	}{
+		{"1.5", 64, 0x3ff8_0000_0000_0000},
+		{"-0", 64, 0x8000_0000_0000_0000},
+		{"inf", 64, 0x7ff0_0000_0000_0000},
+		{"-nan", 64, 0xfff8_0000_0000_0000},
+		{"nan:0x1", 64, 0x7ff0_0000_0000_0001},
+		{"0x1p-1", 64, 0x3fe0_0000_0000_0000},
+		{"0x10", 32, 0x4180_0000},
+		{"nan", 32, 0x7fc0_0000},
+		{"nan:0x200000", 32, 0x7fa0_0000},
+		{"1e1_0", 32, 0x5015_02f9},
+	} {
+		v, err := parseFloat([]byte(tc.s), tc.size)
+		if assert.NoError(tb, err, tc.s) {
+			assert.Equal(tb, tc.exp, v, tc.s)
+		}
+	}
+}
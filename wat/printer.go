@@ -0,0 +1,1121 @@
+package wat
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	"nikand.dev/go/wasm"
+	"tlog.app/go/errors"
+)
+
+type (
+	// Printer renders a Module in the text format.
+	// Names from the name section are used as identifiers where available.
+	// Custom sections other than the name section are not printed.
+	Printer struct {
+		// Folded prints function bodies as nested S-expressions.
+		Folded bool
+
+		d wasm.Decoder
+		m *wasm.Module
+
+		names wasm.Names
+
+		funcs, tables, mems, globals ids
+		types, elems, data           ids
+
+		// current function
+		locals ids
+		labels wasm.NameMap
+		label  wasm.Index // next label index
+		stack  []string   // label ids of the enclosing blocks
+	}
+
+	// ids maps indexes to identifiers including the leading '$'.
+	ids map[wasm.Index]string
+
+	// node is a folded instruction.
+	node struct {
+		text []byte
+		op   wasm.Opcode // Block, Loop, If or 0
+
+		kids []*node // operands
+		body []*node // Block and Loop body, If then branch
+		els  []*node // If else branch
+
+		hasElse bool
+
+		results int // number of values pushed, -1 if unknown
+	}
+)
+
+// Module appends m in the text format to b.
+func (p *Printer) Module(b []byte, m *wasm.Module) (_ []byte, err error) {
+	p.m = m
+
+	_, err = p.d.ModuleNames(m, &p.names)
+	if err != nil {
+		return b, errors.Wrap(err, "name section")
+	}
+
+	p.funcs = makeIDs(p.names.Funcs)
+	p.tables = makeIDs(p.names.Tables)
+	p.mems = makeIDs(p.names.Memories)
+	p.globals = makeIDs(p.names.Globals)
+	p.types = makeIDs(p.names.Types)
+	p.elems = makeIDs(p.names.Elems)
+	p.data = makeIDs(p.names.Data)
+
+	b = append(b, "(module"...)
+
+	if p.names.Module != nil {
+		b = append(b, ' ')
+		b = appendID(b, p.names.Module)
+	}
+
+	for i, tp := range m.Type {
+		b = append(b, "\n  (type"...)
+		b = p.def(b, p.types, wasm.Index(i))
+		b = append(b, " (func"...)
+		b = appendTypes(b, " (param", tp.Params)
+		b = appendTypes(b, " (result", tp.Result)
+		b = append(b, "))"...)
+	}
+
+	var funcs, tables, mems, globals wasm.Index
+
+	for _, im := range m.Import {
+		b = append(b, "\n  (import "...)
+		b = appendString(b, im.Module)
+		b = append(b, ' ')
+		b = appendString(b, im.Name)
+
+		switch im.Kind() {
+		case wasm.ExternFunc:
+			b = append(b, " (func"...)
+			b = p.def(b, p.funcs, funcs)
+			b = p.typeUse(b, im.FuncType(), p.localIDs(funcs))
+			funcs++
+		case wasm.ExternTable:
+			b = append(b, " (table"...)
+			b = p.def(b, p.tables, tables)
+			b = appendTable(b, im.Table())
+			tables++
+		case wasm.ExternMemory:
+			b = append(b, " (memory"...)
+			b = p.def(b, p.mems, mems)
+			b = appendLimits(b, im.Memory())
+			mems++
+		case wasm.ExternGlobal:
+			b = append(b, " (global"...)
+			b = p.def(b, p.globals, globals)
+			g := im.Global()
+			b = appendGlobalType(b, g.Type, g.Mut)
+			globals++
+		}
+
+		b = append(b, "))"...)
+	}
+
+	for i, tp := range m.Function {
+		fn := funcs + wasm.Index(i)
+
+		if i >= len(m.Code) {
+			return b, errors.New("no code for function %d", fn)
+		}
+
+		b, err = p.function(b, fn, tp, m.Code[i])
+		if err != nil {
+			return b, errors.Wrap(err, "func %d", fn)
+		}
+	}
+
+	for i, t := range m.Table {
+		b = append(b, "\n  (table"...)
+		b = p.def(b, p.tables, tables+wasm.Index(i))
+		b = appendTable(b, t)
+		b = append(b, ')')
+	}
+
+	for i, l := range m.Memory {
+		b = append(b, "\n  (memory"...)
+		b = p.def(b, p.mems, mems+wasm.Index(i))
+		b = appendLimits(b, l)
+		b = append(b, ')')
+	}
+
+	for i, g := range m.Global {
+		b = append(b, "\n  (global"...)
+		b = p.def(b, p.globals, globals+wasm.Index(i))
+		b = appendGlobalType(b, g.Type, g.Mut)
+
+		b, err = p.constExpr(b, g.Expr)
+		if err != nil {
+			return b, errors.Wrap(err, "global %d", globals+wasm.Index(i))
+		}
+
+		b = append(b, ')')
+	}
+
+	for _, e := range m.Export {
+		b = append(b, "\n  (export "...)
+		b = appendString(b, e.Name)
+
+		switch e.ExportType {
+		case wasm.ExternFunc:
+			b = append(b, " (func "...)
+			b = p.funcs.ref(b, e.Index)
+		case wasm.ExternTable:
+			b = append(b, " (table "...)
+			b = p.tables.ref(b, e.Index)
+		case wasm.ExternMemory:
+			b = append(b, " (memory "...)
+			b = p.mems.ref(b, e.Index)
+		case wasm.ExternGlobal:
+			b = append(b, " (global "...)
+			b = p.globals.ref(b, e.Index)
+		default:
+			return b, errors.New("export %q: unsupported kind: %x", e.Name, e.ExportType)
+		}
+
+		b = append(b, "))"...)
+	}
+
+	if m.HasStart {
+		b = append(b, "\n  (start "...)
+		b = p.funcs.ref(b, m.Start)
+		b = append(b, ')')
+	}
+
+	for i, el := range m.Element {
+		b, err = p.element(b, wasm.Index(i), el)
+		if err != nil {
+			return b, errors.Wrap(err, "elem %d", i)
+		}
+	}
+
+	for i, d := range m.Data {
+		b = append(b, "\n  (data"...)
+		b = p.def(b, p.data, wasm.Index(i))
+
+		if d.Mode == wasm.DataActive {
+			if d.Memory != 0 {
+				b = append(b, " (memory "...)
+				b = p.mems.ref(b, d.Memory)
+				b = append(b, ')')
+			}
+
+			b, err = p.offset(b, d.Expr)
+			if err != nil {
+				return b, errors.Wrap(err, "data %d", i)
+			}
+		}
+
+		b = append(b, ' ')
+		b = appendString(b, d.Init)
+		b = append(b, ')')
+	}
+
+	b = append(b, ")\n"...)
+
+	return b, nil
+}
+
+func (p *Printer) function(b []byte, fn wasm.Index, tp wasm.Index, code wasm.Code) (_ []byte, err error) {
+	f, err := p.d.Func(code, wasm.FuncCode{})
+	if err != nil {
+		return b, err
+	}
+
+	p.locals = p.localIDs(fn)
+	p.labels = p.names.Labels.Names(fn)
+	p.label = 0
+	p.stack = p.stack[:0]
+
+	b = append(b, "\n  (func"...)
+	b = p.def(b, p.funcs, fn)
+	b = p.typeUse(b, tp, p.locals)
+
+	params := wasm.Index(0)
+	if int(tp) < len(p.m.Type) {
+		params = wasm.Index(len(p.m.Type[tp].Params))
+	}
+
+	b = p.vars(b, "local", params, f.Locals, p.locals)
+
+	if p.Folded {
+		nodes, _, _, err := p.fold(f.Expr, 0)
+		if err != nil {
+			return b, err
+		}
+
+		for _, n := range nodes {
+			b = n.appendTo(b, 2)
+		}
+	} else {
+		b, err = p.flat(b, f.Expr)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	b = append(b, ')')
+
+	return b, nil
+}
+
+// flat appends instructions one per line indented by the block depth.
+func (p *Printer) flat(b []byte, expr []byte) (_ []byte, err error) {
+	var in wasm.Instruction
+	depth := 2
+
+	for i := 0; i < len(expr); {
+		in, i, err = p.d.Instruction(expr, i, in)
+		if err != nil {
+			return b, err
+		}
+
+		switch in.Opcode {
+		case wasm.End:
+			if depth == 2 { // function end
+				continue
+			}
+
+			depth--
+			p.stack = p.stack[:len(p.stack)-1]
+		case wasm.Else:
+			depth--
+		}
+
+		b = indent(b, depth)
+		b = p.instr(b, &in)
+
+		switch in.Opcode {
+		case wasm.Block, wasm.Loop, wasm.If, wasm.Else:
+			depth++
+		}
+	}
+
+	return b, nil
+}
+
+// fold decodes instructions until End or Else and folds them into nodes.
+func (p *Printer) fold(expr []byte, st int) (nodes []*node, term wasm.Opcode, i int, err error) {
+	var in wasm.Instruction
+
+	for i = st; i < len(expr); {
+		in, i, err = p.d.Instruction(expr, i, in)
+		if err != nil {
+			return nil, 0, i, err
+		}
+
+		switch in.Opcode {
+		case wasm.End, wasm.Else:
+			if in.Opcode == wasm.End && len(p.stack) != 0 {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+
+			return nodes, in.Opcode, i, nil
+		}
+
+		n := &node{text: p.instr(nil, &in)}
+
+		switch in.Opcode {
+		case wasm.Block, wasm.Loop, wasm.If:
+			n.op = in.Opcode
+			n.results = p.blockResults(in.BlockType)
+
+			if _, params := p.blockParams(in.BlockType); in.Opcode == wasm.If && params == 0 {
+				nodes, n.kids = takeOperands(nodes, 1)
+			}
+
+			var term wasm.Opcode
+
+			n.body, term, i, err = p.fold(expr, i)
+			if err != nil {
+				return nil, 0, i, err
+			}
+
+			if term == wasm.Else {
+				n.hasElse = true
+
+				n.els, _, i, err = p.fold(expr, i)
+				if err != nil {
+					return nil, 0, i, err
+				}
+			}
+		default:
+			pops, pushes := p.stackEffect(&in)
+
+			nodes, n.kids = takeOperands(nodes, pops)
+			n.results = pushes
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nil, 0, i, wasm.ErrUnexpectedEOF
+}
+
+// takeOperands takes n last nodes if all of them push a single value.
+func takeOperands(nodes []*node, n int) (_, kids []*node) {
+	if n <= 0 || n > len(nodes) {
+		return nodes, nil
+	}
+
+	for _, x := range nodes[len(nodes)-n:] {
+		if x.results != 1 {
+			return nodes, nil
+		}
+	}
+
+	kids = append(kids, nodes[len(nodes)-n:]...)
+
+	return nodes[:len(nodes)-n], kids
+}
+
+func (p *Printer) stackEffect(in *wasm.Instruction) (pops, pushes int) {
+	switch in.Opcode {
+	case wasm.Call:
+		if tp, ok := p.funcType(in.Index); ok {
+			return len(tp.Params), len(tp.Result)
+		}
+
+		return 0, -1
+	case wasm.CallIndir:
+		if int(in.Index) < len(p.m.Type) {
+			tp := p.m.Type[in.Index]
+
+			return len(tp.Params) + 1, len(tp.Result)
+		}
+
+		return 0, -1
+	}
+
+	op, ok := instrInfo(in)
+	if !ok {
+		return 0, -1
+	}
+
+	return int(op.pops), int(op.pushes)
+}
+
+func (p *Printer) funcType(fn wasm.Index) (tp wasm.FuncType, ok bool) {
+	x := wasm.Index(-1)
+
+	for _, im := range p.m.Import {
+		if im.Kind() != wasm.ExternFunc {
+			continue
+		}
+
+		if fn == 0 {
+			x = im.FuncType()
+			break
+		}
+
+		fn--
+	}
+
+	if x < 0 && int(fn) < len(p.m.Function) {
+		x = p.m.Function[fn]
+	}
+
+	if x < 0 || int(x) >= len(p.m.Type) {
+		return tp, false
+	}
+
+	return p.m.Type[x], true
+}
+
+func (p *Printer) blockResults(bt wasm.BlockType) int {
+	if bt == wasm.BlockEmpty {
+		return 0
+	}
+
+	if _, ok := bt.ValueType(); ok {
+		return 1
+	}
+
+	x, _ := bt.TypeIndex()
+	if int(x) >= len(p.m.Type) {
+		return -1
+	}
+
+	if len(p.m.Type[x].Params) != 0 {
+		return -1
+	}
+
+	return len(p.m.Type[x].Result)
+}
+
+func (p *Printer) blockParams(bt wasm.BlockType) (wasm.Index, int) {
+	x, ok := bt.TypeIndex()
+	if !ok || int(x) >= len(p.m.Type) {
+		return 0, 0
+	}
+
+	return x, len(p.m.Type[x].Params)
+}
+
+// appendTo appends the node and its children on separate lines.
+func (n *node) appendTo(b []byte, depth int) []byte {
+	b = indent(b, depth)
+	b = append(b, '(')
+	b = append(b, n.text...)
+
+	for _, k := range n.kids {
+		b = k.appendTo(b, depth+1)
+	}
+
+	switch n.op {
+	case wasm.Block, wasm.Loop:
+		for _, k := range n.body {
+			b = k.appendTo(b, depth+1)
+		}
+	case wasm.If:
+		b = indent(b, depth+1)
+		b = append(b, "(then"...)
+
+		for _, k := range n.body {
+			b = k.appendTo(b, depth+2)
+		}
+
+		b = append(b, ')')
+
+		if n.hasElse {
+			b = indent(b, depth+1)
+			b = append(b, "(else"...)
+
+			for _, k := range n.els {
+				b = k.appendTo(b, depth+2)
+			}
+
+			b = append(b, ')')
+		}
+	}
+
+	return append(b, ')')
+}
+
+// appendInline appends the node on a single line.
+func (n *node) appendInline(b []byte) []byte {
+	b = append(b, '(')
+	b = append(b, n.text...)
+
+	for _, k := range n.kids {
+		b = append(b, ' ')
+		b = k.appendInline(b)
+	}
+
+	return append(b, ')')
+}
+
+// constExpr appends constant expression folded on a single line.
+func (p *Printer) constExpr(b []byte, expr wasm.Code) (_ []byte, err error) {
+	nodes, _, _, err := p.fold(expr, 0)
+	if err != nil {
+		return b, errors.Wrap(err, "const expr")
+	}
+
+	for _, n := range nodes {
+		b = append(b, ' ')
+		b = n.appendInline(b)
+	}
+
+	return b, nil
+}
+
+// offset appends element or data segment offset expression.
+func (p *Printer) offset(b []byte, expr wasm.Code) (_ []byte, err error) {
+	nodes, _, _, err := p.fold(expr, 0)
+	if err != nil {
+		return b, errors.Wrap(err, "offset")
+	}
+
+	if len(nodes) == 1 {
+		b = append(b, ' ')
+		return nodes[0].appendInline(b), nil
+	}
+
+	b = append(b, " (offset"...)
+
+	for _, n := range nodes {
+		b = append(b, ' ')
+		b = n.appendInline(b)
+	}
+
+	return append(b, ')'), nil
+}
+
+func (p *Printer) element(b []byte, x wasm.Index, el wasm.Element) (_ []byte, err error) {
+	b = append(b, "\n  (elem"...)
+	b = p.def(b, p.elems, x)
+
+	switch el.Mode {
+	case wasm.ElemDeclarative:
+		b = append(b, " declare"...)
+	case wasm.ElemActive:
+		if el.Table != 0 {
+			b = append(b, " (table "...)
+			b = p.tables.ref(b, el.Table)
+			b = append(b, ')')
+		}
+
+		b, err = p.offset(b, el.Expr)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	if len(el.Init) == 0 {
+		b = append(b, " func"...)
+
+		for _, f := range el.Funcs {
+			b = append(b, ' ')
+			b = p.funcs.ref(b, f)
+		}
+
+		return append(b, ')'), nil
+	}
+
+	tp := el.Type
+	if tp == 0 {
+		tp = wasm.FuncRef
+	}
+
+	b = append(b, ' ')
+	b = append(b, tp.String()...)
+
+	for _, e := range el.Init {
+		b = append(b, " (item"...)
+
+		b, err = p.constExpr(b, e)
+		if err != nil {
+			return b, err
+		}
+
+		b = append(b, ')')
+	}
+
+	return append(b, ')'), nil
+}
+
+// instr appends instruction name with immediates.
+func (p *Printer) instr(b []byte, in *wasm.Instruction) []byte {
+	op, ok := instrInfo(in)
+	if !ok {
+		return append(b, in.Name()...)
+	}
+
+	b = append(b, op.name...)
+
+	switch x := in.Opcode; {
+	case x == wasm.Block || x == wasm.Loop || x == wasm.If:
+		var id string
+
+		if n := p.labels.Name(p.label); n != nil {
+			id = string(appendID(nil, n))
+
+			b = append(b, ' ')
+			b = append(b, id...)
+		}
+
+		p.label++
+		p.stack = append(p.stack, id)
+
+		b = p.blockType(b, in.BlockType)
+	case x == wasm.Br || x == wasm.BrIf:
+		b = p.labelRef(b, in.Label)
+	case x == wasm.BrTable:
+		for _, l := range in.Labels {
+			b = p.labelRef(b, l)
+		}
+
+		b = p.labelRef(b, in.Label)
+	case x == wasm.Call || x == wasm.RefFunc:
+		b = append(b, ' ')
+		b = p.funcs.ref(b, in.Index)
+	case x == wasm.CallIndir:
+		if in.Table != 0 {
+			b = append(b, ' ')
+			b = p.tables.ref(b, in.Table)
+		}
+
+		b = append(b, " (type "...)
+		b = p.types.ref(b, in.Index)
+		b = append(b, ')')
+	case x == wasm.SelectT:
+		b = appendTypes(b, " (result", in.Types)
+	case x >= wasm.LocalGet && x <= wasm.LocalTee:
+		b = append(b, ' ')
+		b = p.locals.ref(b, in.Index)
+	case x == wasm.GlobalGet || x == wasm.GlobalSet:
+		b = append(b, ' ')
+		b = p.globals.ref(b, in.Index)
+	case x == wasm.TableGet || x == wasm.TableSet:
+		b = append(b, ' ')
+		b = p.tables.ref(b, in.Table)
+	case x >= wasm.I32Load && x <= wasm.I64Store32:
+		b = appendMemArg(b, in.MemArg, op.align)
+	case x == wasm.MemorySize || x == wasm.MemoryGrow:
+		b = p.memRef(b, in.Memory)
+	case x == wasm.I32Const:
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, int64(in.I32), 10)
+	case x == wasm.I64Const:
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, in.I64, 10)
+	case x == wasm.F32Const:
+		b = append(b, ' ')
+		b = appendFloat32(b, in.F32)
+	case x == wasm.F64Const:
+		b = append(b, ' ')
+		b = appendFloat64(b, in.F64)
+	case x == wasm.RefNull:
+		b = append(b, ' ')
+		b = appendHeapType(b, in.RefType)
+	case x == wasm.FCExt:
+		b = p.fcImmediates(b, in)
+	case x == wasm.FDExt:
+		b = p.fdImmediates(b, in, op)
+	case x == wasm.FEExt:
+		if in.Ext != wasm.FEAtomicFence {
+			b = appendMemArg(b, in.MemArg, op.align)
+		}
+	}
+
+	return b
+}
+
+func (p *Printer) fcImmediates(b []byte, in *wasm.Instruction) []byte {
+	switch in.Ext {
+	case wasm.FCMemoryInit:
+		b = p.memRef(b, in.Memory)
+		b = append(b, ' ')
+		b = p.data.ref(b, in.Index)
+	case wasm.FCDataDrop:
+		b = append(b, ' ')
+		b = p.data.ref(b, in.Index)
+	case wasm.FCMemoryCopy:
+		if in.Memory != 0 || in.Src != 0 {
+			b = append(b, ' ')
+			b = p.mems.ref(b, in.Memory)
+			b = append(b, ' ')
+			b = p.mems.ref(b, in.Src)
+		}
+	case wasm.FCMemoryFill:
+		b = p.memRef(b, in.Memory)
+	case wasm.FCTableInit:
+		if in.Table != 0 {
+			b = append(b, ' ')
+			b = p.tables.ref(b, in.Table)
+		}
+
+		b = append(b, ' ')
+		b = p.elems.ref(b, in.Index)
+	case wasm.FCElemDrop:
+		b = append(b, ' ')
+		b = p.elems.ref(b, in.Index)
+	case wasm.FCTableCopy:
+		if in.Table != 0 || in.Src != 0 {
+			b = append(b, ' ')
+			b = p.tables.ref(b, in.Table)
+			b = append(b, ' ')
+			b = p.tables.ref(b, in.Src)
+		}
+	case wasm.FCTableGrow, wasm.FCTableSize, wasm.FCTableFill:
+		b = append(b, ' ')
+		b = p.tables.ref(b, in.Table)
+	}
+
+	return b
+}
+
+func (p *Printer) fdImmediates(b []byte, in *wasm.Instruction, op opInfo) []byte {
+	switch in.Ext {
+	case wasm.FDV128Const:
+		b = append(b, " i32x4"...)
+
+		for j := 0; j < len(in.V128); j += 4 {
+			b = append(b, " 0x"...)
+			b = appendHex(b, uint64(binary.LittleEndian.Uint32(in.V128[j:])), 8)
+		}
+
+		return b
+	case wasm.FDI8x16Shuffle:
+		for _, x := range in.V128 {
+			b = append(b, ' ')
+			b = strconv.AppendUint(b, uint64(x), 10)
+		}
+
+		return b
+	}
+
+	name := op.name
+
+	if isSIMDMemory(name) {
+		b = appendMemArg(b, in.MemArg, op.align)
+	}
+
+	if isSIMDLane(name) {
+		b = append(b, ' ')
+		b = strconv.AppendUint(b, uint64(in.Lane), 10)
+	}
+
+	return b
+}
+
+func (p *Printer) blockType(b []byte, bt wasm.BlockType) []byte {
+	if tp, ok := bt.ValueType(); ok {
+		b = append(b, " (result "...)
+		b = append(b, tp.String()...)
+
+		return append(b, ')')
+	}
+
+	if x, ok := bt.TypeIndex(); ok {
+		b = append(b, " (type "...)
+		b = p.types.ref(b, x)
+		b = append(b, ')')
+	}
+
+	return b
+}
+
+func (p *Printer) labelRef(b []byte, l wasm.Index) []byte {
+	b = append(b, ' ')
+
+	if j := len(p.stack) - 1 - int(l); j >= 0 && j < len(p.stack) && p.stack[j] != "" {
+		return append(b, p.stack[j]...)
+	}
+
+	return strconv.AppendUint(b, uint64(l), 10)
+}
+
+func (p *Printer) memRef(b []byte, x wasm.Index) []byte {
+	if x == 0 {
+		return b
+	}
+
+	b = append(b, ' ')
+
+	return p.mems.ref(b, x)
+}
+
+// typeUse appends function type index with params and results.
+func (p *Printer) typeUse(b []byte, tp wasm.Index, locals ids) []byte {
+	b = append(b, " (type "...)
+	b = p.types.ref(b, tp)
+	b = append(b, ')')
+
+	if int(tp) >= len(p.m.Type) {
+		return b
+	}
+
+	ft := p.m.Type[tp]
+
+	b = p.vars(b, "param", 0, ft.Params, locals)
+	b = appendTypes(b, " (result", ft.Result)
+
+	return b
+}
+
+// vars appends params or locals, named ones are written separately.
+func (p *Printer) vars(b []byte, kw string, base wasm.Index, tps wasm.ResultType, names ids) []byte {
+	open := false
+
+	for j, tp := range tps {
+		id, named := names[base+wasm.Index(j)]
+
+		if open && named {
+			b = append(b, ')')
+			open = false
+		}
+
+		if !open {
+			b = append(b, " ("...)
+			b = append(b, kw...)
+			open = true
+		}
+
+		if named {
+			b = append(b, ' ')
+			b = append(b, id...)
+		}
+
+		b = append(b, ' ')
+		b = append(b, tp.String()...)
+
+		if named {
+			b = append(b, ')')
+			open = false
+		}
+	}
+
+	if open {
+		b = append(b, ')')
+	}
+
+	return b
+}
+
+func (p *Printer) localIDs(fn wasm.Index) ids {
+	return makeIDs(p.names.Locals.Names(fn))
+}
+
+// def appends item identifier if any and the index comment.
+func (p *Printer) def(b []byte, x ids, i wasm.Index) []byte {
+	if id, ok := x[i]; ok {
+		b = append(b, ' ')
+		b = append(b, id...)
+	}
+
+	b = append(b, " (;"...)
+	b = strconv.AppendUint(b, uint64(i), 10)
+
+	return append(b, ";)"...)
+}
+
+func (x ids) ref(b []byte, i wasm.Index) []byte {
+	if id, ok := x[i]; ok {
+		return append(b, id...)
+	}
+
+	return strconv.AppendUint(b, uint64(i), 10)
+}
+
+// makeIDs makes valid unique identifiers of names.
+func makeIDs(m wasm.NameMap) ids {
+	if len(m) == 0 {
+		return nil
+	}
+
+	x := make(ids, len(m))
+	used := make(map[string]struct{}, len(m))
+
+	for _, n := range m {
+		if len(n.Name) == 0 {
+			continue
+		}
+
+		id := string(appendID(nil, n.Name))
+
+		for j := 1; ; j++ {
+			if _, ok := used[id]; !ok {
+				break
+			}
+
+			id = string(appendID(nil, n.Name)) + "." + strconv.Itoa(j)
+		}
+
+		used[id] = struct{}{}
+		x[n.Index] = id
+	}
+
+	return x
+}
+
+// appendID appends '$' and the name with chars not allowed in identifiers replaced by '_'.
+func appendID(b, name []byte) []byte {
+	b = append(b, '$')
+
+	for _, c := range name {
+		if !idChar(c) {
+			c = '_'
+		}
+
+		b = append(b, c)
+	}
+
+	return b
+}
+
+func idChar(c byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	}
+
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '/', ':', '<', '=', '>', '?', '@', '\\', '^', '_', '`', '|', '~':
+		return true
+	}
+
+	return false
+}
+
+// appendString appends quoted string escaping non-printable characters.
+func appendString(b, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c >= 0x20 && c < 0x7f:
+			b = append(b, c)
+		default:
+			b = append(b, '\\', hex[c>>4], hex[c&0xf])
+		}
+	}
+
+	return append(b, '"')
+}
+
+func appendTypes(b []byte, kw string, tps wasm.ResultType) []byte {
+	if len(tps) == 0 {
+		return b
+	}
+
+	b = append(b, kw...)
+
+	for _, tp := range tps {
+		b = append(b, ' ')
+		b = append(b, tp.String()...)
+	}
+
+	return append(b, ')')
+}
+
+func appendLimits(b []byte, l wasm.Limits) []byte {
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(l.Lo), 10)
+
+	if l.Hi >= 0 {
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, int64(l.Hi), 10)
+	}
+
+	if l.Shared {
+		b = append(b, " shared"...)
+	}
+
+	return b
+}
+
+func appendTable(b []byte, t wasm.Table) []byte {
+	b = appendLimits(b, t.Limits)
+	b = append(b, ' ')
+
+	return append(b, t.Type.String()...)
+}
+
+func appendGlobalType(b []byte, tp wasm.Type, mut byte) []byte {
+	if mut == 0 {
+		b = append(b, ' ')
+		return append(b, tp.String()...)
+	}
+
+	b = append(b, " (mut "...)
+	b = append(b, tp.String()...)
+
+	return append(b, ')')
+}
+
+func appendHeapType(b []byte, tp wasm.Type) []byte {
+	switch tp {
+	case wasm.FuncRef:
+		return append(b, "func"...)
+	case wasm.ExternRef:
+		return append(b, "extern"...)
+	default:
+		return append(b, tp.String()...)
+	}
+}
+
+// appendMemArg appends offset and alignment if they are not default.
+func appendMemArg(b []byte, m wasm.MemArg, natural int8) []byte {
+	if m.Offset != 0 {
+		b = append(b, " offset="...)
+		b = strconv.AppendUint(b, uint64(m.Offset), 10)
+	}
+
+	if m.Align != int(natural) {
+		b = append(b, " align="...)
+
+		if m.Align >= 0 && m.Align < 32 {
+			b = strconv.AppendUint(b, 1<<m.Align, 10)
+		} else {
+			b = strconv.AppendInt(b, int64(m.Align), 10) // invalid anyway
+		}
+	}
+
+	return b
+}
+
+func appendFloat32(b []byte, f float32) []byte {
+	bits := math.Float32bits(f)
+
+	if f != f {
+		const canon = 1 << 22
+
+		return appendNaN(b, bits>>31 != 0, uint64(bits&(1<<23-1)), canon)
+	}
+
+	return appendFloat(b, float64(f), 32)
+}
+
+func appendFloat64(b []byte, f float64) []byte {
+	bits := math.Float64bits(f)
+
+	if f != f {
+		const canon = 1 << 51
+
+		return appendNaN(b, bits>>63 != 0, bits&(1<<52-1), canon)
+	}
+
+	return appendFloat(b, f, 64)
+}
+
+func appendFloat(b []byte, f float64, size int) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(b, "inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	}
+
+	return strconv.AppendFloat(b, f, 'g', -1, size)
+}
+
+func appendNaN(b []byte, neg bool, payload, canon uint64) []byte {
+	if neg {
+		b = append(b, '-')
+	}
+
+	b = append(b, "nan"...)
+
+	if payload == canon {
+		return b
+	}
+
+	b = append(b, ":0x"...)
+
+	return strconv.AppendUint(b, payload, 16)
+}
+
+func appendHex(b []byte, v uint64, width int) []byte {
+	st := len(b)
+	b = strconv.AppendUint(b, v, 16)
+
+	for len(b)-st < width {
+		b = append(b, 0)
+		copy(b[st+1:], b[st:])
+		b[st] = '0'
+	}
+
+	return b
+}
+
+func indent(b []byte, depth int) []byte {
+	b = append(b, '\n')
+
+	for i := 0; i < depth; i++ {
+		b = append(b, "  "...)
+	}
+
+	return b
+}
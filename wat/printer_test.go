@@ -0,0 +1,136 @@
+package wat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nikand.dev/go/wasm"
+)
+
+func testModule(tb testing.TB) *wasm.Module {
+	var e wasm.Encoder
+
+	names := e.Names(nil, &wasm.Names{
+		Module: []byte("test"),
+		Funcs:  wasm.NameMap{{Index: 0, Name: []byte("print")}, {Index: 1, Name: []byte("main")}},
+		Locals: wasm.IndirectNameMap{
+			{Index: 1, Names: wasm.NameMap{{Index: 0, Name: []byte("x")}, {Index: 1, Name: []byte("tmp var")}}},
+		},
+		Labels: wasm.IndirectNameMap{
+			{Index: 1, Names: wasm.NameMap{{Index: 0, Name: []byte("exit")}}},
+		},
+		Globals: wasm.NameMap{{Index: 0, Name: []byte("sp")}},
+	})
+
+	m := &wasm.Module{
+		Version: 1,
+		Type: []wasm.FuncType{
+			{Params: wasm.ResultType{wasm.I32}},
+			{Params: wasm.ResultType{wasm.I32}, Result: wasm.ResultType{wasm.I32}},
+		},
+		Function: []wasm.Index{1},
+		Memory:   []wasm.Limits{{Lo: 1, Hi: -1}},
+		Global:   []wasm.Global{{Type: wasm.I32, Mut: 1, Expr: wasm.Code{wasm.I32Const, 0x80, 0x08, wasm.End}}},
+		Export:   []wasm.Export{{Name: []byte("main"), ExportType: wasm.ExternFunc, Index: 1}},
+		Code: []wasm.Code{{
+			1, 1, wasm.I32, // local tmp var
+			wasm.Block, wasm.I32,
+			wasm.LocalGet, 0,
+			wasm.LocalGet, 0,
+			wasm.I32EqZ,
+			wasm.BrIf, 0,
+			wasm.Call, 0,
+			wasm.I32Const, 1,
+			wasm.End,
+			wasm.LocalTee, 1,
+			wasm.I32Load, 2, 4,
+			wasm.F32Const, 0, 0, 0xc0, 0x7f,
+			wasm.Drop,
+			wasm.End,
+		}},
+		Data:   []wasm.Data{{Expr: wasm.Code{wasm.I32Const, 8, wasm.End}, Init: []byte("hi\n\"")}},
+		Custom: []wasm.Custom{{Name: []byte(wasm.NameSectionName), Data: names}},
+	}
+
+	im := wasm.Import{Module: []byte("env"), Name: []byte("print")}
+	im.SetFunc(0)
+
+	m.Import = append(m.Import, im)
+
+	return m
+}
+
+func TestPrinterFlat(tb *testing.T) {
+	var p Printer
+
+	b, err := p.Module(nil, testModule(tb))
+	require.NoError(tb, err)
+
+	assert.Equal(tb, `(module $test
+  (type (;0;) (func (param i32)))
+  (type (;1;) (func (param i32) (result i32)))
+  (import "env" "print" (func $print (;0;) (type 0) (param i32)))
+  (func $main (;1;) (type 1) (param $x i32) (result i32) (local $tmp_var i32)
+    block $exit (result i32)
+      local.get $x
+      local.get $x
+      i32.eqz
+      br_if $exit
+      call $print
+      i32.const 1
+    end
+    local.tee $tmp_var
+    i32.load offset=4
+    f32.const nan
+    drop)
+  (memory (;0;) 1)
+  (global $sp (;0;) (mut i32) (i32.const 1024))
+  (export "main" (func $main))
+  (data (;0;) (i32.const 8) "hi\0a\""))
+`, string(b))
+}
+
+func TestPrinterFolded(tb *testing.T) {
+	p := Printer{Folded: true}
+
+	b, err := p.Module(nil, testModule(tb))
+	require.NoError(tb, err)
+
+	assert.Contains(tb, string(b), `
+  (func $main (;1;) (type 1) (param $x i32) (result i32) (local $tmp_var i32)
+    (i32.load offset=4
+      (local.tee $tmp_var
+        (block $exit (result i32)
+          (local.get $x)
+          (br_if $exit
+            (i32.eqz
+              (local.get $x)))
+          (call $print)
+          (i32.const 1))))
+    (drop
+      (f32.const nan)))
+`)
+}
+
+func TestAppendFloat(tb *testing.T) {
+	for _, tc := range []struct {
+		f   float64
+		exp string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "-0"},
+		{1.5, "1.5"},
+		{1e21, "1e+21"},
+		{math.Inf(-1), "-inf"},
+		{math.Float64frombits(0x7ff8000000000000), "nan"},
+		{math.Float64frombits(0xfff0000000000001), "-nan:0x1"},
+	} {
+		assert.Equal(tb, tc.exp, string(appendFloat64(nil, tc.f)), "%v", tc.f)
+	}
+
+	assert.Equal(tb, "0.1", string(appendFloat32(nil, 0.1)))
+	assert.Equal(tb, "nan:0x200000", string(appendFloat32(nil, math.Float32frombits(0x7fa00000))))
+}
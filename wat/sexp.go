@@ -0,0 +1,256 @@
+package wat
+
+import (
+	"bytes"
+	"strconv"
+
+	"tlog.app/go/errors"
+)
+
+type (
+	// sexp is a parsed S-expression: an atom, a string or a list.
+	sexp struct {
+		kind byte
+		pos  int
+
+		text []byte // atom text or decoded string
+		list []*sexp
+	}
+)
+
+// sexp kinds.
+const (
+	sAtom = iota
+	sString
+	sList
+)
+
+// parseSexps parses all the top level S-expressions.
+func parseSexps(src []byte) (l []*sexp, err error) {
+	i := 0
+
+	for {
+		i, err = skipSpace(src, i)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(src) {
+			return l, nil
+		}
+
+		var s *sexp
+
+		s, i, err = parseSexp(src, i)
+		if err != nil {
+			return nil, err
+		}
+
+		l = append(l, s)
+	}
+}
+
+func parseSexp(src []byte, st int) (s *sexp, i int, err error) {
+	i = st
+
+	switch c := src[i]; c {
+	case '(':
+		s = &sexp{kind: sList, pos: st}
+		i++
+
+		for {
+			i, err = skipSpace(src, i)
+			if err != nil {
+				return nil, i, err
+			}
+
+			if i == len(src) {
+				return nil, st, posError(src, st, errors.New("unclosed paren"))
+			}
+
+			if src[i] == ')' {
+				return s, i + 1, nil
+			}
+
+			var x *sexp
+
+			x, i, err = parseSexp(src, i)
+			if err != nil {
+				return nil, i, err
+			}
+
+			s.list = append(s.list, x)
+		}
+	case ')':
+		return nil, st, posError(src, st, errors.New("unexpected close paren"))
+	case '"':
+		s = &sexp{kind: sString, pos: st}
+
+		s.text, i, err = parseString(src, st)
+		if err != nil {
+			return nil, i, err
+		}
+
+		return s, i, nil
+	}
+
+	for i < len(src) && idChar(src[i]) {
+		i++
+	}
+
+	if i == st {
+		return nil, st, posError(src, st, errors.New("unexpected char: %q", src[st]))
+	}
+
+	return &sexp{kind: sAtom, pos: st, text: src[st:i]}, i, nil
+}
+
+// parseString parses quoted string with escape sequences.
+func parseString(src []byte, st int) (s []byte, i int, err error) {
+	s = []byte{}
+
+	for i = st + 1; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == '"':
+			return s, i + 1, nil
+		case c == '\n':
+			return nil, i, posError(src, i, errors.New("newline in string"))
+		case c != '\\':
+			s = append(s, c)
+			i++
+
+			continue
+		}
+
+		if i+1 >= len(src) {
+			break
+		}
+
+		switch c = src[i+1]; c {
+		case 't':
+			s = append(s, '\t')
+		case 'n':
+			s = append(s, '\n')
+		case 'r':
+			s = append(s, '\r')
+		case '"', '\'', '\\':
+			s = append(s, c)
+		case 'u':
+			end := bytes.IndexByte(src[i:], '}')
+			if end < 0 || i+2 >= len(src) || src[i+2] != '{' {
+				return nil, i, posError(src, i, errors.New("bad unicode escape"))
+			}
+
+			r, err := strconv.ParseUint(string(src[i+3:i+end]), 16, 32)
+			if err != nil {
+				return nil, i, posError(src, i, errors.Wrap(err, "bad unicode escape"))
+			}
+
+			s = append(s, string(rune(r))...)
+			i += end + 1
+
+			continue
+		default:
+			if i+2 >= len(src) || hexDigit(c) < 0 || hexDigit(src[i+2]) < 0 {
+				return nil, i, posError(src, i, errors.New("bad escape sequence"))
+			}
+
+			s = append(s, byte(hexDigit(c)<<4|hexDigit(src[i+2])))
+			i++
+		}
+
+		i += 2
+	}
+
+	return nil, st, posError(src, st, errors.New("unclosed string"))
+}
+
+// skipSpace skips whitespaces and comments.
+func skipSpace(src []byte, st int) (i int, err error) {
+	i = st
+
+	for i < len(src) {
+		switch c := src[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == ';' && i+1 < len(src) && src[i+1] == ';':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '(' && i+1 < len(src) && src[i+1] == ';':
+			st := i
+			depth := 0
+
+			for {
+				if i+1 >= len(src) {
+					return i, posError(src, st, errors.New("unclosed comment"))
+				}
+
+				switch {
+				case src[i] == '(' && src[i+1] == ';':
+					depth++
+					i += 2
+				case src[i] == ';' && src[i+1] == ')':
+					depth--
+					i += 2
+				default:
+					i++
+				}
+
+				if depth == 0 {
+					break
+				}
+			}
+		default:
+			return i, nil
+		}
+	}
+
+	return i, nil
+}
+
+func hexDigit(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c - 'a' + 10)
+	case c >= 'A' && c <= 'F':
+		return int(c - 'A' + 10)
+	default:
+		return -1
+	}
+}
+
+// posError adds line and column of pos to the error.
+func posError(src []byte, pos int, err error) error {
+	line := 1 + bytes.Count(src[:pos], []byte{'\n'})
+	col := pos - bytes.LastIndexByte(src[:pos], '\n')
+
+	return errors.Wrap(err, "%d:%d", line, col)
+}
+
+// head returns the list first atom.
+func (s *sexp) head() string {
+	if s.kind != sList || len(s.list) == 0 || s.list[0].kind != sAtom {
+		return ""
+	}
+
+	return string(s.list[0].text)
+}
+
+// is reports whether s is a list started with keyword kw.
+func (s *sexp) is(kw string) bool {
+	return s.head() == kw
+}
+
+func (s *sexp) atom() bool {
+	return s.kind == sAtom
+}
+
+// id reports whether s is an identifier.
+func (s *sexp) id() bool {
+	return s.kind == sAtom && len(s.text) > 1 && s.text[0] == '$'
+}
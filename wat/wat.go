@@ -0,0 +1,54 @@
+// Package wat implements the WebAssembly text format.
+package wat
+
+import (
+	"strings"
+
+	"nikand.dev/go/wasm"
+)
+
+type (
+	// opInfo describes an instruction for the text format.
+	opInfo struct {
+		name string
+
+		align int8 // natural alignment log2 for memory instructions
+
+		// stack effect, used to fold instructions
+		pops, pushes int8
+	}
+)
+
+// instrInfo returns instruction description.
+// ok is false if the instruction is unknown.
+func instrInfo(in *wasm.Instruction) (op opInfo, ok bool) {
+	var tab []opInfo
+	x := uint32(in.Opcode)
+
+	switch in.Opcode {
+	case wasm.FCExt:
+		tab, x = fcOps[:], in.Ext
+	case wasm.FDExt:
+		tab, x = fdOps[:], in.Ext
+	case wasm.FEExt:
+		tab, x = feOps[:], in.Ext
+	default:
+		tab = ops[:]
+	}
+
+	if x >= uint32(len(tab)) || tab[x].name == "" {
+		return op, false
+	}
+
+	return tab[x], true
+}
+
+// isSIMDMemory reports whether FDExt instruction has memarg immediate.
+func isSIMDMemory(name string) bool {
+	return strings.HasPrefix(name, "v128.load") || strings.HasPrefix(name, "v128.store")
+}
+
+// isSIMDLane reports whether FDExt instruction has lane index immediate.
+func isSIMDLane(name string) bool {
+	return strings.Contains(name, "_lane")
+}